@@ -91,6 +91,13 @@ type AmazonCloudWatchAgentSpec struct {
 	// ImagePullPolicy indicates the pull policy to be used for retrieving the container image (Always, Never, IfNotPresent)
 	// +optional
 	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// SecurityContext configures the security context of the collector container injected as a sidecar, the
+	// containers of the agent's own workloads aren't affected. The sidecar complies with the restricted Pod Security
+	// Standard by default and runs as the user of the application container unless the security context sets one.
+	// With a read-only root filesystem, the directories the agent writes to under /opt/aws/amazon-cloudwatch-agent
+	// are backed by an emptyDir.
+	// +optional
+	SecurityContext *v1.SecurityContext `json:"securityContext,omitempty"`
 	// Config is the raw JSON to be used as the collector's configuration. Refer to the CloudWatch Agent documentation for details.
 	// +required
 	Config string `json:"config,omitempty"`
@@ -0,0 +1,51 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=clusterotelinst;clusterotelinsts
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="Endpoint",type="string",JSONPath=".spec.exporter.endpoint"
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=".spec.priority"
+// +operator-sdk:csv:customresourcedefinitions:displayName="OpenTelemetry Cluster Instrumentation"
+// +operator-sdk:csv:customresourcedefinitions:resources={{Pod,v1}}
+
+// ClusterInstrumentation declares cluster-wide defaults for OpenTelemetry instrumentation.
+//
+// A pod is instrumented with the Instrumentation selected in its namespace merged on top of the
+// ClusterInstrumentation that applies to it, which is itself merged on top of the operator defaults.
+// The ClusterInstrumentation that applies to a pod is chosen with the same selector and priority rules
+// as namespaced Instrumentations. The merge rules are:
+//   - a field set in the more specific object wins, unset fields are inherited;
+//   - the per-language images, resources and configuration a namespaced Instrumentation is defaulted with
+//     don't count as set: a value equal to its default is inherited from the ClusterInstrumentation;
+//   - env vars, common and per language, are merged by name;
+//   - resource attributes are merged by key;
+//   - lists other than env vars, such as propagators, are replaced as a whole;
+//   - the sampler, the env policy, the exporter TLS settings and the exporter agent reference are replaced as a
+//     whole, their fields depending on each other.
+type ClusterInstrumentation struct {
+	Status            InstrumentationStatus `json:"status,omitempty"`
+	metav1.TypeMeta   `json:",inline"`
+	Spec              InstrumentationSpec `json:"spec,omitempty"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterInstrumentationList contains a list of ClusterInstrumentation.
+type ClusterInstrumentationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterInstrumentation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterInstrumentation{}, &ClusterInstrumentationList{})
+}
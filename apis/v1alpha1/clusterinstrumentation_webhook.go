@@ -0,0 +1,67 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var clusterinstrumentationlog = logf.Log.WithName("clusterinstrumentation-resource")
+
+func (r *ClusterInstrumentation) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-cloudwatch-aws-amazon-com-v1alpha1-clusterinstrumentation,mutating=true,failurePolicy=fail,sideEffects=None,groups=cloudwatch.aws.amazon.com,resources=clusterinstrumentations,verbs=create;update,versions=v1alpha1,name=mclusterinstrumentation.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ClusterInstrumentation{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// Unlike the Instrumentation, the spec isn't defaulted: any field set here would win over the operator defaults
+// it is merged on top of.
+func (r *ClusterInstrumentation) Default() {
+	clusterinstrumentationlog.Info("default", "name", r.Name)
+	if r.Labels == nil {
+		r.Labels = map[string]string{}
+	}
+	if r.Labels["app.kubernetes.io/managed-by"] == "" {
+		r.Labels["app.kubernetes.io/managed-by"] = "amazon-cloudwatch-agent-operator"
+	}
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-cloudwatch-aws-amazon-com-v1alpha1-clusterinstrumentation,mutating=false,failurePolicy=fail,groups=cloudwatch.aws.amazon.com,resources=clusterinstrumentations,versions=v1alpha1,name=vclusterinstrumentationcreateupdate.kb.io,sideEffects=none,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=delete,path=/validate-cloudwatch-aws-amazon-com-v1alpha1-clusterinstrumentation,mutating=false,failurePolicy=ignore,groups=cloudwatch.aws.amazon.com,resources=clusterinstrumentations,versions=v1alpha1,name=vclusterinstrumentationdelete.kb.io,sideEffects=none,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ClusterInstrumentation{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (r *ClusterInstrumentation) ValidateCreate() (admission.Warnings, error) {
+	clusterinstrumentationlog.Info("validate create", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (r *ClusterInstrumentation) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	clusterinstrumentationlog.Info("validate update", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (r *ClusterInstrumentation) ValidateDelete() (admission.Warnings, error) {
+	clusterinstrumentationlog.Info("validate delete", "name", r.Name)
+	return nil, nil
+}
+
+// validate applies the validation of the Instrumentation, as the spec of both is the same.
+func (r *ClusterInstrumentation) validate() error {
+	inst := Instrumentation{ObjectMeta: r.ObjectMeta, Spec: r.Spec}
+	return inst.validate()
+}
@@ -5,15 +5,51 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // InstrumentationSpec defines the desired state of OpenTelemetry SDK and instrumentation.
 type InstrumentationSpec struct {
+	// Selector selects the pods this Instrumentation applies to. When several Instrumentations in a namespace
+	// apply to a pod, the one with the highest priority is used. Pods matched by a selector are instrumented
+	// even without an inject annotation, using the language detected for each container.
+	// An empty or missing selector matches every pod that requests instrumentation with an annotation.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Priority orders the Instrumentations that apply to the same pod, the highest value wins.
+	// Ties are broken in favour of Instrumentations with a selector, then by name.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// FailurePolicy defines whether pods are admitted when this instrumentation cannot be injected into them.
+	// Ignore (default) admits the pod without instrumentation, Fail rejects its creation; updates of existing pods
+	// are always admitted. The instrumentation.opentelemetry.io/failure-policy annotation on the pod or namespace
+	// takes precedence, and applies to the injection of the agent sidecar as well.
+	// +optional
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+
 	// Exporter defines exporter configuration.
 	// +optional
 	Exporter `json:"exporter,omitempty"`
 
+	// Traces selects the exporter of the traces, set in the OTEL_TRACES_EXPORTER env var.
+	// +optional
+	Traces Signal `json:"traces,omitempty"`
+
+	// Metrics selects the exporter of the metrics, set in the OTEL_METRICS_EXPORTER env var.
+	// +optional
+	Metrics Signal `json:"metrics,omitempty"`
+
+	// Logs selects the exporter of the logs, set in the OTEL_LOGS_EXPORTER env var.
+	// +optional
+	Logs Signal `json:"logs,omitempty"`
+
+	// ApplicationSignals configures the service metrics generated by the AWS distributions of the SDKs.
+	// +optional
+	ApplicationSignals *ApplicationSignals `json:"applicationSignals,omitempty"`
+
 	// Resource defines the configuration for the resource attributes, as defined by the OpenTelemetry specification.
 	// +optional
 	Resource Resource `json:"resource,omitempty"`
@@ -31,14 +67,86 @@ type InstrumentationSpec struct {
 	// Env defines common env vars. There are four layers for env vars' definitions and
 	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
 	// If the former var had been defined, then the other vars would be ignored.
+	// The envPolicy can give precedence to the env vars of the instrumentation over the original container env vars.
 	// +optional
 	Env []corev1.EnvVar `json:"env,omitempty"`
 
+	// EnvPolicy defines whether the env vars set by the instrumentation replace the ones the containers already
+	// define. When it is set, the instrumentation.opentelemetry.io/env-policy-report annotation of the pod lists,
+	// for each container, the env vars that were kept, overridden or appended.
+	// +optional
+	EnvPolicy EnvPolicy `json:"envPolicy,omitempty"`
+
+	// SecurityContext overrides the security context of the init containers injected into the instrumented pods,
+	// which comply with the restricted Pod Security Standard by default. Unless set, the user and group are those of
+	// the instrumented container. It doesn't apply to the Go instrumentation, which requires a privileged container.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
 	// Java defines configuration for java auto-instrumentation.
 	// +optional
 	Java Java `json:"java,omitempty"`
+
+	// Python defines configuration for python auto-instrumentation.
+	// +optional
+	Python Python `json:"python,omitempty"`
+
+	// NodeJS defines configuration for nodejs auto-instrumentation.
+	// +optional
+	NodeJS NodeJS `json:"nodejs,omitempty"`
+
+	// DotNet defines configuration for dotnet auto-instrumentation.
+	// +optional
+	DotNet DotNet `json:"dotnet,omitempty"`
+
+	// Go defines configuration for go auto-instrumentation.
+	// +optional
+	Go Go `json:"go,omitempty"`
+
+	// ApacheHttpd defines configuration for Apache HTTPD auto-instrumentation.
+	// +optional
+	ApacheHttpd ApacheHttpd `json:"apacheHttpd,omitempty"`
+
+	// Nginx defines configuration for Nginx auto-instrumentation.
+	// +optional
+	Nginx Nginx `json:"nginx,omitempty"`
 }
 
+// FailurePolicy defines how a pod is admitted when its instrumentation cannot be injected.
+// +kubebuilder:validation:Enum=Ignore;Fail
+type FailurePolicy string
+
+const (
+	// FailurePolicyIgnore admits the pod without instrumentation.
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+	// FailurePolicyFail rejects the pod.
+	FailurePolicyFail FailurePolicy = "Fail"
+)
+
+// EnvPolicy defines how the env vars set by the instrumentation are combined with the ones the containers define.
+type EnvPolicy struct {
+	// Mode is preserve (default), which keeps the env vars the containers define, or override, which replaces them
+	// with the ones set by the instrumentation. Env vars whose value is extended by the instrumentation, such as
+	// JAVA_TOOL_OPTIONS or OTEL_RESOURCE_ATTRIBUTES, keep the value of the container in both modes.
+	// +optional
+	Mode EnvPolicyMode `json:"mode,omitempty"`
+
+	// EnforceKeys lists the env vars replaced even in the preserve mode, such as the exporter endpoint or the sampler.
+	// +optional
+	EnforceKeys []string `json:"enforceKeys,omitempty"`
+}
+
+// EnvPolicyMode defines whether the env vars the containers define take precedence over the instrumentation.
+// +kubebuilder:validation:Enum=preserve;override
+type EnvPolicyMode string
+
+const (
+	// EnvPolicyModePreserve keeps the env vars the containers define.
+	EnvPolicyModePreserve EnvPolicyMode = "preserve"
+	// EnvPolicyModeOverride replaces the env vars the containers define with the ones set by the instrumentation.
+	EnvPolicyModeOverride EnvPolicyMode = "override"
+)
+
 // Resource defines the configuration for the resource attributes, as defined by the OpenTelemetry specification.
 // See also: https://github.com/open-telemetry/opentelemetry-specification/blob/v1.8.0/specification/overview.md#resources
 type Resource struct {
@@ -50,13 +158,248 @@ type Resource struct {
 	// AddK8sUIDAttributes defines whether K8s UID attributes should be collected (e.g. k8s.deployment.uid).
 	// +optional
 	AddK8sUIDAttributes bool `json:"addK8sUIDAttributes,omitempty"`
+
+	// FromLabels maps the labels of the pod or its namespace to resource attributes.
+	// For example app.kubernetes.io/version: service.version
+	// +optional
+	FromLabels []AttributeMapping `json:"fromLabels,omitempty"`
+
+	// FromAnnotations maps the annotations of the pod or its namespace to resource attributes.
+	// +optional
+	FromAnnotations []AttributeMapping `json:"fromAnnotations,omitempty"`
+
+	// ServiceName lists, by precedence, the sources of the service name. The first source with a value is used.
+	// It defaults to the owners of kind KnativeService, Deployment, Rollout, StatefulSet, Job and CronJob, then
+	// the pod name, then the container name.
+	// +optional
+	ServiceName []ServiceAttributeSource `json:"serviceName,omitempty"`
+
+	// ServiceVersion lists, by precedence, the sources of the service.version attribute. The first source with a
+	// value is used. It defaults to the image of the container, its tag or else its digest.
+	// +optional
+	ServiceVersion []ServiceAttributeSource `json:"serviceVersion,omitempty"`
 }
 
+// ServiceAttributeSource is a source of the service name or version of the instrumented container.
+type ServiceAttributeSource struct {
+	// From is the type of the source. The service version can only be read from labels, annotations and images.
+	// +kubebuilder:validation:Enum=Label;Annotation;Owner;PodName;ContainerName;Image
+	From ServiceAttributeSourceType `json:"from"`
+
+	// Key is the key of the label or annotation of the pod, e.g. app.kubernetes.io/name.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Kind is the kind of the owner of the pod: Deployment, StatefulSet, DaemonSet, ReplicaSet, Job, CronJob,
+	// Rollout (Argo Rollouts) or KnativeService.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// ServiceAttributeSourceType is the type of a ServiceAttributeSource.
+type ServiceAttributeSourceType string
+
+const (
+	// ServiceAttributeSourceLabel reads a label of the pod.
+	ServiceAttributeSourceLabel ServiceAttributeSourceType = "Label"
+	// ServiceAttributeSourceAnnotation reads an annotation of the pod.
+	ServiceAttributeSourceAnnotation ServiceAttributeSourceType = "Annotation"
+	// ServiceAttributeSourceOwner uses the name of an owner of the pod.
+	ServiceAttributeSourceOwner ServiceAttributeSourceType = "Owner"
+	// ServiceAttributeSourcePodName uses the name of the pod.
+	ServiceAttributeSourcePodName ServiceAttributeSourceType = "PodName"
+	// ServiceAttributeSourceContainerName uses the name of the instrumented container.
+	ServiceAttributeSourceContainerName ServiceAttributeSourceType = "ContainerName"
+	// ServiceAttributeSourceImage uses the tag of the image of the instrumented container, or else its digest.
+	ServiceAttributeSourceImage ServiceAttributeSourceType = "Image"
+)
+
+// AttributeMapping maps labels or annotations, selected by key or by a regular expression matching their keys, to
+// resource attributes. The attributes defined by the Instrumentation and the ones detected from the pod take
+// precedence. The resource.opentelemetry.io/<attribute> annotations of the pod override every other attribute.
+type AttributeMapping struct {
+	// From is the object the labels or annotations are read from, Pod (default) or Namespace.
+	// +optional
+	From AttributeMappingScope `json:"from,omitempty"`
+
+	// Key is the key of the label or annotation.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// KeyRegex is a regular expression matching the whole keys of the labels or annotations, in place of Key.
+	// Its capture groups can be referenced by Attribute, e.g. app.kubernetes.io/(.*) and app.$1.
+	// +optional
+	KeyRegex string `json:"keyRegex,omitempty"`
+
+	// Attribute is the name of the resource attribute. It defaults to k8s.<pod|namespace>.<labels|annotations>.<key>.
+	// +optional
+	Attribute string `json:"attribute,omitempty"`
+}
+
+// AttributeMappingScope is the object the labels or annotations of an AttributeMapping are read from.
+// +kubebuilder:validation:Enum=Pod;Namespace
+type AttributeMappingScope string
+
+const (
+	// AttributeMappingScopePod reads the labels or annotations of the pod.
+	AttributeMappingScopePod AttributeMappingScope = "Pod"
+	// AttributeMappingScopeNamespace reads the labels or annotations of the namespace of the pod.
+	AttributeMappingScopeNamespace AttributeMappingScope = "Namespace"
+)
+
 // Exporter defines OTLP exporter configuration.
 type Exporter struct {
 	// Endpoint is address of the collector with OTLP endpoint.
 	// +optional
 	Endpoint string `json:"endpoint,omitempty"`
+
+	// Protocol is the OTLP transport protocol, set in the OTEL_EXPORTER_OTLP_PROTOCOL env var.
+	// +optional
+	Protocol ExporterProtocol `json:"protocol,omitempty"`
+
+	// TracesEndpoint overrides Endpoint for traces.
+	// +optional
+	TracesEndpoint string `json:"tracesEndpoint,omitempty"`
+
+	// MetricsEndpoint overrides Endpoint for metrics.
+	// +optional
+	MetricsEndpoint string `json:"metricsEndpoint,omitempty"`
+
+	// LogsEndpoint overrides Endpoint for logs.
+	// +optional
+	LogsEndpoint string `json:"logsEndpoint,omitempty"`
+
+	// Headers are sent with every export request. Values can be read from Secrets.
+	// +optional
+	Headers []ExporterHeader `json:"headers,omitempty"`
+
+	// Timeout is the maximum time the exporter waits for each batch export.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Compression is the compression used for export requests.
+	// +optional
+	Compression ExporterCompression `json:"compression,omitempty"`
+
+	// TLS defines the TLS configuration of the exporter.
+	// +optional
+	TLS *ExporterTLS `json:"tls,omitempty"`
+
+	// Agent references the AmazonCloudWatchAgent the telemetry is sent to. The OTLP, X-Ray sampling and
+	// SMP endpoints are computed from the Service of the agent, unless they are set explicitly.
+	// +optional
+	Agent *AgentReference `json:"agent,omitempty"`
+
+	// Mode defines how the agent is reached. With nodeLocal, the endpoints point to the agent running on the
+	// node of the pod through its host IP, instead of the Service of the agent.
+	// +optional
+	Mode ExporterMode `json:"mode,omitempty"`
+}
+
+// ExporterMode defines how the instrumented applications reach the agent.
+// +kubebuilder:validation:Enum=service;nodeLocal
+type ExporterMode string
+
+const (
+	// ExporterModeService sends the telemetry to the Service of the agent.
+	ExporterModeService ExporterMode = "service"
+	// ExporterModeNodeLocal sends the telemetry to the agent on the node of the pod, for agents deployed as a
+	// DaemonSet.
+	ExporterModeNodeLocal ExporterMode = "nodeLocal"
+)
+
+// AgentReference identifies an AmazonCloudWatchAgent.
+type AgentReference struct {
+	// Name of the AmazonCloudWatchAgent.
+	Name string `json:"name"`
+
+	// Namespace of the AmazonCloudWatchAgent. Defaults to the namespace of the Instrumentation.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ExporterProtocol is the OTLP transport protocol.
+// +kubebuilder:validation:Enum=grpc;http/protobuf
+type ExporterProtocol string
+
+const (
+	// ExporterProtocolGRPC exports over OTLP/gRPC.
+	ExporterProtocolGRPC ExporterProtocol = "grpc"
+	// ExporterProtocolHTTPProtobuf exports over OTLP/HTTP with protobuf payloads.
+	ExporterProtocolHTTPProtobuf ExporterProtocol = "http/protobuf"
+)
+
+// ExporterCompression is the compression used by the OTLP exporter.
+// +kubebuilder:validation:Enum=gzip;none
+type ExporterCompression string
+
+// ExporterHeader defines a header sent with the export requests.
+type ExporterHeader struct {
+	// Name of the header.
+	Name string `json:"name"`
+
+	// Value of the header.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// ValueFrom reads the value of the header from a Secret in the pod's namespace.
+	// +optional
+	ValueFrom *corev1.SecretKeySelector `json:"valueFrom,omitempty"`
+}
+
+// ExporterTLS defines the TLS configuration of the OTLP exporter.
+type ExporterTLS struct {
+	// CA is the key of a ConfigMap in the pod's namespace holding the certificate authority used to verify the endpoint.
+	// +optional
+	CA *corev1.ConfigMapKeySelector `json:"ca,omitempty"`
+
+	// ClientCertSecret is the name of a Secret of type kubernetes.io/tls in the pod's namespace, holding the
+	// client certificate and key used for mutual TLS.
+	// +optional
+	ClientCertSecret string `json:"clientCertSecret,omitempty"`
+
+	// Insecure disables TLS for gRPC endpoints without a scheme.
+	// +optional
+	Insecure *bool `json:"insecure,omitempty"`
+}
+
+// Signal defines the export of a telemetry signal.
+type Signal struct {
+	// Exporter is the exporter of the signal. The SDK default is used when it isn't set.
+	// +optional
+	Exporter SignalExporter `json:"exporter,omitempty"`
+}
+
+// SignalExporter is the exporter of a telemetry signal.
+// +kubebuilder:validation:Enum=otlp;none;logging
+type SignalExporter string
+
+const (
+	// SignalExporterOTLP exports the signal over OTLP.
+	SignalExporterOTLP SignalExporter = "otlp"
+	// SignalExporterNone disables the export of the signal.
+	SignalExporterNone SignalExporter = "none"
+	// SignalExporterLogging writes the signal to the standard output of the application.
+	SignalExporterLogging SignalExporter = "logging"
+)
+
+// ApplicationSignals configures the service metrics generated by the AWS distributions of the SDKs.
+type ApplicationSignals struct {
+	// Enabled turns the generation of the service metrics on or off, through the OTEL_SMP_ENABLED env var.
+	// The SDK default is used when it isn't set.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Endpoint is the OTLP endpoint the service metrics are sent to, set in the OTEL_AWS_SMP_EXPORTER_ENDPOINT env
+	// var. It is required when the metrics are enabled, unless the exporter references an agent or runs in
+	// nodeLocal mode, or the env var is set in spec.env.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// RuntimeMetrics turns the runtime metrics of the application, such as garbage collection and thread counts,
+	// on or off. The SDK default is used when it isn't set.
+	// +optional
+	RuntimeMetrics *bool `json:"runtimeMetrics,omitempty"`
 }
 
 // Sampler defines sampling configuration.
@@ -81,6 +424,45 @@ type Java struct {
 	// +optional
 	Image string `json:"image,omitempty"`
 
+	// JVMOptionsEnvName is the env var the javaagent argument is added to, JAVA_TOOL_OPTIONS by default. It can
+	// be set to JDK_JAVA_OPTIONS, or to a custom variable for images whose entrypoint passes it to the JVM.
+	// +optional
+	JVMOptionsEnvName string `json:"jvmOptionsEnvName,omitempty"`
+
+	// AgentPath is the path of the javaagent JAR in the image, /javaagent.jar by default.
+	// +optional
+	AgentPath string `json:"agentPath,omitempty"`
+
+	// MountPath is the directory the javaagent and its extensions are copied to, and mounted at in the
+	// instrumented containers, /otel-auto-instrumentation by default.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// ImagePullPolicy of the init containers that copy the javaagent and its extensions.
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// VolumeSizeLimit limits the size of the emptyDir volume the javaagent and its extensions are copied to.
+	// +optional
+	VolumeSizeLimit *resource.Quantity `json:"volumeSizeLimit,omitempty"`
+
+	// Extensions are javaagent extensions copied from their images, and loaded by the javaagent with the
+	// otel.javaagent.extensions system property.
+	// +optional
+	Extensions []JavaExtension `json:"extensions,omitempty"`
+
+	// Delivery defines how the javaagent and its extensions are made available to the instrumented containers.
+	// InitContainer (default) copies them to an emptyDir volume. ImageVolume mounts their images as read-only
+	// volumes, with Kubernetes image volumes when the cluster enables them, or with CSIDriver otherwise, and falls
+	// back to InitContainer when neither is available.
+	// +optional
+	Delivery AgentDelivery `json:"delivery,omitempty"`
+
+	// CSIDriver is a CSI driver that mounts container images, such as csi-image.warm-metal.tech, used by the
+	// ImageVolume delivery on clusters without Kubernetes image volumes.
+	// +optional
+	CSIDriver string `json:"csiDriver,omitempty"`
+
 	// Env defines java specific env vars. There are four layers for env vars' definitions and
 	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
 	// If the former var had been defined, then the other vars would be ignored.
@@ -92,6 +474,154 @@ type Java struct {
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
+// AgentDelivery defines how auto-instrumentation agents are made available to the instrumented containers.
+// +kubebuilder:validation:Enum=InitContainer;ImageVolume
+type AgentDelivery string
+
+const (
+	// AgentDeliveryInitContainer copies the agents from their images to an emptyDir volume with init containers.
+	AgentDeliveryInitContainer AgentDelivery = "InitContainer"
+	// AgentDeliveryImageVolume mounts the images of the agents as read-only volumes.
+	AgentDeliveryImageVolume AgentDelivery = "ImageVolume"
+)
+
+// JavaExtension is a javaagent extension shipped in a container image.
+type JavaExtension struct {
+	// Image is a container image with the extension.
+	Image string `json:"image"`
+
+	// Path is the extension JAR, or a directory of extension JARs, in the image.
+	Path string `json:"path"`
+}
+
+// Python defines Python SDK and instrumentation configuration.
+type Python struct {
+	// Image is a container image with Python SDK and auto-instrumentation.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Env defines python specific env vars. There are four layers for env vars' definitions and
+	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
+	// If the former var had been defined, then the other vars would be ignored.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources describes the compute resource requirements.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// NodeJS defines NodeJS SDK and instrumentation configuration.
+type NodeJS struct {
+	// Image is a container image with NodeJS SDK and auto-instrumentation.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Env defines nodejs specific env vars. There are four layers for env vars' definitions and
+	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
+	// If the former var had been defined, then the other vars would be ignored.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources describes the compute resource requirements.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// DotNet defines DotNet SDK and instrumentation configuration.
+type DotNet struct {
+	// Image is a container image with DotNet SDK and auto-instrumentation.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Env defines DotNet specific env vars. There are four layers for env vars' definitions and
+	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
+	// If the former var had been defined, then the other vars would be ignored.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources describes the compute resource requirements.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// Go defines Go SDK and instrumentation configuration.
+type Go struct {
+	// Image is a container image with Go SDK and auto-instrumentation.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Env defines Go specific env vars. There are four layers for env vars' definitions and
+	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
+	// If the former var had been defined, then the other vars would be ignored.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources describes the compute resource requirements.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ApacheHttpd defines Apache SDK and instrumentation configuration.
+type ApacheHttpd struct {
+	// Image is a container image with Apache SDK and auto-instrumentation.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Env defines Apache HTTPD specific env vars. There are four layers for env vars' definitions and
+	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
+	// If the former var had been defined, then the other vars would be ignored.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Attrs defines Apache HTTPD agent specific attributes. The precedence is:
+	// `agent default attributes` > `instrument spec attributes` .
+	// Attributes are documented at https://github.com/open-telemetry/opentelemetry-cpp-contrib/tree/main/instrumentation/otel-webserver-module
+	// +optional
+	Attrs []corev1.EnvVar `json:"attrs,omitempty"`
+
+	// Apache HTTPD server version. One of 2.4 or 2.2. Default is 2.4
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Location of Apache HTTPD server configuration.
+	// Needed only if different from default "/usr/local/apache2/conf"
+	// +optional
+	ConfigPath string `json:"configPath,omitempty"`
+
+	// Resources describes the compute resource requirements.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// Nginx defines Nginx SDK and instrumentation configuration.
+type Nginx struct {
+	// Image is a container image with Nginx SDK and auto-instrumentation.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Env defines Nginx specific env vars. There are four layers for env vars' definitions and
+	// the precedence order is: `original container env vars` > `language specific env vars` > `common env vars` > `instrument spec configs' vars`.
+	// If the former var had been defined, then the other vars would be ignored.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Attrs defines Nginx agent specific attributes. The precedence order is:
+	// `agent default attributes` > `instrument spec attributes` .
+	// Attributes are documented at https://github.com/open-telemetry/opentelemetry-cpp-contrib/tree/main/instrumentation/otel-webserver-module
+	// +optional
+	Attrs []corev1.EnvVar `json:"attrs,omitempty"`
+
+	// Location of Nginx configuration file.
+	// Needed only if different from default "/etc/nginx/nginx.conf"
+	// +optional
+	ConfigFile string `json:"configFile,omitempty"`
+
+	// Resources describes the compute resource requirements.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
 // InstrumentationStatus defines status of the instrumentation.
 type InstrumentationStatus struct {
 }
@@ -4,13 +4,18 @@
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -18,19 +23,53 @@ import (
 )
 
 const (
-	AnnotationDefaultAutoInstrumentationJava = "instrumentation.opentelemetry.io/default-auto-instrumentation-java-image"
-	envPrefix                                = "OTEL_"
+	AnnotationDefaultAutoInstrumentationJava        = "instrumentation.opentelemetry.io/default-auto-instrumentation-java-image"
+	AnnotationDefaultAutoInstrumentationPython      = "instrumentation.opentelemetry.io/default-auto-instrumentation-python-image"
+	AnnotationDefaultAutoInstrumentationNodeJS      = "instrumentation.opentelemetry.io/default-auto-instrumentation-nodejs-image"
+	AnnotationDefaultAutoInstrumentationDotNet      = "instrumentation.opentelemetry.io/default-auto-instrumentation-dotnet-image"
+	AnnotationDefaultAutoInstrumentationGo          = "instrumentation.opentelemetry.io/default-auto-instrumentation-go-image"
+	AnnotationDefaultAutoInstrumentationApacheHttpd = "instrumentation.opentelemetry.io/default-auto-instrumentation-apache-httpd-image"
+	AnnotationDefaultAutoInstrumentationNginx       = "instrumentation.opentelemetry.io/default-auto-instrumentation-nginx-image"
+	envPrefix                                       = "OTEL_"
 )
 
 // log is for logging in this package.
 var instrumentationlog = logf.Log.WithName("instrumentation-resource")
 
-func (r *Instrumentation) SetupWebhookWithManager(mgr ctrl.Manager) error {
+// SetupWebhookWithManager registers the webhooks of the Instrumentation. The default images, keyed by the annotation
+// recording them on the Instrumentation, are read on every request so that they follow the operator configuration.
+func (r *Instrumentation) SetupWebhookWithManager(mgr ctrl.Manager, defaultImages func() map[string]string) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
+		WithDefaulter(&instrumentationDefaulter{defaultImages: defaultImages}).
 		Complete()
 }
 
+type instrumentationDefaulter struct {
+	defaultImages func() map[string]string
+}
+
+var _ admission.CustomDefaulter = &instrumentationDefaulter{}
+
+// Default records the current default images in the annotations the Instrumentation doesn't have yet, where the
+// upgrade finds them, and defaults the Instrumentation from them.
+func (d *instrumentationDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	inst, ok := obj.(*Instrumentation)
+	if !ok {
+		return fmt.Errorf("expected an Instrumentation but got a %T", obj)
+	}
+	if inst.Annotations == nil {
+		inst.Annotations = map[string]string{}
+	}
+	for annotation, image := range d.defaultImages() {
+		if _, ok := inst.Annotations[annotation]; !ok && image != "" {
+			inst.Annotations[annotation] = image
+		}
+	}
+	inst.Default()
+	return nil
+}
+
 //+kubebuilder:webhook:path=/mutate-cloudwatch-aws-amazon-com-v1alpha1-instrumentation,mutating=true,failurePolicy=fail,sideEffects=None,groups=cloudwatch.aws.amazon.com,resources=instrumentations,verbs=create;update,versions=v1alpha1,name=minstrumentation.kb.io,admissionReviewVersions=v1
 
 var _ webhook.Defaulter = &Instrumentation{}
@@ -45,23 +84,147 @@ func (r *Instrumentation) Default() {
 		r.Labels["app.kubernetes.io/managed-by"] = "amazon-cloudwatch-agent-operator"
 	}
 
-	if r.Spec.Java.Image == "" {
-		if val, ok := r.Annotations[AnnotationDefaultAutoInstrumentationJava]; ok {
-			r.Spec.Java.Image = val
+	r.Spec.defaultLanguages(r.Annotations)
+}
+
+// DefaultedSpec returns the values the webhook defaults an Instrumentation setting none of them to, the images being
+// read from the given default image annotations. Injection uses it to recognise the values that were only defaulted.
+func DefaultedSpec(annotations map[string]string) InstrumentationSpec {
+	var spec InstrumentationSpec
+	spec.defaultLanguages(annotations)
+	return spec
+}
+
+// defaultLanguages defaults the images, resources and configuration of the instrumented languages.
+func (s *InstrumentationSpec) defaultLanguages(annotations map[string]string) {
+	if s.Java.Image == "" {
+		if val, ok := annotations[AnnotationDefaultAutoInstrumentationJava]; ok {
+			s.Java.Image = val
 		}
 	}
-	if r.Spec.Java.Resources.Limits == nil {
-		r.Spec.Java.Resources.Limits = corev1.ResourceList{
+	if s.Java.Resources.Limits == nil {
+		s.Java.Resources.Limits = corev1.ResourceList{
 			corev1.ResourceCPU:    resource.MustParse("500m"),
 			corev1.ResourceMemory: resource.MustParse("64Mi"),
 		}
 	}
-	if r.Spec.Java.Resources.Requests == nil {
-		r.Spec.Java.Resources.Requests = corev1.ResourceList{
+	if s.Java.Resources.Requests == nil {
+		s.Java.Resources.Requests = corev1.ResourceList{
 			corev1.ResourceCPU:    resource.MustParse("50m"),
 			corev1.ResourceMemory: resource.MustParse("64Mi"),
 		}
 	}
+	if s.Python.Image == "" {
+		if val, ok := annotations[AnnotationDefaultAutoInstrumentationPython]; ok {
+			s.Python.Image = val
+		}
+	}
+	if s.Python.Resources.Limits == nil {
+		s.Python.Resources.Limits = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+		}
+	}
+	if s.Python.Resources.Requests == nil {
+		s.Python.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+		}
+	}
+	if s.NodeJS.Image == "" {
+		if val, ok := annotations[AnnotationDefaultAutoInstrumentationNodeJS]; ok {
+			s.NodeJS.Image = val
+		}
+	}
+	if s.NodeJS.Resources.Limits == nil {
+		s.NodeJS.Resources.Limits = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.NodeJS.Resources.Requests == nil {
+		s.NodeJS.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.DotNet.Image == "" {
+		if val, ok := annotations[AnnotationDefaultAutoInstrumentationDotNet]; ok {
+			s.DotNet.Image = val
+		}
+	}
+	if s.DotNet.Resources.Limits == nil {
+		s.DotNet.Resources.Limits = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.DotNet.Resources.Requests == nil {
+		s.DotNet.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.Go.Image == "" {
+		if val, ok := annotations[AnnotationDefaultAutoInstrumentationGo]; ok {
+			s.Go.Image = val
+		}
+	}
+	if s.Go.Resources.Limits == nil {
+		s.Go.Resources.Limits = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+		}
+	}
+	if s.Go.Resources.Requests == nil {
+		s.Go.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+		}
+	}
+	if s.ApacheHttpd.Image == "" {
+		if val, ok := annotations[AnnotationDefaultAutoInstrumentationApacheHttpd]; ok {
+			s.ApacheHttpd.Image = val
+		}
+	}
+	if s.ApacheHttpd.Resources.Limits == nil {
+		s.ApacheHttpd.Resources.Limits = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.ApacheHttpd.Resources.Requests == nil {
+		s.ApacheHttpd.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.ApacheHttpd.Version == "" {
+		s.ApacheHttpd.Version = "2.4"
+	}
+	if s.ApacheHttpd.ConfigPath == "" {
+		s.ApacheHttpd.ConfigPath = "/usr/local/apache2/conf"
+	}
+	if s.Nginx.Image == "" {
+		if val, ok := annotations[AnnotationDefaultAutoInstrumentationNginx]; ok {
+			s.Nginx.Image = val
+		}
+	}
+	if s.Nginx.Resources.Limits == nil {
+		s.Nginx.Resources.Limits = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.Nginx.Resources.Requests == nil {
+		s.Nginx.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		}
+	}
+	if s.Nginx.ConfigFile == "" {
+		s.Nginx.ConfigFile = "/etc/nginx/nginx.conf"
+	}
 }
 
 // +kubebuilder:webhook:verbs=create;update,path=/validate-cloudwatch-aws-amazon-com-v1alpha1-instrumentation,mutating=false,failurePolicy=fail,groups=cloudwatch.aws.amazon.com,resources=instrumentations,versions=v1alpha1,name=vinstrumentationcreateupdate.kb.io,sideEffects=none,admissionReviewVersions=v1
@@ -119,6 +282,12 @@ func validateJaegerRemoteSamplerArgument(argument string) error {
 }
 
 func (r *Instrumentation) validate() error {
+	if r.Spec.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.Spec.Selector); err != nil {
+			return fmt.Errorf("spec.selector is invalid: %w", err)
+		}
+	}
+
 	switch r.Spec.Sampler.Type {
 	case "": // not set, do nothing
 	case TraceIDRatio, ParentBasedTraceIDRatio:
@@ -145,14 +314,249 @@ func (r *Instrumentation) validate() error {
 	default:
 		return fmt.Errorf("spec.sampler.type is not valid: %s", r.Spec.Sampler.Type)
 	}
+	if err := r.validateExporter(); err != nil {
+		return err
+	}
+	if err := r.validateSignals(); err != nil {
+		return err
+	}
+	if err := r.validateEnvPolicy(); err != nil {
+		return err
+	}
+
+	if err := validateAttributeMappings("spec.resource.fromLabels", r.Spec.Resource.FromLabels); err != nil {
+		return err
+	}
+	if err := validateAttributeMappings("spec.resource.fromAnnotations", r.Spec.Resource.FromAnnotations); err != nil {
+		return err
+	}
+	if err := validateServiceAttributeSources("spec.resource.serviceName", r.Spec.Resource.ServiceName,
+		ServiceAttributeSourceLabel, ServiceAttributeSourceAnnotation, ServiceAttributeSourceOwner, ServiceAttributeSourcePodName, ServiceAttributeSourceContainerName); err != nil {
+		return err
+	}
+	if err := validateServiceAttributeSources("spec.resource.serviceVersion", r.Spec.Resource.ServiceVersion,
+		ServiceAttributeSourceLabel, ServiceAttributeSourceAnnotation, ServiceAttributeSourceImage); err != nil {
+		return err
+	}
+
+	if err := r.validateJava(); err != nil {
+		return err
+	}
+
 	// validate env vars
 	if err := r.validateEnv(r.Spec.Java.Env); err != nil {
 		return err
 	}
+	if err := r.validateEnv(r.Spec.Python.Env); err != nil {
+		return err
+	}
+	if err := r.validateEnv(r.Spec.NodeJS.Env); err != nil {
+		return err
+	}
+	if err := r.validateEnv(r.Spec.DotNet.Env); err != nil {
+		return err
+	}
+	if err := r.validateEnv(r.Spec.Go.Env); err != nil {
+		return err
+	}
+	if err := r.validateEnv(r.Spec.ApacheHttpd.Env); err != nil {
+		return err
+	}
+	if err := r.validateEnv(r.Spec.Nginx.Env); err != nil {
+		return err
+	}
+
+	// validate web server settings
+	switch r.Spec.ApacheHttpd.Version {
+	case "", "2.2", "2.4":
+	default:
+		return fmt.Errorf("spec.apacheHttpd.version %s is not supported, it must be one of 2.2 or 2.4", r.Spec.ApacheHttpd.Version)
+	}
+	if r.Spec.ApacheHttpd.ConfigPath != "" && !filepath.IsAbs(r.Spec.ApacheHttpd.ConfigPath) {
+		return fmt.Errorf("spec.apacheHttpd.configPath %s must be an absolute path", r.Spec.ApacheHttpd.ConfigPath)
+	}
+	if r.Spec.Nginx.ConfigFile != "" && !filepath.IsAbs(r.Spec.Nginx.ConfigFile) {
+		return fmt.Errorf("spec.nginx.configFile %s must be an absolute path", r.Spec.Nginx.ConfigFile)
+	}
 
 	return nil
 }
 
+func validateAttributeMappings(field string, mappings []AttributeMapping) error {
+	for i, mapping := range mappings {
+		if (mapping.Key == "") == (mapping.KeyRegex == "") {
+			return fmt.Errorf("%s[%d] must define exactly one of key and keyRegex", field, i)
+		}
+		if mapping.KeyRegex != "" {
+			if _, err := regexp.Compile(mapping.KeyRegex); err != nil {
+				return fmt.Errorf("%s[%d].keyRegex is not a valid regular expression: %w", field, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateServiceAttributeSources(field string, sources []ServiceAttributeSource, allowed ...ServiceAttributeSourceType) error {
+	for i, source := range sources {
+		supported := false
+		for _, from := range allowed {
+			supported = supported || source.From == from
+		}
+		if !supported {
+			return fmt.Errorf("%s[%d].from %s is not supported", field, i, source.From)
+		}
+		switch source.From {
+		case ServiceAttributeSourceLabel, ServiceAttributeSourceAnnotation:
+			if source.Key == "" {
+				return fmt.Errorf("%s[%d].key must be set for the %s source", field, i, source.From)
+			}
+		case ServiceAttributeSourceOwner:
+			switch source.Kind {
+			case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "CronJob", "Rollout", "KnativeService":
+			default:
+				return fmt.Errorf("%s[%d].kind %q is not a supported owner kind", field, i, source.Kind)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Instrumentation) validateJava() error {
+	java := r.Spec.Java
+	if java.JVMOptionsEnvName != "" {
+		if errs := validation.IsEnvVarName(java.JVMOptionsEnvName); len(errs) > 0 {
+			return fmt.Errorf("spec.java.jvmOptionsEnvName %s is not a valid env var name: %s", java.JVMOptionsEnvName, strings.Join(errs, ", "))
+		}
+	}
+	if java.AgentPath != "" && !filepath.IsAbs(java.AgentPath) {
+		return fmt.Errorf("spec.java.agentPath %s must be an absolute path", java.AgentPath)
+	}
+	if java.MountPath != "" && (!filepath.IsAbs(java.MountPath) || filepath.Clean(java.MountPath) == "/") {
+		return fmt.Errorf("spec.java.mountPath %s must be an absolute path other than /", java.MountPath)
+	}
+	if java.VolumeSizeLimit != nil && java.VolumeSizeLimit.Sign() <= 0 {
+		return fmt.Errorf("spec.java.volumeSizeLimit %s must be positive", java.VolumeSizeLimit.String())
+	}
+	if java.CSIDriver != "" && java.Delivery != AgentDeliveryImageVolume {
+		return fmt.Errorf("spec.java.csiDriver requires the %s delivery", AgentDeliveryImageVolume)
+	}
+	for i, extension := range java.Extensions {
+		if extension.Image == "" {
+			return fmt.Errorf("spec.java.extensions[%d] image must not be empty", i)
+		}
+		if !filepath.IsAbs(extension.Path) {
+			return fmt.Errorf("spec.java.extensions[%d] path %s must be an absolute path", i, extension.Path)
+		}
+	}
+	return nil
+}
+
+func (r *Instrumentation) validateExporter() error {
+	names := map[string]bool{}
+	for _, header := range r.Spec.Exporter.Headers {
+		if header.Name == "" {
+			return fmt.Errorf("spec.exporter.headers name must not be empty")
+		}
+		if names[strings.ToLower(header.Name)] {
+			return fmt.Errorf("spec.exporter.headers %s is defined more than once", header.Name)
+		}
+		names[strings.ToLower(header.Name)] = true
+		if (header.Value == "") == (header.ValueFrom == nil) {
+			return fmt.Errorf("spec.exporter.headers %s must define exactly one of value or valueFrom", header.Name)
+		}
+		if header.ValueFrom != nil && (header.ValueFrom.Name == "" || header.ValueFrom.Key == "") {
+			return fmt.Errorf("spec.exporter.headers %s valueFrom must reference a Secret name and key", header.Name)
+		}
+	}
+	if r.Spec.Exporter.Timeout != nil && r.Spec.Exporter.Timeout.Duration < 0 {
+		return fmt.Errorf("spec.exporter.timeout must not be negative: %s", r.Spec.Exporter.Timeout.Duration)
+	}
+	if tls := r.Spec.Exporter.TLS; tls != nil {
+		if tls.CA != nil && (tls.CA.Name == "" || tls.CA.Key == "") {
+			return fmt.Errorf("spec.exporter.tls.ca must reference a ConfigMap name and key")
+		}
+		if tls.Insecure != nil && *tls.Insecure && (tls.CA != nil || tls.ClientCertSecret != "") {
+			return fmt.Errorf("spec.exporter.tls.insecure cannot be combined with a CA or a client certificate")
+		}
+	}
+	if r.Spec.Exporter.Agent != nil && r.Spec.Exporter.Agent.Name == "" {
+		return fmt.Errorf("spec.exporter.agent name must not be empty")
+	}
+	return nil
+}
+
+// validateSignals rejects exporter selections that contradict the rest of the exporter configuration, and Application
+// Signals that are enabled without a way to reach their endpoint.
+func (r *Instrumentation) validateSignals() error {
+	signals := []struct {
+		name             string
+		signal           Signal
+		endpointField    string
+		endpoint, envVar string
+	}{
+		{"traces", r.Spec.Traces, "tracesEndpoint", r.Spec.Exporter.TracesEndpoint, "OTEL_TRACES_EXPORTER"},
+		{"metrics", r.Spec.Metrics, "metricsEndpoint", r.Spec.Exporter.MetricsEndpoint, "OTEL_METRICS_EXPORTER"},
+		{"logs", r.Spec.Logs, "logsEndpoint", r.Spec.Exporter.LogsEndpoint, "OTEL_LOGS_EXPORTER"},
+	}
+	for _, s := range signals {
+		if s.signal.Exporter == "" {
+			continue
+		}
+		if s.signal.Exporter != SignalExporterOTLP && s.endpoint != "" {
+			return fmt.Errorf("spec.exporter.%s is set but spec.%s.exporter is %s", s.endpointField, s.name, s.signal.Exporter)
+		}
+		if idx := envIndex(r.Spec.Env, s.envVar); idx != -1 && r.Spec.Env[idx].Value != string(s.signal.Exporter) {
+			return fmt.Errorf("spec.%s.exporter %s conflicts with the %s env var set to %s", s.name, s.signal.Exporter, s.envVar, r.Spec.Env[idx].Value)
+		}
+	}
+
+	appSignals := r.Spec.ApplicationSignals
+	if appSignals == nil {
+		return nil
+	}
+	if appSignals.Enabled == nil {
+		return nil
+	}
+	if !*appSignals.Enabled {
+		if appSignals.RuntimeMetrics != nil && *appSignals.RuntimeMetrics {
+			return fmt.Errorf("spec.applicationSignals.runtimeMetrics requires spec.applicationSignals.enabled")
+		}
+		return nil
+	}
+	resolvesEndpoint := r.Spec.Exporter.Agent != nil || r.Spec.Exporter.Mode == ExporterModeNodeLocal
+	if appSignals.Endpoint == "" && !resolvesEndpoint && envIndex(r.Spec.Env, "OTEL_AWS_SMP_EXPORTER_ENDPOINT") == -1 {
+		return fmt.Errorf("spec.applicationSignals.endpoint must be set when Application Signals are enabled, unless spec.exporter references an agent or uses the nodeLocal mode")
+	}
+	return nil
+}
+
+func (r *Instrumentation) validateEnvPolicy() error {
+	policy := r.Spec.EnvPolicy
+	if policy.Mode == EnvPolicyModeOverride && len(policy.EnforceKeys) > 0 {
+		return fmt.Errorf("spec.envPolicy.enforceKeys cannot be combined with the override mode, which replaces every env var")
+	}
+	keys := map[string]bool{}
+	for _, key := range policy.EnforceKeys {
+		if errs := validation.IsEnvVarName(key); len(errs) > 0 {
+			return fmt.Errorf("spec.envPolicy.enforceKeys %q is not a valid env var name: %s", key, strings.Join(errs, ", "))
+		}
+		if keys[key] {
+			return fmt.Errorf("spec.envPolicy.enforceKeys %s is defined more than once", key)
+		}
+		keys[key] = true
+	}
+	return nil
+}
+
+func envIndex(envs []corev1.EnvVar, name string) int {
+	for i, env := range envs {
+		if env.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 func (r *Instrumentation) validateEnv(envs []corev1.EnvVar) error {
 	for _, env := range envs {
 		if !strings.HasPrefix(env.Name, envPrefix) {
@@ -10,9 +10,25 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentReference) DeepCopyInto(out *AgentReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentReference.
+func (in *AgentReference) DeepCopy() *AgentReference {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AmazonCloudWatchAgent) DeepCopyInto(out *AmazonCloudWatchAgent) {
 	*out = *in
@@ -102,6 +118,11 @@ func (in *AmazonCloudWatchAgentSpec) DeepCopyInto(out *AmazonCloudWatchAgentSpec
 			(*out)[key] = val
 		}
 	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.VolumeMounts != nil {
 		in, out := &in.VolumeMounts, &out.VolumeMounts
 		*out = make([]corev1.VolumeMount, len(*in))
@@ -185,9 +206,203 @@ func (in *AmazonCloudWatchAgentStatus) DeepCopy() *AmazonCloudWatchAgentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheHttpd) DeepCopyInto(out *ApacheHttpd) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Attrs != nil {
+		in, out := &in.Attrs, &out.Attrs
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheHttpd.
+func (in *ApacheHttpd) DeepCopy() *ApacheHttpd {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheHttpd)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSignals) DeepCopyInto(out *ApplicationSignals) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RuntimeMetrics != nil {
+		in, out := &in.RuntimeMetrics, &out.RuntimeMetrics
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSignals.
+func (in *ApplicationSignals) DeepCopy() *ApplicationSignals {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSignals)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AttributeMapping) DeepCopyInto(out *AttributeMapping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttributeMapping.
+func (in *AttributeMapping) DeepCopy() *AttributeMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(AttributeMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterInstrumentation) DeepCopyInto(out *ClusterInstrumentation) {
+	*out = *in
+	out.Status = in.Status
+	out.TypeMeta = in.TypeMeta
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterInstrumentation.
+func (in *ClusterInstrumentation) DeepCopy() *ClusterInstrumentation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterInstrumentation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterInstrumentation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterInstrumentationList) DeepCopyInto(out *ClusterInstrumentationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterInstrumentation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterInstrumentationList.
+func (in *ClusterInstrumentationList) DeepCopy() *ClusterInstrumentationList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterInstrumentationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterInstrumentationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DotNet) DeepCopyInto(out *DotNet) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DotNet.
+func (in *DotNet) DeepCopy() *DotNet {
+	if in == nil {
+		return nil
+	}
+	out := new(DotNet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvPolicy) DeepCopyInto(out *EnvPolicy) {
+	*out = *in
+	if in.EnforceKeys != nil {
+		in, out := &in.EnforceKeys, &out.EnforceKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvPolicy.
+func (in *EnvPolicy) DeepCopy() *EnvPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Exporter) DeepCopyInto(out *Exporter) {
 	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]ExporterHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ExporterTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Agent != nil {
+		in, out := &in.Agent, &out.Agent
+		*out = new(AgentReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Exporter.
@@ -200,6 +415,74 @@ func (in *Exporter) DeepCopy() *Exporter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterHeader) DeepCopyInto(out *ExporterHeader) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterHeader.
+func (in *ExporterHeader) DeepCopy() *ExporterHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterTLS) DeepCopyInto(out *ExporterTLS) {
+	*out = *in
+	if in.CA != nil {
+		in, out := &in.CA, &out.CA
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Insecure != nil {
+		in, out := &in.Insecure, &out.Insecure
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterTLS.
+func (in *ExporterTLS) DeepCopy() *ExporterTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Go) DeepCopyInto(out *Go) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Go.
+func (in *Go) DeepCopy() *Go {
+	if in == nil {
+		return nil
+	}
+	out := new(Go)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Ingress) DeepCopyInto(out *Ingress) {
 	*out = *in
@@ -297,7 +580,20 @@ func (in *InstrumentationList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstrumentationSpec) DeepCopyInto(out *InstrumentationSpec) {
 	*out = *in
-	out.Exporter = in.Exporter
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Exporter.DeepCopyInto(&out.Exporter)
+	out.Traces = in.Traces
+	out.Metrics = in.Metrics
+	out.Logs = in.Logs
+	if in.ApplicationSignals != nil {
+		in, out := &in.ApplicationSignals, &out.ApplicationSignals
+		*out = new(ApplicationSignals)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Resource.DeepCopyInto(&out.Resource)
 	if in.Propagators != nil {
 		in, out := &in.Propagators, &out.Propagators
@@ -312,7 +608,19 @@ func (in *InstrumentationSpec) DeepCopyInto(out *InstrumentationSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.EnvPolicy.DeepCopyInto(&out.EnvPolicy)
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Java.DeepCopyInto(&out.Java)
+	in.Python.DeepCopyInto(&out.Python)
+	in.NodeJS.DeepCopyInto(&out.NodeJS)
+	in.DotNet.DeepCopyInto(&out.DotNet)
+	in.Go.DeepCopyInto(&out.Go)
+	in.ApacheHttpd.DeepCopyInto(&out.ApacheHttpd)
+	in.Nginx.DeepCopyInto(&out.Nginx)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstrumentationSpec.
@@ -343,6 +651,16 @@ func (in *InstrumentationStatus) DeepCopy() *InstrumentationStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Java) DeepCopyInto(out *Java) {
 	*out = *in
+	if in.VolumeSizeLimit != nil {
+		in, out := &in.VolumeSizeLimit, &out.VolumeSizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]JavaExtension, len(*in))
+		copy(*out, *in)
+	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make([]corev1.EnvVar, len(*in))
@@ -363,6 +681,74 @@ func (in *Java) DeepCopy() *Java {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JavaExtension) DeepCopyInto(out *JavaExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JavaExtension.
+func (in *JavaExtension) DeepCopy() *JavaExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(JavaExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Nginx) DeepCopyInto(out *Nginx) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Attrs != nil {
+		in, out := &in.Attrs, &out.Attrs
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Nginx.
+func (in *Nginx) DeepCopy() *Nginx {
+	if in == nil {
+		return nil
+	}
+	out := new(Nginx)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeJS) DeepCopyInto(out *NodeJS) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeJS.
+func (in *NodeJS) DeepCopy() *NodeJS {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeJS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenShiftRoute) DeepCopyInto(out *OpenShiftRoute) {
 	*out = *in
@@ -378,6 +764,29 @@ func (in *OpenShiftRoute) DeepCopy() *OpenShiftRoute {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Python) DeepCopyInto(out *Python) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Python.
+func (in *Python) DeepCopy() *Python {
+	if in == nil {
+		return nil
+	}
+	out := new(Python)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -388,6 +797,26 @@ func (in *Resource) DeepCopyInto(out *Resource) {
 			(*out)[key] = val
 		}
 	}
+	if in.FromLabels != nil {
+		in, out := &in.FromLabels, &out.FromLabels
+		*out = make([]AttributeMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.FromAnnotations != nil {
+		in, out := &in.FromAnnotations, &out.FromAnnotations
+		*out = make([]AttributeMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceName != nil {
+		in, out := &in.ServiceName, &out.ServiceName
+		*out = make([]ServiceAttributeSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceVersion != nil {
+		in, out := &in.ServiceVersion, &out.ServiceVersion
+		*out = make([]ServiceAttributeSource, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Resource.
@@ -429,3 +858,33 @@ func (in *ScaleSubresourceStatus) DeepCopy() *ScaleSubresourceStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAttributeSource) DeepCopyInto(out *ServiceAttributeSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAttributeSource.
+func (in *ServiceAttributeSource) DeepCopy() *ServiceAttributeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAttributeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Signal) DeepCopyInto(out *Signal) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Signal.
+func (in *Signal) DeepCopy() *Signal {
+	if in == nil {
+		return nil
+	}
+	out := new(Signal)
+	in.DeepCopyInto(out)
+	return out
+}
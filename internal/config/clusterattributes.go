@@ -0,0 +1,184 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// eksLabelPrefix prefixes the labels set on the nodes of EKS clusters, e.g. eks.amazonaws.com/nodegroup.
+	eksLabelPrefix = "eks.amazonaws.com/"
+	// eksctlClusterNameLabel is set on the nodes of the EKS clusters created by eksctl.
+	eksctlClusterNameLabel = "alpha.eksctl.io/cluster-name"
+	// gkeNodePoolLabel is set on the nodes of GKE clusters.
+	gkeNodePoolLabel = "cloud.google.com/gke-nodepool"
+	// aksClusterLabel is set on the nodes of AKS clusters.
+	aksClusterLabel = "kubernetes.azure.com/cluster"
+)
+
+// ClusterAttributes are the cloud and cluster resource attributes added to the telemetry of the instrumented pods.
+type ClusterAttributes struct {
+	// CloudProvider is the cloud.provider attribute, e.g. aws.
+	CloudProvider string `json:"cloudProvider,omitempty"`
+	// CloudPlatform is the cloud.platform attribute, e.g. aws_eks.
+	CloudPlatform string `json:"cloudPlatform,omitempty"`
+	// CloudRegion is the cloud.region attribute.
+	CloudRegion string `json:"cloudRegion,omitempty"`
+	// CloudAccountID is the cloud.account.id attribute.
+	CloudAccountID string `json:"cloudAccountId,omitempty"`
+	// ClusterName is the k8s.cluster.name attribute.
+	ClusterName string `json:"clusterName,omitempty"`
+}
+
+// ResourceAttributes returns the attributes that are set.
+func (a ClusterAttributes) ResourceAttributes() map[attribute.Key]string {
+	res := map[attribute.Key]string{}
+	for k, v := range map[attribute.Key]string{
+		semconv.CloudProviderKey:  a.CloudProvider,
+		semconv.CloudPlatformKey:  a.CloudPlatform,
+		semconv.CloudRegionKey:    a.CloudRegion,
+		semconv.CloudAccountIDKey: a.CloudAccountID,
+		semconv.K8SClusterNameKey: a.ClusterName,
+	} {
+		if v != "" {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// orDefault returns the attributes, the empty ones being taken from the defaults.
+func (a ClusterAttributes) orDefault(defaults ClusterAttributes) ClusterAttributes {
+	return ClusterAttributes{
+		CloudProvider:  orDefault(a.CloudProvider, defaults.CloudProvider),
+		CloudPlatform:  orDefault(a.CloudPlatform, defaults.CloudPlatform),
+		CloudRegion:    orDefault(a.CloudRegion, defaults.CloudRegion),
+		CloudAccountID: orDefault(a.CloudAccountID, defaults.CloudAccountID),
+		ClusterName:    orDefault(a.ClusterName, defaults.ClusterName),
+	}
+}
+
+// ClusterAttributes returns the cloud and cluster attributes: those of the operator configuration, then the flags,
+// then the ones detected from the nodes.
+func (c *Config) ClusterAttributes() ClusterAttributes {
+	return c.operatorConfig.Get().Cluster.orDefault(c.clusterAttributes).orDefault(c.detectedClusterAttributes.Get())
+}
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=list
+
+// DetectClusterAttributes learns the cloud and cluster attributes from the provider ID and the labels of a node.
+func (c *Config) DetectClusterAttributes(ctx context.Context, reader client.Reader) error {
+	nodes := &corev1.NodeList{}
+	if err := reader.List(ctx, nodes, client.Limit(1)); err != nil {
+		return fmt.Errorf("failed to list the nodes: %w", err)
+	}
+	if len(nodes.Items) == 0 {
+		return nil
+	}
+	detected := clusterAttributesFromNode(nodes.Items[0])
+	c.logger.V(1).Info("cluster attributes detected", "node", nodes.Items[0].Name, "attributes", detected)
+	c.detectedClusterAttributes.Set(detected)
+	return nil
+}
+
+// clusterAttributesFromNode parses provider IDs such as aws:///us-west-2a/i-0123456789abcdef0,
+// gce://my-project/us-central1-a/gke-node and azure:///subscriptions/<id>/resourceGroups/....
+func clusterAttributesFromNode(node corev1.Node) ClusterAttributes {
+	var attributes ClusterAttributes
+	scheme, path, _ := strings.Cut(node.Spec.ProviderID, "://")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var zone string
+	switch scheme {
+	case "aws":
+		attributes.CloudProvider = "aws"
+		zone = segments[0]
+		if hasLabelPrefix(node, eksLabelPrefix) {
+			attributes.CloudPlatform = "aws_eks"
+		}
+		attributes.ClusterName = node.Labels[eksctlClusterNameLabel]
+	case "gce":
+		attributes.CloudProvider = "gcp"
+		attributes.CloudAccountID = segments[0]
+		if len(segments) > 1 {
+			zone = segments[1]
+		}
+		if _, ok := node.Labels[gkeNodePoolLabel]; ok {
+			attributes.CloudPlatform = "gcp_kubernetes_engine"
+		}
+	case "azure":
+		attributes.CloudProvider = "azure"
+		if len(segments) > 1 && strings.EqualFold(segments[0], "subscriptions") {
+			attributes.CloudAccountID = segments[1]
+		}
+		if _, ok := node.Labels[aksClusterLabel]; ok {
+			attributes.CloudPlatform = "azure_aks"
+		}
+	}
+
+	attributes.CloudRegion = orDefault(node.Labels[corev1.LabelTopologyRegion], node.Labels[corev1.LabelFailureDomainBetaRegion])
+	if attributes.CloudRegion == "" && zone != "" {
+		attributes.CloudRegion = regionOfZone(scheme, zone)
+	}
+	return attributes
+}
+
+// regionOfZone returns the region of an availability zone, e.g. us-west-2 for us-west-2a on AWS, or us-central1
+// for us-central1-a on GCP.
+func regionOfZone(scheme string, zone string) string {
+	if scheme == "gce" {
+		if i := strings.LastIndex(zone, "-"); i > 0 {
+			return zone[:i]
+		}
+		return ""
+	}
+	if last := zone[len(zone)-1]; last >= 'a' && last <= 'z' {
+		return zone[:len(zone)-1]
+	}
+	return ""
+}
+
+func hasLabelPrefix(node corev1.Node, prefix string) bool {
+	for label := range node.Labels {
+		if strings.HasPrefix(label, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type clusterAttributesStore interface {
+	Set(attributes ClusterAttributes)
+	Get() ClusterAttributes
+}
+
+func newClusterAttributesWrapper() clusterAttributesStore {
+	return &clusterAttributesWrapper{}
+}
+
+type clusterAttributesWrapper struct {
+	mu         sync.Mutex
+	attributes ClusterAttributes
+}
+
+func (p *clusterAttributesWrapper) Set(attributes ClusterAttributes) {
+	p.mu.Lock()
+	p.attributes = attributes
+	p.mu.Unlock()
+}
+
+func (p *clusterAttributesWrapper) Get() ClusterAttributes {
+	p.mu.Lock()
+	attributes := p.attributes
+	p.mu.Unlock()
+	return attributes
+}
@@ -0,0 +1,82 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClusterAttributesFromNode(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		providerID string
+		labels     map[string]string
+		expected   ClusterAttributes
+	}{
+		{
+			name:       "eks",
+			providerID: "aws:///us-west-2a/i-0123456789abcdef0",
+			labels:     map[string]string{"eks.amazonaws.com/nodegroup": "default", "alpha.eksctl.io/cluster-name": "prod"},
+			expected:   ClusterAttributes{CloudProvider: "aws", CloudPlatform: "aws_eks", CloudRegion: "us-west-2", ClusterName: "prod"},
+		},
+		{
+			name:       "aws with topology labels",
+			providerID: "aws:///eu-west-1b/i-0123456789abcdef0",
+			labels:     map[string]string{corev1.LabelTopologyRegion: "eu-west-1"},
+			expected:   ClusterAttributes{CloudProvider: "aws", CloudRegion: "eu-west-1"},
+		},
+		{
+			name:       "gke",
+			providerID: "gce://shop-prod/us-central1-a/gke-prod-default-pool-1a2b3c4d-x9z8",
+			labels:     map[string]string{"cloud.google.com/gke-nodepool": "default-pool"},
+			expected:   ClusterAttributes{CloudProvider: "gcp", CloudPlatform: "gcp_kubernetes_engine", CloudRegion: "us-central1", CloudAccountID: "shop-prod"},
+		},
+		{
+			name:       "aks",
+			providerID: "azure:///subscriptions/0a1b2c3d/resourceGroups/mc_shop/providers/Microsoft.Compute/virtualMachineScaleSets/aks-default/virtualMachines/0",
+			labels:     map[string]string{"kubernetes.azure.com/cluster": "MC_shop", corev1.LabelFailureDomainBetaRegion: "westeurope"},
+			expected:   ClusterAttributes{CloudProvider: "azure", CloudPlatform: "azure_aks", CloudRegion: "westeurope", CloudAccountID: "0a1b2c3d"},
+		},
+		{
+			name:     "unknown provider",
+			expected: ClusterAttributes{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}, Spec: corev1.NodeSpec{ProviderID: tt.providerID}}
+			assert.Equal(t, tt.expected, clusterAttributesFromNode(node))
+		})
+	}
+}
+
+func TestClusterAttributes(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"eks.amazonaws.com/nodegroup": "default"}},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-west-2a/i-0123456789abcdef0"},
+	}
+	cfg := New(
+		WithClusterAttributes(ClusterAttributes{CloudRegion: "us-east-1", ClusterName: "flag"}),
+		WithOperatorConfig(&OperatorConfig{Cluster: ClusterAttributes{ClusterName: "prod", CloudAccountID: "123456789012"}}),
+	)
+	assert.Equal(t, ClusterAttributes{CloudRegion: "us-east-1", ClusterName: "prod", CloudAccountID: "123456789012"}, cfg.ClusterAttributes())
+
+	require.NoError(t, cfg.DetectClusterAttributes(context.Background(), fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(node).Build()))
+	assert.Equal(t, ClusterAttributes{
+		CloudProvider:  "aws",
+		CloudPlatform:  "aws_eks",
+		CloudRegion:    "us-east-1",
+		CloudAccountID: "123456789012",
+		ClusterName:    "prod",
+	}, cfg.ClusterAttributes())
+	assert.Equal(t, "aws_eks", cfg.ClusterAttributes().ResourceAttributes()["cloud.platform"])
+	assert.Len(t, cfg.ClusterAttributes().ResourceAttributes(), 5)
+}
@@ -0,0 +1,63 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+)
+
+// imageVolumesMinVersion is the first Kubernetes version enabling the ImageVolume feature gate by default.
+var imageVolumesMinVersion = utilversion.MustParseGeneric("1.35.0")
+
+// DetectImageVolumes checks whether the API server is recent enough to mount images as volumes.
+func (c *Config) DetectImageVolumes(dcl discovery.ServerVersionInterface) error {
+	info, err := dcl.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get the server version: %w", err)
+	}
+	serverVersion, err := utilversion.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse the server version: %w", err)
+	}
+	available := serverVersion.AtLeast(imageVolumesMinVersion)
+	c.logger.V(1).Info("image volumes detection", "serverVersion", info.GitVersion, "available", available)
+	c.imageVolumes.Set(available)
+	return nil
+}
+
+// ImageVolumesAvailable tells whether container images can be mounted as volumes in the pods.
+func (c *Config) ImageVolumesAvailable() bool {
+	return c.imageVolumes.Get()
+}
+
+type imageVolumesStore interface {
+	Set(available bool)
+	Get() bool
+}
+
+func newImageVolumesWrapper() imageVolumesStore {
+	return &imageVolumesWrapper{}
+}
+
+type imageVolumesWrapper struct {
+	mu        sync.Mutex
+	available bool
+}
+
+func (p *imageVolumesWrapper) Set(available bool) {
+	p.mu.Lock()
+	p.available = available
+	p.mu.Unlock()
+}
+
+func (p *imageVolumesWrapper) Get() bool {
+	p.mu.Lock()
+	available := p.available
+	p.mu.Unlock()
+	return available
+}
@@ -0,0 +1,46 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+func TestDetectImageVolumes(t *testing.T) {
+	for _, tt := range []struct {
+		gitVersion string
+		available  bool
+	}{
+		{gitVersion: "v1.29.4-eks-036c24b", available: false},
+		{gitVersion: "v1.34.1", available: false},
+		{gitVersion: "v1.35.0", available: true},
+		{gitVersion: "v1.36.2-eks-1a2b3c4", available: true},
+	} {
+		t.Run(tt.gitVersion, func(t *testing.T) {
+			dcl := &fakediscovery.FakeDiscovery{
+				Fake:               &kubetesting.Fake{},
+				FakedServerVersion: &apiversion.Info{GitVersion: tt.gitVersion},
+			}
+			cfg := New(WithImageVolumes(!tt.available))
+			require.NoError(t, cfg.DetectImageVolumes(dcl))
+			assert.Equal(t, tt.available, cfg.ImageVolumesAvailable())
+		})
+	}
+}
+
+func TestDetectImageVolumesInvalidVersion(t *testing.T) {
+	dcl := &fakediscovery.FakeDiscovery{
+		Fake:               &kubetesting.Fake{},
+		FakedServerVersion: &apiversion.Info{GitVersion: "unknown"},
+	}
+	cfg := New()
+	assert.Error(t, cfg.DetectImageVolumes(dcl))
+	assert.False(t, cfg.ImageVolumesAvailable())
+}
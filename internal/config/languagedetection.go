@@ -0,0 +1,117 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// LanguageDetectionRule describes the signals used to recognise the runtime of a container when the
+// instrumentation.opentelemetry.io/inject-auto annotation is used.
+type LanguageDetectionRule struct {
+	// Language is the instrumentation to inject when the rule matches, one of java, python, nodejs or dotnet.
+	Language string `yaml:"language" json:"language"`
+	// Commands are executable names looked up in the container command and args, e.g. "java".
+	// Versioned executables such as "python3.11" match "python".
+	Commands []string `yaml:"commands,omitempty" json:"commands,omitempty"`
+	// EnvVars are environment variable names whose presence in the container indicates the language.
+	EnvVars []string `yaml:"envVars,omitempty" json:"envVars,omitempty"`
+	// Images are regular expressions matched against the container image.
+	Images []string `yaml:"images,omitempty" json:"images,omitempty"`
+
+	// imagePatterns are the compiled Images, set when the rules are validated.
+	imagePatterns []*regexp.Regexp
+}
+
+// MatchesImage reports whether one of the image patterns of the rule matches the image. Only the patterns of
+// validated rules, such as the loaded and default ones, are compiled; the others never match.
+func (r LanguageDetectionRule) MatchesImage(image string) bool {
+	for _, pattern := range r.imagePatterns {
+		if pattern.MatchString(image) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultLanguageDetectionRules are compiled once, the rules being evaluated on every admission.
+var defaultLanguageDetectionRules = mustCompileLanguageDetectionRules([]LanguageDetectionRule{
+	{
+		Language: "java",
+		Commands: []string{"java"},
+		EnvVars:  []string{"JAVA_HOME", "JAVA_VERSION", "JAVA_TOOL_OPTIONS"},
+		Images:   []string{`(^|/)(openjdk|eclipse-temurin|amazoncorretto|java|tomcat|jetty)[^/]*(:|@|$)`},
+	},
+	{
+		Language: "python",
+		Commands: []string{"python", "gunicorn", "uvicorn", "flask", "django-admin"},
+		EnvVars:  []string{"PYTHONPATH", "PYTHON_VERSION"},
+		Images:   []string{`(^|/)python[^/]*(:|@|$)`},
+	},
+	{
+		Language: "nodejs",
+		Commands: []string{"node", "npm", "yarn"},
+		EnvVars:  []string{"NODE_VERSION", "NODE_ENV"},
+		Images:   []string{`(^|/)node[^/]*(:|@|$)`},
+	},
+	{
+		Language: "dotnet",
+		Commands: []string{"dotnet"},
+		EnvVars:  []string{"DOTNET_VERSION", "ASPNETCORE_URLS", "ASPNET_VERSION"},
+		Images:   []string{`(^|/)dotnet/(aspnet|runtime|sdk)(:|@|$)`},
+	},
+})
+
+// DefaultLanguageDetectionRules returns the rule set used when none is configured.
+func DefaultLanguageDetectionRules() []LanguageDetectionRule {
+	return defaultLanguageDetectionRules
+}
+
+// LoadLanguageDetectionRules reads a YAML list of rules from the given file.
+func LoadLanguageDetectionRules(path string) ([]LanguageDetectionRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []LanguageDetectionRule
+	if err := yaml.UnmarshalStrict(content, &rules); err != nil {
+		return nil, fmt.Errorf("couldn't parse the language detection rules in %s: %w", path, err)
+	}
+	if err := validateLanguageDetectionRules(rules); err != nil {
+		return nil, fmt.Errorf("invalid language detection rules in %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// validateLanguageDetectionRules checks the rules and compiles their image patterns in place.
+func validateLanguageDetectionRules(rules []LanguageDetectionRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Language {
+		case "java", "python", "nodejs", "dotnet":
+		default:
+			return fmt.Errorf("language %q is not supported", rule.Language)
+		}
+		rule.imagePatterns = make([]*regexp.Regexp, 0, len(rule.Images))
+		for _, image := range rule.Images {
+			pattern, err := regexp.Compile(image)
+			if err != nil {
+				return fmt.Errorf("image pattern %q for %s: %w", image, rule.Language, err)
+			}
+			rule.imagePatterns = append(rule.imagePatterns, pattern)
+		}
+	}
+	return nil
+}
+
+func mustCompileLanguageDetectionRules(rules []LanguageDetectionRule) []LanguageDetectionRule {
+	if err := validateLanguageDetectionRules(rules); err != nil {
+		panic(err)
+	}
+	return rules
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/open-telemetry/opentelemetry-operator/pkg/autodetect"
+	corev1 "k8s.io/api/core/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/version"
@@ -32,14 +33,21 @@ type Config struct {
 	autoInstrumentationDotNetImage      string
 	autoInstrumentationGoImage          string
 	autoInstrumentationApacheHttpdImage string
+	autoInstrumentationNginxImage       string
 	targetAllocatorConfigMapEntry       string
 	autoInstrumentationNodeJSImage      string
 	autoInstrumentationJavaImage        string
 	onOpenShiftRoutesChange             changeHandler
+	onOperatorConfigChange              changeHandler
 	labelsFilter                        []string
+	languageDetectionRules              []LanguageDetectionRule
+	operatorConfig                      operatorConfigStore
 	openshiftRoutes                     openshiftRoutesStore
 	autoDetectFrequency                 time.Duration
 	hpaVersion                          hpaVersionStore
+	imageVolumes                        imageVolumesStore
+	clusterAttributes                   ClusterAttributes
+	detectedClusterAttributes           clusterAttributesStore
 }
 
 // New constructs a new configuration based on the given options.
@@ -51,6 +59,7 @@ func New(opts ...Option) Config {
 		logger:                  logf.Log.WithName("config"),
 		openshiftRoutes:         newOpenShiftRoutesWrapper(),
 		hpaVersion:              newHPAVersionWrapper(),
+		imageVolumes:            newImageVolumesWrapper(),
 		version:                 version.Get(),
 		onOpenShiftRoutesChange: newOnChange(),
 	}
@@ -58,6 +67,11 @@ func New(opts ...Option) Config {
 		opt(&o)
 	}
 
+	operatorConfig := newOperatorConfigWrapper()
+	if o.operatorConfig != nil {
+		operatorConfig.Set(o.operatorConfig)
+	}
+
 	return Config{
 		autoDetect:                          o.autoDetect,
 		autoDetectFrequency:                 o.autoDetectFrequency,
@@ -69,13 +83,21 @@ func New(opts ...Option) Config {
 		logger:                              o.logger,
 		openshiftRoutes:                     o.openshiftRoutes,
 		hpaVersion:                          o.hpaVersion,
+		imageVolumes:                        o.imageVolumes,
+		clusterAttributes:                   o.clusterAttributes,
+		detectedClusterAttributes:           newClusterAttributesWrapper(),
 		onOpenShiftRoutesChange:             o.onOpenShiftRoutesChange,
+		onOperatorConfigChange:              newOnChange(),
 		autoInstrumentationJavaImage:        o.autoInstrumentationJavaImage,
 		autoInstrumentationNodeJSImage:      o.autoInstrumentationNodeJSImage,
 		autoInstrumentationPythonImage:      o.autoInstrumentationPythonImage,
 		autoInstrumentationDotNetImage:      o.autoInstrumentationDotNetImage,
+		autoInstrumentationGoImage:          o.autoInstrumentationGoImage,
 		autoInstrumentationApacheHttpdImage: o.autoInstrumentationApacheHttpdImage,
+		autoInstrumentationNginxImage:       o.autoInstrumentationNginxImage,
 		labelsFilter:                        o.labelsFilter,
+		languageDetectionRules:              o.languageDetectionRules,
+		operatorConfig:                      operatorConfig,
 	}
 }
 
@@ -130,7 +152,7 @@ func (c *Config) AutoDetect() error {
 
 // CollectorImage represents the flag to override the OpenTelemetry Collector container image.
 func (c *Config) CollectorImage() string {
-	return c.collectorImage
+	return orDefault(c.operatorConfig.Get().Images.Agent, c.collectorImage)
 }
 
 // CollectorConfigMapEntry represents the configuration file name for the collector. Immutable.
@@ -160,45 +182,86 @@ func (c *Config) AutoscalingVersion() autodetect.AutoscalingVersion {
 
 // AutoInstrumentationJavaImage returns OpenTelemetry Java auto-instrumentation container image.
 func (c *Config) AutoInstrumentationJavaImage() string {
-	return c.autoInstrumentationJavaImage
+	return orDefault(c.operatorConfig.Get().Images.Java, c.autoInstrumentationJavaImage)
 }
 
 // AutoInstrumentationNodeJSImage returns OpenTelemetry NodeJS auto-instrumentation container image.
 func (c *Config) AutoInstrumentationNodeJSImage() string {
-	return c.autoInstrumentationNodeJSImage
+	return orDefault(c.operatorConfig.Get().Images.NodeJS, c.autoInstrumentationNodeJSImage)
 }
 
 // AutoInstrumentationPythonImage returns OpenTelemetry Python auto-instrumentation container image.
 func (c *Config) AutoInstrumentationPythonImage() string {
-	return c.autoInstrumentationPythonImage
+	return orDefault(c.operatorConfig.Get().Images.Python, c.autoInstrumentationPythonImage)
 }
 
 // AutoInstrumentationDotNetImage returns OpenTelemetry DotNet auto-instrumentation container image.
 func (c *Config) AutoInstrumentationDotNetImage() string {
-	return c.autoInstrumentationDotNetImage
+	return orDefault(c.operatorConfig.Get().Images.DotNet, c.autoInstrumentationDotNetImage)
 }
 
 // AutoInstrumentationGoImage returns OpenTelemetry Go auto-instrumentation container image.
 func (c *Config) AutoInstrumentationGoImage() string {
-	return c.autoInstrumentationGoImage
+	return orDefault(c.operatorConfig.Get().Images.Go, c.autoInstrumentationGoImage)
 }
 
 // AutoInstrumentationApacheHttpdImage returns OpenTelemetry ApacheHttpd auto-instrumentation container image.
 func (c *Config) AutoInstrumentationApacheHttpdImage() string {
-	return c.autoInstrumentationApacheHttpdImage
+	return orDefault(c.operatorConfig.Get().Images.ApacheHttpd, c.autoInstrumentationApacheHttpdImage)
+}
+
+// AutoInstrumentationNginxImage returns OpenTelemetry Nginx auto-instrumentation container image.
+func (c *Config) AutoInstrumentationNginxImage() string {
+	return orDefault(c.operatorConfig.Get().Images.Nginx, c.autoInstrumentationNginxImage)
+}
+
+// LanguageDetectionRules returns the rules used to infer the runtime of a container, falling back to the default rule set.
+func (c *Config) LanguageDetectionRules() []LanguageDetectionRule {
+	if rules := c.operatorConfig.Get().Webhook.LanguageDetectionRules; len(rules) > 0 {
+		return rules
+	}
+	if len(c.languageDetectionRules) == 0 {
+		return DefaultLanguageDetectionRules()
+	}
+	return c.languageDetectionRules
 }
 
 // LabelsFilter Returns the filters converted to regex strings used to filter out unwanted labels from propagations.
 func (c *Config) LabelsFilter() []string {
+	if labelFilters := c.operatorConfig.Get().LabelFilters; len(labelFilters) > 0 {
+		return labelFiltersToRegex(labelFilters)
+	}
 	return c.labelsFilter
 }
 
+// DefaultInstrumentation returns the settings of the default Instrumentation from the operator configuration file.
+func (c *Config) DefaultInstrumentation() DefaultInstrumentation {
+	return c.operatorConfig.Get().DefaultInstrumentation
+}
+
+// AgentResources returns the default resources of the agent container, if configured.
+func (c *Config) AgentResources() *corev1.ResourceRequirements {
+	return c.operatorConfig.Get().AgentResources
+}
+
+// IgnoredNamespaces returns the namespaces whose pods are never mutated by the webhook.
+func (c *Config) IgnoredNamespaces() []string {
+	return c.operatorConfig.Get().Webhook.IgnoredNamespaces
+}
+
 // RegisterOpenShiftRoutesChangeCallback registers the given function as a callback that
 // is called when the OpenShift Routes detection detects a change.
 func (c *Config) RegisterOpenShiftRoutesChangeCallback(f func() error) {
 	c.onOpenShiftRoutesChange.Register(f)
 }
 
+func orDefault(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	return defaultValue
+}
+
 type hpaVersionStore interface {
 	Set(hpaV autodetect.AutoscalingVersion)
 	Get() autodetect.AutoscalingVersion
@@ -0,0 +1,175 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// OperatorConfig is the content of the operator configuration file, usually mounted from a ConfigMap. Its values
+// take precedence over the command line flags, and the file is reloaded whenever it changes.
+type OperatorConfig struct {
+	// Images are the default agent and auto-instrumentation images.
+	Images Images `json:"images,omitempty"`
+	// DefaultInstrumentation holds the settings of the Instrumentation used when none exists in the namespace.
+	DefaultInstrumentation DefaultInstrumentation `json:"defaultInstrumentation,omitempty"`
+	// AgentResources are the resources of the agent container when the AmazonCloudWatchAgent doesn't define them.
+	AgentResources *corev1.ResourceRequirements `json:"agentResources,omitempty"`
+	// LabelFilters are the patterns of the labels that aren't propagated to the agent workloads, "*" being a wildcard.
+	LabelFilters []string `json:"labelFilters,omitempty"`
+	// Webhook configures the pod mutating webhook.
+	Webhook Webhook `json:"webhook,omitempty"`
+	// Cluster holds the cloud and cluster attributes added to the telemetry of the instrumented pods.
+	Cluster ClusterAttributes `json:"cluster,omitempty"`
+}
+
+// Images holds the default container images.
+type Images struct {
+	Agent       string `json:"agent,omitempty"`
+	Java        string `json:"java,omitempty"`
+	Python      string `json:"python,omitempty"`
+	NodeJS      string `json:"nodejs,omitempty"`
+	DotNet      string `json:"dotnet,omitempty"`
+	Go          string `json:"go,omitempty"`
+	ApacheHttpd string `json:"apacheHttpd,omitempty"`
+	Nginx       string `json:"nginx,omitempty"`
+}
+
+// DefaultInstrumentation holds the exporter and sampler settings of the default Instrumentation. Empty values keep
+// the built-in defaults.
+type DefaultInstrumentation struct {
+	// Sampler is the value of OTEL_TRACES_SAMPLER.
+	Sampler string `json:"sampler,omitempty"`
+	// SamplerArg is the value of OTEL_TRACES_SAMPLER_ARG.
+	SamplerArg string `json:"samplerArg,omitempty"`
+	// SmpEnabled turns the Application Signals of the default Instrumentation on or off.
+	SmpEnabled *bool `json:"smpEnabled,omitempty"`
+	// TracesEndpoint is the OTLP/gRPC traces endpoint, used by the SDKs exporting over gRPC.
+	TracesEndpoint string `json:"tracesEndpoint,omitempty"`
+	// HTTPTracesEndpoint is the OTLP/HTTP traces endpoint, used by the SDKs exporting over HTTP.
+	HTTPTracesEndpoint string `json:"httpTracesEndpoint,omitempty"`
+	// SmpEndpoint is the OTLP/gRPC endpoint of the service metrics.
+	SmpEndpoint string `json:"smpEndpoint,omitempty"`
+	// HTTPSmpEndpoint is the OTLP/HTTP endpoint of the service metrics.
+	HTTPSmpEndpoint string `json:"httpSmpEndpoint,omitempty"`
+	// MetricsExporter is the metrics exporter of the default Instrumentation: otlp, none or logging.
+	MetricsExporter string `json:"metricsExporter,omitempty"`
+}
+
+// Webhook configures the pod mutating webhook.
+type Webhook struct {
+	// IgnoredNamespaces are the namespaces whose pods are never mutated.
+	IgnoredNamespaces []string `json:"ignoredNamespaces,omitempty"`
+	// LanguageDetectionRules replace the rules used to detect the runtime of containers.
+	LanguageDetectionRules []LanguageDetectionRule `json:"languageDetectionRules,omitempty"`
+}
+
+// LoadOperatorConfig reads and validates the operator configuration file.
+func LoadOperatorConfig(path string) (*OperatorConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	operatorConfig := &OperatorConfig{}
+	if err := yaml.UnmarshalStrict(content, operatorConfig); err != nil {
+		return nil, fmt.Errorf("couldn't parse the operator configuration in %s: %w", path, err)
+	}
+	if err := validateLanguageDetectionRules(operatorConfig.Webhook.LanguageDetectionRules); err != nil {
+		return nil, fmt.Errorf("invalid language detection rules in %s: %w", path, err)
+	}
+	return operatorConfig, nil
+}
+
+// WatchOperatorConfig reloads the operator configuration file whenever it changes, until the context is done. The
+// directory of the file is watched, as the kubelet updates ConfigMap volumes by swapping a symlink. A file that can't
+// be loaded is reported and the previous configuration is kept.
+func (c *Config) WatchOperatorConfig(ctx context.Context, path string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			c.reloadOperatorConfig(path)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			c.logger.Error(err, "failed to watch the operator configuration", "path", path)
+		}
+	}
+}
+
+func (c *Config) reloadOperatorConfig(path string) {
+	operatorConfig, err := LoadOperatorConfig(path)
+	if err != nil {
+		c.logger.Error(err, "failed to reload the operator configuration, keeping the previous one", "path", path)
+		return
+	}
+	if c.operatorConfig.Set(operatorConfig) {
+		c.logger.Info("operator configuration reloaded", "path", path)
+		if err = c.onOperatorConfigChange.Do(); err != nil {
+			c.logger.Error(err, "configuration change notification failed for callback")
+		}
+	}
+}
+
+// OnOperatorConfigChange registers a callback executed whenever the reloaded operator configuration file differs
+// from the previous one.
+func (c *Config) OnOperatorConfigChange(f func() error) {
+	c.onOperatorConfigChange.Register(f)
+}
+
+type operatorConfigStore interface {
+	// Set stores the configuration and tells whether it differs from the previous one.
+	Set(operatorConfig *OperatorConfig) bool
+	Get() OperatorConfig
+}
+
+func newOperatorConfigWrapper() operatorConfigStore {
+	return &operatorConfigWrapper{}
+}
+
+type operatorConfigWrapper struct {
+	mu      sync.Mutex
+	current OperatorConfig
+}
+
+func (p *operatorConfigWrapper) Set(operatorConfig *OperatorConfig) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous, _ := yaml.Marshal(p.current)
+	next, _ := yaml.Marshal(operatorConfig)
+	p.current = *operatorConfig
+	return string(previous) != string(next)
+}
+
+func (p *operatorConfigWrapper) Get() OperatorConfig {
+	p.mu.Lock()
+	operatorConfig := p.current
+	p.mu.Unlock()
+	return operatorConfig
+}
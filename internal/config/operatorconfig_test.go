@@ -0,0 +1,134 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const operatorConfigContent = `
+images:
+  java: java:2
+defaultInstrumentation:
+  samplerArg: "0.1"
+  smpEnabled: false
+agentResources:
+  limits:
+    memory: 512Mi
+labelFilters: ["app.kubernetes.io/*"]
+webhook:
+  ignoredNamespaces: ["kube-system"]
+  languageDetectionRules:
+  - language: java
+    commands: ["java"]
+    images: ["(^|/)openjdk:"]
+`
+
+func TestLoadOperatorConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: operatorConfigContent},
+		{name: "empty", content: ""},
+		{name: "unknown field", content: "image: {}", wantErr: true},
+		{name: "unsupported language", content: "webhook:\n  languageDetectionRules:\n  - language: rust", wantErr: true},
+		{name: "invalid image pattern", content: "webhook:\n  languageDetectionRules:\n  - language: java\n    images: ['jdk(']", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(test.content), 0600))
+			_, err := LoadOperatorConfig(path)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOperatorConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(operatorConfigContent), 0600))
+	operatorConfig, err := LoadOperatorConfig(path)
+	require.NoError(t, err)
+
+	cfg := New(
+		WithAutoInstrumentationJavaImage("java:1"),
+		WithAutoInstrumentationPythonImage("python:1"),
+		WithLabelFilters([]string{"team"}),
+		WithOperatorConfig(operatorConfig),
+	)
+	assert.Equal(t, "java:2", cfg.AutoInstrumentationJavaImage())
+	assert.Equal(t, "python:1", cfg.AutoInstrumentationPythonImage())
+	assert.Equal(t, []string{`app\.kubernetes\.io/.*`}, cfg.LabelsFilter())
+	assert.Equal(t, "0.1", cfg.DefaultInstrumentation().SamplerArg)
+	assert.False(t, *cfg.DefaultInstrumentation().SmpEnabled)
+	assert.Equal(t, resource.MustParse("512Mi"), cfg.AgentResources().Limits.Memory().DeepCopy())
+	assert.Equal(t, []string{"kube-system"}, cfg.IgnoredNamespaces())
+	require.Len(t, cfg.LanguageDetectionRules(), 1)
+	assert.True(t, cfg.LanguageDetectionRules()[0].MatchesImage("openjdk:17"))
+
+	defaults := New(WithAutoInstrumentationJavaImage("java:1"))
+	assert.Equal(t, "java:1", defaults.AutoInstrumentationJavaImage())
+	assert.Nil(t, defaults.AgentResources())
+	assert.Equal(t, DefaultLanguageDetectionRules(), defaults.LanguageDetectionRules())
+}
+
+func TestWatchOperatorConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("images:\n  java: java:1\n"), 0600))
+	operatorConfig, err := LoadOperatorConfig(path)
+	require.NoError(t, err)
+	cfg := New(WithOperatorConfig(operatorConfig))
+	var changes atomic.Int32
+	cfg.OnOperatorConfigChange(func() error {
+		changes.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- cfg.WatchOperatorConfig(ctx, path)
+	}()
+
+	// files are replaced atomically, as the kubelet does for ConfigMaps, so that a truncated file is never read
+	writeFile := func(content string) {
+		tmp := path + ".tmp"
+		require.NoError(t, os.WriteFile(tmp, []byte(content), 0600))
+		require.NoError(t, os.Rename(tmp, path))
+	}
+
+	// an invalid file keeps the previous configuration
+	assert.Eventually(t, func() bool {
+		writeFile("images: [")
+		return cfg.AutoInstrumentationJavaImage() == "java:1"
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Zero(t, changes.Load())
+
+	assert.Eventually(t, func() bool {
+		writeFile("images:\n  java: java:2\n")
+		return cfg.AutoInstrumentationJavaImage() == "java:2"
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return changes.Load() > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
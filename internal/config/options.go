@@ -27,6 +27,7 @@ type options struct {
 	autoInstrumentationNodeJSImage      string
 	autoInstrumentationPythonImage      string
 	autoInstrumentationApacheHttpdImage string
+	autoInstrumentationNginxImage       string
 	collectorImage                      string
 	collectorConfigMapEntry             string
 	targetAllocatorConfigMapEntry       string
@@ -34,8 +35,12 @@ type options struct {
 	operatorOpAMPBridgeImage            string
 	onOpenShiftRoutesChange             changeHandler
 	labelsFilter                        []string
+	languageDetectionRules              []LanguageDetectionRule
+	operatorConfig                      *OperatorConfig
 	openshiftRoutes                     openshiftRoutesStore
 	hpaVersion                          hpaVersionStore
+	imageVolumes                        imageVolumesStore
+	clusterAttributes                   ClusterAttributes
 	autoDetectFrequency                 time.Duration
 }
 
@@ -84,6 +89,11 @@ func WithPlatform(ora autodetect.OpenShiftRoutesAvailability) Option {
 		o.openshiftRoutes.Set(ora)
 	}
 }
+func WithImageVolumes(available bool) Option {
+	return func(o *options) {
+		o.imageVolumes.Set(available)
+	}
+}
 func WithVersion(v version.Version) Option {
 	return func(o *options) {
 		o.version = v
@@ -126,27 +136,56 @@ func WithAutoInstrumentationApacheHttpdImage(s string) Option {
 	}
 }
 
+func WithAutoInstrumentationNginxImage(s string) Option {
+	return func(o *options) {
+		o.autoInstrumentationNginxImage = s
+	}
+}
+
+func WithLanguageDetectionRules(rules []LanguageDetectionRule) Option {
+	return func(o *options) {
+		o.languageDetectionRules = rules
+	}
+}
+
 func WithLabelFilters(labelFilters []string) Option {
 	return func(o *options) {
+		o.labelsFilter = labelFiltersToRegex(labelFilters)
+	}
+}
 
-		filters := []string{}
-		for _, pattern := range labelFilters {
-			var result strings.Builder
+// WithClusterAttributes sets the cloud and cluster attributes given by flags.
+func WithClusterAttributes(attributes ClusterAttributes) Option {
+	return func(o *options) {
+		o.clusterAttributes = attributes
+	}
+}
 
-			for i, literal := range strings.Split(pattern, "*") {
+// WithOperatorConfig sets the initial content of the operator configuration file.
+func WithOperatorConfig(operatorConfig *OperatorConfig) Option {
+	return func(o *options) {
+		o.operatorConfig = operatorConfig
+	}
+}
+
+// labelFiltersToRegex converts the label patterns, where "*" is a wildcard, to regular expressions.
+func labelFiltersToRegex(labelFilters []string) []string {
+	filters := []string{}
+	for _, pattern := range labelFilters {
+		var result strings.Builder
 
-				// Replace * with .*
-				if i > 0 {
-					result.WriteString(".*")
-				}
+		for i, literal := range strings.Split(pattern, "*") {
 
-				// Quote any regular expression meta characters in the
-				// literal text.
-				result.WriteString(regexp.QuoteMeta(literal))
+			// Replace * with .*
+			if i > 0 {
+				result.WriteString(".*")
 			}
-			filters = append(filters, result.String())
-		}
 
-		o.labelsFilter = filters
+			// Quote any regular expression meta characters in the
+			// literal text.
+			result.WriteString(regexp.QuoteMeta(literal))
+		}
+		filters = append(filters, result.String())
 	}
+	return filters
 }
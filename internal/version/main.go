@@ -10,40 +10,64 @@ import (
 )
 
 var (
-	version                 string
-	buildDate               string
-	agent                   string
-	autoInstrumentationJava string
+	version                        string
+	buildDate                      string
+	agent                          string
+	autoInstrumentationJava        string
+	autoInstrumentationPython      string
+	autoInstrumentationNodeJS      string
+	autoInstrumentationDotNet      string
+	autoInstrumentationGo          string
+	autoInstrumentationApacheHttpd string
+	autoInstrumentationNginx       string
 )
 
 // Version holds this Operator's version as well as the version of some of the components it uses.
 type Version struct {
-	Operator                string `json:"amazon-cloudwatch-agent-operator"`
-	BuildDate               string `json:"build-date"`
-	AmazonCloudWatchAgent   string `json:"amazon-cloudwatch-agent-version"`
-	Go                      string `json:"go-version"`
-	AutoInstrumentationJava string `json:"auto-instrumentation-java"`
+	Operator                       string `json:"amazon-cloudwatch-agent-operator"`
+	BuildDate                      string `json:"build-date"`
+	AmazonCloudWatchAgent          string `json:"amazon-cloudwatch-agent-version"`
+	Go                             string `json:"go-version"`
+	AutoInstrumentationJava        string `json:"auto-instrumentation-java"`
+	AutoInstrumentationPython      string `json:"auto-instrumentation-python"`
+	AutoInstrumentationNodeJS      string `json:"auto-instrumentation-nodejs"`
+	AutoInstrumentationDotNet      string `json:"auto-instrumentation-dotnet"`
+	AutoInstrumentationGo          string `json:"auto-instrumentation-go"`
+	AutoInstrumentationApacheHttpd string `json:"auto-instrumentation-apache-httpd"`
+	AutoInstrumentationNginx       string `json:"auto-instrumentation-nginx"`
 }
 
 // Get returns the Version object with the relevant information.
 func Get() Version {
 	return Version{
-		Operator:                version,
-		BuildDate:               buildDate,
-		AmazonCloudWatchAgent:   AmazonCloudWatchAgent(),
-		Go:                      runtime.Version(),
-		AutoInstrumentationJava: AutoInstrumentationJava(),
+		Operator:                       version,
+		BuildDate:                      buildDate,
+		AmazonCloudWatchAgent:          AmazonCloudWatchAgent(),
+		Go:                             runtime.Version(),
+		AutoInstrumentationJava:        AutoInstrumentationJava(),
+		AutoInstrumentationPython:      AutoInstrumentationPython(),
+		AutoInstrumentationNodeJS:      AutoInstrumentationNodeJS(),
+		AutoInstrumentationDotNet:      AutoInstrumentationDotNet(),
+		AutoInstrumentationGo:          AutoInstrumentationGo(),
+		AutoInstrumentationApacheHttpd: AutoInstrumentationApacheHttpd(),
+		AutoInstrumentationNginx:       AutoInstrumentationNginx(),
 	}
 }
 
 func (v Version) String() string {
 	return fmt.Sprintf(
-		"Version(Operator='%v', BuildDate='%v', AmazonCloudWatchAgent='%v', Go='%v', AutoInstrumentationJava='%v')",
+		"Version(Operator='%v', BuildDate='%v', AmazonCloudWatchAgent='%v', Go='%v', AutoInstrumentationJava='%v', AutoInstrumentationPython='%v', AutoInstrumentationNodeJS='%v', AutoInstrumentationDotNet='%v', AutoInstrumentationGo='%v', AutoInstrumentationApacheHttpd='%v', AutoInstrumentationNginx='%v')",
 		v.Operator,
 		v.BuildDate,
 		v.AmazonCloudWatchAgent,
 		v.Go,
 		v.AutoInstrumentationJava,
+		v.AutoInstrumentationPython,
+		v.AutoInstrumentationNodeJS,
+		v.AutoInstrumentationDotNet,
+		v.AutoInstrumentationGo,
+		v.AutoInstrumentationApacheHttpd,
+		v.AutoInstrumentationNginx,
 	)
 }
 
@@ -64,3 +88,45 @@ func AutoInstrumentationJava() string {
 	}
 	return "0.0.0"
 }
+
+func AutoInstrumentationPython() string {
+	if len(autoInstrumentationPython) > 0 {
+		return autoInstrumentationPython
+	}
+	return "0.0.0"
+}
+
+func AutoInstrumentationNodeJS() string {
+	if len(autoInstrumentationNodeJS) > 0 {
+		return autoInstrumentationNodeJS
+	}
+	return "0.0.0"
+}
+
+func AutoInstrumentationDotNet() string {
+	if len(autoInstrumentationDotNet) > 0 {
+		return autoInstrumentationDotNet
+	}
+	return "0.0.0"
+}
+
+func AutoInstrumentationGo() string {
+	if len(autoInstrumentationGo) > 0 {
+		return autoInstrumentationGo
+	}
+	return "0.0.0"
+}
+
+func AutoInstrumentationApacheHttpd() string {
+	if len(autoInstrumentationApacheHttpd) > 0 {
+		return autoInstrumentationApacheHttpd
+	}
+	return "0.0.0"
+}
+
+func AutoInstrumentationNginx() string {
+	if len(autoInstrumentationNginx) > 0 {
+		return autoInstrumentationNginx
+	}
+	return "0.0.0"
+}
@@ -0,0 +1,70 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webhookhandler
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+// AnnotationFailurePolicy overrides, on a pod or its namespace, the failure policy of the Instrumentation injected
+// into the pod. Possible values are "Ignore" and "Fail", the pod annotation takes precedence. It applies to every
+// mutation of the pod that fails, the injection of the agent sidecar included, and only rejects pod creations.
+const AnnotationFailurePolicy = "instrumentation.opentelemetry.io/failure-policy"
+
+// FailurePolicyError is returned by a PodMutator that failed to mutate a pod, along with the failure policy of the
+// configuration it was applying.
+type FailurePolicyError struct {
+	Policy v1alpha1.FailurePolicy
+	Err    error
+}
+
+func (e *FailurePolicyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FailurePolicyError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveFailurePolicy returns the policy that applies to a mutation error: the pod annotation, then the namespace
+// annotation, then the policy carried by the error. Pods are admitted by default.
+func ResolveFailurePolicy(ns corev1.Namespace, pod corev1.Pod, err error) v1alpha1.FailurePolicy {
+	for _, value := range []string{pod.Annotations[AnnotationFailurePolicy], ns.Annotations[AnnotationFailurePolicy]} {
+		switch {
+		case strings.EqualFold(value, string(v1alpha1.FailurePolicyFail)):
+			return v1alpha1.FailurePolicyFail
+		case strings.EqualFold(value, string(v1alpha1.FailurePolicyIgnore)):
+			return v1alpha1.FailurePolicyIgnore
+		}
+	}
+	var policyErr *FailurePolicyError
+	if errors.As(err, &policyErr) && policyErr.Policy == v1alpha1.FailurePolicyFail {
+		return v1alpha1.FailurePolicyFail
+	}
+	return v1alpha1.FailurePolicyIgnore
+}
+
+// owningWorkload returns a reference to the workload that controls the pod, the pod itself not having a name yet.
+// The Deployment of a ReplicaSet is looked up, so that the event shows up where users look for it. Nil is returned
+// for bare pods.
+func (p *podSidecarInjector) owningWorkload(ctx context.Context, namespace string, pod corev1.Pod) *corev1.ObjectReference {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return nil
+	}
+	if owner.Kind == "ReplicaSet" {
+		if parent, found := p.owners.ReplicaSetOwner(ctx, namespace, owner.Name, pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]); found && parent.UID != "" {
+			return &corev1.ObjectReference{APIVersion: parent.APIVersion, Kind: parent.Kind, Name: parent.Name, Namespace: namespace, UID: parent.UID}
+		}
+	}
+	return &corev1.ObjectReference{APIVersion: owner.APIVersion, Kind: owner.Kind, Name: owner.Name, Namespace: namespace, UID: owner.UID}
+}
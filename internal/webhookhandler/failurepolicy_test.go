@@ -0,0 +1,135 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webhookhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
+)
+
+func TestFailurePolicy(t *testing.T) {
+	errInjection := errors.New("injection failed")
+	errFail := fmt.Errorf("resolving: %w", &FailurePolicyError{Policy: v1alpha1.FailurePolicyFail, Err: errInjection})
+
+	for _, tt := range []struct {
+		name     string
+		nsValue  string
+		podValue string
+		err      error
+		expected v1alpha1.FailurePolicy
+	}{
+		{name: "default", err: errInjection, expected: v1alpha1.FailurePolicyIgnore},
+		{name: "instrumentation", err: errFail, expected: v1alpha1.FailurePolicyFail},
+		{name: "namespace annotation", nsValue: "fail", err: errInjection, expected: v1alpha1.FailurePolicyFail},
+		{name: "pod annotation over namespace", nsValue: "Fail", podValue: "Ignore", err: errInjection, expected: v1alpha1.FailurePolicyIgnore},
+		{name: "annotation over instrumentation", podValue: "Ignore", err: errFail, expected: v1alpha1.FailurePolicyIgnore},
+		{name: "invalid annotation", podValue: "maybe", err: errFail, expected: v1alpha1.FailurePolicyFail},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationFailurePolicy: tt.nsValue}}}
+			pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationFailurePolicy: tt.podValue}}}
+			assert.Equal(t, tt.expected, ResolveFailurePolicy(ns, pod, tt.err))
+		})
+	}
+}
+
+func TestMutationFailed(t *testing.T) {
+	controller := true
+	deployment := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "app", UID: "deployment-uid", Controller: &controller}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "app-5d8f", Namespace: "apps", OwnerReferences: []metav1.OwnerReference{deployment}}}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "apps",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-5d8f", Controller: &controller}},
+	}}
+	err := &FailurePolicyError{Policy: v1alpha1.FailurePolicyFail, Err: errors.New("agent not found")}
+
+	recorder := record.NewFakeRecorder(10)
+	injector := &podSidecarInjector{
+		logger:   logr.Discard(),
+		recorder: recorder,
+		owners:   owners.NewResolver(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(rs).Build(), logr.Discard()),
+	}
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+
+	res := injector.mutationFailed(context.Background(), admissionv1.Create, ns, pod, err)
+	assert.False(t, res.Allowed)
+	assert.Contains(t, res.Result.Message, "agent not found")
+	assert.Contains(t, <-recorder.Events, "pod rejected")
+
+	// the updates of existing pods, such as the removal of a finalizer, are admitted under Fail
+	for _, operation := range []admissionv1.Operation{admissionv1.Update, admissionv1.Delete, admissionv1.Connect} {
+		res = injector.mutationFailed(context.Background(), operation, ns, pod, err)
+		assert.True(t, res.Allowed, operation)
+		assert.Equal(t, int32(http.StatusInternalServerError), res.Result.Code)
+		assert.Contains(t, <-recorder.Events, "pod admitted without instrumentation")
+	}
+
+	ns.Annotations = map[string]string{AnnotationFailurePolicy: "Ignore"}
+	res = injector.mutationFailed(context.Background(), admissionv1.Create, ns, pod, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int32(http.StatusInternalServerError), res.Result.Code)
+	assert.Len(t, recorder.Events, 1)
+
+	owner := injector.owningWorkload(context.Background(), "apps", pod)
+	assert.Equal(t, "Deployment", owner.Kind)
+	assert.Equal(t, "app", owner.Name)
+	assert.Nil(t, injector.owningWorkload(context.Background(), "apps", corev1.Pod{}))
+}
+
+type failingMutator struct {
+	err error
+}
+
+func (m failingMutator) Mutate(_ context.Context, _ corev1.Namespace, pod corev1.Pod) (corev1.Pod, error) {
+	return pod, m.err
+}
+
+func TestHandleFailurePolicyByOperation(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps", Finalizers: []string{"example.com/cleanup"}}}
+	encoded, err := json.Marshal(pod)
+	require.NoError(t, err)
+	mutator := failingMutator{err: &FailurePolicyError{Policy: v1alpha1.FailurePolicyFail, Err: errors.New("instrumentation not found")}}
+	injector := NewWebhookHandler(config.New(), logr.Discard(), admission.NewDecoder(scheme.Scheme),
+		fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ns).Build(), record.NewFakeRecorder(10), []PodMutator{mutator})
+
+	for _, tt := range []struct {
+		operation admissionv1.Operation
+		allowed   bool
+	}{
+		{operation: admissionv1.Create, allowed: false},
+		{operation: admissionv1.Update, allowed: true},
+	} {
+		t.Run(string(tt.operation), func(t *testing.T) {
+			res := injector.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: tt.operation,
+				Namespace: "apps",
+				Object:    runtime.RawExtension{Raw: encoded},
+			}})
+			assert.Equal(t, tt.allowed, res.Allowed)
+			assert.Contains(t, res.Result.Message, "instrumentation not found")
+		})
+	}
+}
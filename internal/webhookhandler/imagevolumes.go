@@ -0,0 +1,58 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webhookhandler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AnnotationImageVolumes lists, as a JSON object keyed by volume name, the volumes of the pod to be mounted from
+// images. The Kubernetes API types used by the operator predate image volumes, so mutators add those volumes without
+// a source and list them in this annotation. The webhook then sets their source in the admitted pod, and removes
+// the annotation.
+const AnnotationImageVolumes = "instrumentation.opentelemetry.io/image-volumes"
+
+// ImageVolumeSource is the image volume source of the Kubernetes API.
+type ImageVolumeSource struct {
+	Reference  string            `json:"reference"`
+	PullPolicy corev1.PullPolicy `json:"pullPolicy,omitempty"`
+}
+
+// withImageVolumes sets the image source of the volumes listed in the AnnotationImageVolumes annotation of the
+// marshaled pod.
+func withImageVolumes(marshaledPod []byte) ([]byte, error) {
+	pod := map[string]interface{}{}
+	if err := json.Unmarshal(marshaledPod, &pod); err != nil {
+		return nil, err
+	}
+	metadata, _ := pod["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	listed, _ := annotations[AnnotationImageVolumes].(string)
+	if listed == "" {
+		return marshaledPod, nil
+	}
+
+	sources := map[string]ImageVolumeSource{}
+	if err := json.Unmarshal([]byte(listed), &sources); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationImageVolumes, err)
+	}
+	spec, _ := pod["spec"].(map[string]interface{})
+	volumes, _ := spec["volumes"].([]interface{})
+	for _, v := range volumes {
+		volume, _ := v.(map[string]interface{})
+		name, _ := volume["name"].(string)
+		if source, ok := sources[name]; ok {
+			volume["image"] = source
+		}
+	}
+
+	delete(annotations, AnnotationImageVolumes)
+	if len(annotations) == 0 {
+		delete(metadata, "annotations")
+	}
+	return json.Marshal(pod)
+}
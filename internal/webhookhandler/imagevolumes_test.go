@@ -0,0 +1,46 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webhookhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithImageVolumes(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		pod      string
+		expected string
+	}{
+		{
+			name:     "without image volumes",
+			pod:      `{"metadata":{"name":"app"},"spec":{"volumes":[{"name":"data","emptyDir":{}}]}}`,
+			expected: `{"metadata":{"name":"app"},"spec":{"volumes":[{"name":"data","emptyDir":{}}]}}`,
+		},
+		{
+			name: "image volumes",
+			pod: `{"metadata":{"annotations":{"instrumentation.opentelemetry.io/image-volumes":"{\"agent\":{\"reference\":\"java:1\",\"pullPolicy\":\"IfNotPresent\"}}","team":"payments"}},
+				"spec":{"volumes":[{"name":"data","emptyDir":{}},{"name":"agent"}]}}`,
+			expected: `{"metadata":{"annotations":{"team":"payments"}},
+				"spec":{"volumes":[{"name":"data","emptyDir":{}},{"name":"agent","image":{"reference":"java:1","pullPolicy":"IfNotPresent"}}]}}`,
+		},
+		{
+			name:     "annotation removed",
+			pod:      `{"metadata":{"annotations":{"instrumentation.opentelemetry.io/image-volumes":"{\"agent\":{\"reference\":\"java:1\"}}"}},"spec":{"volumes":[{"name":"agent"}]}}`,
+			expected: `{"metadata":{},"spec":{"volumes":[{"name":"agent","image":{"reference":"java:1"}}]}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, err := withImageVolumes([]byte(tt.pod))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(patched))
+		})
+	}
+
+	_, err := withImageVolumes([]byte(`{"metadata":{"annotations":{"instrumentation.opentelemetry.io/image-volumes":"agent"}}}`))
+	assert.ErrorContains(t, err, "invalid instrumentation.opentelemetry.io/image-volumes annotation")
+}
@@ -7,22 +7,30 @@ package webhookhandler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
 )
 
 // +kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=ignore,groups="",resources=pods,verbs=create;update,versions=v1,name=mpod.kb.io,sideEffects=none,admissionReviewVersions=v1
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=list;watch
 // +kubebuilder:rbac:groups=cloudwatch.aws.amazon.com,resources=amazoncloudwatchagents,verbs=get;list;watch
 // +kubebuilder:rbac:groups=cloudwatch.aws.amazon.com,resources=instrumentations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudwatch.aws.amazon.com,resources=clusterinstrumentations,verbs=get;list;watch
 // +kubebuilder:rbac:groups="apps",resources=replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 var _ WebhookHandler = (*podSidecarInjector)(nil)
 
@@ -38,6 +46,8 @@ type podSidecarInjector struct {
 	logger      logr.Logger
 	podMutators []PodMutator
 	config      config.Config
+	recorder    record.EventRecorder
+	owners      *owners.Resolver
 }
 
 // PodMutator mutates a pod.
@@ -46,12 +56,14 @@ type PodMutator interface {
 }
 
 // NewWebhookHandler creates a new WebhookHandler.
-func NewWebhookHandler(cfg config.Config, logger logr.Logger, decoder *admission.Decoder, cl client.Client, podMutators []PodMutator) WebhookHandler {
+func NewWebhookHandler(cfg config.Config, logger logr.Logger, decoder *admission.Decoder, cl client.Client, recorder record.EventRecorder, podMutators []PodMutator) WebhookHandler {
 	return &podSidecarInjector{
 		config:      cfg,
 		decoder:     decoder,
 		logger:      logger,
 		client:      cl,
+		recorder:    recorder,
+		owners:      owners.NewResolver(cl, logger),
 		podMutators: podMutators,
 	}
 }
@@ -63,6 +75,12 @@ func (p *podSidecarInjector) Handle(ctx context.Context, req admission.Request)
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	for _, ignored := range p.config.IgnoredNamespaces() {
+		if ignored == req.Namespace {
+			return admission.Allowed("the namespace is ignored by the operator configuration")
+		}
+	}
+
 	// we use the req.Namespace here because the pod might have not been created yet
 	ns := corev1.Namespace{}
 	err = p.client.Get(ctx, types.NamespacedName{Name: req.Namespace, Namespace: ""}, &ns)
@@ -77,13 +95,21 @@ func (p *podSidecarInjector) Handle(ctx context.Context, req admission.Request)
 		return res
 	}
 
+	original := pod.DeepCopy()
+	// the owners of the pod are looked up by several mutators, within a shared budget
+	ctx = owners.WithBudget(ctx, owners.DefaultRequestBudget)
 	for _, m := range p.podMutators {
-		pod, err = m.Mutate(ctx, ns, pod)
+		mutated, err := m.Mutate(ctx, ns, pod)
 		if err != nil {
-			res := admission.Errored(http.StatusInternalServerError, err)
-			res.Allowed = true
-			return res
+			return p.mutationFailed(ctx, req.Operation, ns, pod, err)
 		}
+		pod = mutated
+	}
+
+	// the pod is only re-encoded when mutated, as this drops the fields unknown to the API types of the operator,
+	// such as the source of image volumes, which can't be updated
+	if equality.Semantic.DeepEqual(original, &pod) {
+		return admission.PatchResponseFromRaw(req.Object.Raw, req.Object.Raw)
 	}
 
 	marshaledPod, err := json.Marshal(pod)
@@ -92,5 +118,32 @@ func (p *podSidecarInjector) Handle(ctx context.Context, req admission.Request)
 		res.Allowed = true
 		return res
 	}
+	if marshaledPod, err = withImageVolumes(marshaledPod); err != nil {
+		res := admission.Errored(http.StatusInternalServerError, err)
+		res.Allowed = true
+		return res
+	}
 	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
 }
+
+// mutationFailed admits or rejects the pod depending on the failure policy, and records an event on the owning
+// workload in both cases. Only the creation of a pod is rejected: denying the updates of an existing pod, which
+// can't be instrumented anymore, would only block changes such as the removal of its finalizers.
+func (p *podSidecarInjector) mutationFailed(ctx context.Context, operation admissionv1.Operation, ns corev1.Namespace, pod corev1.Pod, err error) admission.Response {
+	reject := operation == admissionv1.Create && ResolveFailurePolicy(ns, pod, err) == v1alpha1.FailurePolicyFail
+	if owner := p.owningWorkload(ctx, ns.Name, pod); owner != nil {
+		if reject {
+			p.recorder.Eventf(owner, corev1.EventTypeWarning, "InstrumentationInjectionFailed", "pod rejected, the instrumentation could not be injected: %v", err)
+		} else {
+			p.recorder.Eventf(owner, corev1.EventTypeWarning, "InstrumentationInjectionFailed", "pod admitted without instrumentation, it could not be injected: %v", err)
+		}
+	}
+
+	if reject {
+		p.logger.Error(err, "rejecting the pod since the instrumentation could not be injected", "namespace", ns.Name, "failurePolicy", v1alpha1.FailurePolicyFail)
+		return admission.Denied(fmt.Sprintf("the instrumentation could not be injected and the failure policy is %s: %v", v1alpha1.FailurePolicyFail, err))
+	}
+	res := admission.Errored(http.StatusInternalServerError, err)
+	res.Allowed = true
+	return res
+}
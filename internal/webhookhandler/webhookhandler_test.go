@@ -15,6 +15,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubectl/pkg/scheme"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -140,7 +141,7 @@ func TestShouldInjectSidecar(t *testing.T) {
 			// the webhook handler
 			cfg := config.New()
 			decoder := admission.NewDecoder(scheme.Scheme)
-			injector := NewWebhookHandler(cfg, logger, decoder, k8sClient, []PodMutator{sidecar.NewMutator(logger, cfg, k8sClient)})
+			injector := NewWebhookHandler(cfg, logger, decoder, k8sClient, record.NewFakeRecorder(10), []PodMutator{sidecar.NewMutator(logger, cfg, k8sClient)})
 
 			// test
 			res := injector.Handle(context.Background(), req)
@@ -358,7 +359,7 @@ func TestPodShouldNotBeChanged(t *testing.T) {
 			// the webhook handler
 			cfg := config.New()
 			decoder := admission.NewDecoder(scheme.Scheme)
-			injector := NewWebhookHandler(cfg, logger, decoder, k8sClient, []PodMutator{sidecar.NewMutator(logger, cfg, k8sClient)})
+			injector := NewWebhookHandler(cfg, logger, decoder, k8sClient, record.NewFakeRecorder(10), []PodMutator{sidecar.NewMutator(logger, cfg, k8sClient)})
 			require.NoError(t, err)
 
 			// test
@@ -416,7 +417,7 @@ func TestFailOnInvalidRequest(t *testing.T) {
 			// prepare
 			cfg := config.New()
 			decoder := admission.NewDecoder(scheme.Scheme)
-			injector := NewWebhookHandler(cfg, logger, decoder, k8sClient, []PodMutator{sidecar.NewMutator(logger, cfg, k8sClient)})
+			injector := NewWebhookHandler(cfg, logger, decoder, k8sClient, record.NewFakeRecorder(10), []PodMutator{sidecar.NewMutator(logger, cfg, k8sClient)})
 
 			// test
 			res := injector.Handle(context.Background(), tt.req)
@@ -3,26 +3,30 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/open-telemetry/opentelemetry-operator/pkg/featuregate"
 	routev1 "github.com/openshift/api/route/v1"
 	"github.com/spf13/pflag"
 	colfeaturegate "go.opentelemetry.io/collector/featuregate"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/record"
 	k8sapiflag "k8s.io/component-base/cli/flag"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -32,12 +36,21 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/version"
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/webhookhandler"
 	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/instrumentation"
+	instrumentationrollout "github.com/aws/amazon-cloudwatch-agent-operator/pkg/instrumentation/rollout"
+	instrumentationupgrade "github.com/aws/amazon-cloudwatch-agent-operator/pkg/instrumentation/upgrade"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
 	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/sidecar"
 )
 
 const (
-	cloudwatchAgentImageRepository         = "public.ecr.aws/cloudwatch-agent/cloudwatch-agent"
-	autoInstrumentationJavaImageRepository = "public.ecr.aws/aws-observability/adot-autoinstrumentation-java"
+	cloudwatchAgentImageRepository                = "public.ecr.aws/cloudwatch-agent/cloudwatch-agent"
+	autoInstrumentationJavaImageRepository        = "public.ecr.aws/aws-observability/adot-autoinstrumentation-java"
+	autoInstrumentationNginxImageRepository       = "ghcr.io/open-telemetry/opentelemetry-operator/autoinstrumentation-apache-httpd"
+	autoInstrumentationApacheHttpdImageRepository = "ghcr.io/open-telemetry/opentelemetry-operator/autoinstrumentation-apache-httpd"
+	autoInstrumentationGoImageRepository          = "ghcr.io/open-telemetry/opentelemetry-go-instrumentation/autoinstrumentation-go"
+	autoInstrumentationDotNetImageRepository      = "public.ecr.aws/aws-observability/adot-autoinstrumentation-dotnet"
+	autoInstrumentationNodeJSImageRepository      = "public.ecr.aws/aws-observability/adot-autoinstrumentation-node"
+	autoInstrumentationPythonImageRepository      = "public.ecr.aws/aws-observability/adot-autoinstrumentation-python"
 )
 
 var (
@@ -69,14 +82,42 @@ func main() {
 
 	// add flags related to this operator
 	var (
-		agentImage              string
-		autoInstrumentationJava string
-		webhookPort             int
-		tlsOpt                  tlsConfig
+		agentImage                     string
+		autoInstrumentationJava        string
+		autoInstrumentationPython      string
+		autoInstrumentationNodeJS      string
+		autoInstrumentationDotNet      string
+		autoInstrumentationGo          string
+		autoInstrumentationApacheHttpd string
+		autoInstrumentationNginx       string
+		languageDetectionRulesFile     string
+		operatorConfigFile             string
+		enableInstrumentationRollout   bool
+		instrumentationRolloutInterval time.Duration
+		instrumentationRolloutMax      int
+		webhookPort                    int
+		clusterAttributes              config.ClusterAttributes
+		tlsOpt                         tlsConfig
 	)
 
 	pflag.StringVar(&agentImage, "agent-image", fmt.Sprintf("%s:%s", cloudwatchAgentImageRepository, v.AmazonCloudWatchAgent), "The default cloudwatch agent image. This image is used when no image is specified in the CustomResource.")
 	pflag.StringVar(&autoInstrumentationJava, "auto-instrumentation-java-image", fmt.Sprintf("%s:%s", autoInstrumentationJavaImageRepository, v.AutoInstrumentationJava), "The default OpenTelemetry Java instrumentation image. This image is used when no image is specified in the CustomResource.")
+	pflag.StringVar(&autoInstrumentationPython, "auto-instrumentation-python-image", fmt.Sprintf("%s:%s", autoInstrumentationPythonImageRepository, v.AutoInstrumentationPython), "The default OpenTelemetry Python instrumentation image. This image is used when no image is specified in the CustomResource.")
+	pflag.StringVar(&autoInstrumentationNodeJS, "auto-instrumentation-nodejs-image", fmt.Sprintf("%s:%s", autoInstrumentationNodeJSImageRepository, v.AutoInstrumentationNodeJS), "The default OpenTelemetry NodeJS instrumentation image. This image is used when no image is specified in the CustomResource.")
+	pflag.StringVar(&autoInstrumentationDotNet, "auto-instrumentation-dotnet-image", fmt.Sprintf("%s:%s", autoInstrumentationDotNetImageRepository, v.AutoInstrumentationDotNet), "The default OpenTelemetry .NET instrumentation image. This image is used when no image is specified in the CustomResource.")
+	pflag.StringVar(&autoInstrumentationGo, "auto-instrumentation-go-image", fmt.Sprintf("%s:%s", autoInstrumentationGoImageRepository, v.AutoInstrumentationGo), "The default OpenTelemetry Go instrumentation image. This image is used when no image is specified in the CustomResource.")
+	pflag.StringVar(&autoInstrumentationApacheHttpd, "auto-instrumentation-apache-httpd-image", fmt.Sprintf("%s:%s", autoInstrumentationApacheHttpdImageRepository, v.AutoInstrumentationApacheHttpd), "The default OpenTelemetry Apache HTTPD instrumentation image. This image is used when no image is specified in the CustomResource.")
+	pflag.StringVar(&autoInstrumentationNginx, "auto-instrumentation-nginx-image", fmt.Sprintf("%s:%s", autoInstrumentationNginxImageRepository, v.AutoInstrumentationNginx), "The default OpenTelemetry Nginx instrumentation image. This image is used when no image is specified in the CustomResource.")
+	pflag.StringVar(&languageDetectionRulesFile, "language-detection-rules", "", "Path to a YAML file with the rules used to detect the runtime of containers annotated with instrumentation.opentelemetry.io/inject-auto. The built-in rules are used when empty.")
+	pflag.StringVar(&operatorConfigFile, "config-file", "", "Path to the operator configuration file, usually mounted from a ConfigMap. It holds the default images, default Instrumentation settings, agent resources, label filters and webhook settings, takes precedence over the flags and is reloaded when it changes.")
+	pflag.BoolVar(&enableInstrumentationRollout, "enable-instrumentation-rollout", false, "Restart the Deployments, StatefulSets and DaemonSets whose pods run an outdated auto-instrumentation, or weren't instrumented because they were created before the operator.")
+	pflag.DurationVar(&instrumentationRolloutInterval, "instrumentation-rollout-interval", time.Minute, "The interval between two checks of the instrumented workloads.")
+	pflag.IntVar(&instrumentationRolloutMax, "instrumentation-rollout-max-restarts", 1, "The maximum number of restarted workloads rolling out at the same time.")
+	pflag.StringVar(&clusterAttributes.ClusterName, "cluster-name", "", "The k8s.cluster.name resource attribute of the instrumented pods.")
+	pflag.StringVar(&clusterAttributes.CloudProvider, "cloud-provider", "", "The cloud.provider resource attribute of the instrumented pods. It is detected from the nodes when empty.")
+	pflag.StringVar(&clusterAttributes.CloudPlatform, "cloud-platform", "", "The cloud.platform resource attribute of the instrumented pods. It is detected from the nodes when empty.")
+	pflag.StringVar(&clusterAttributes.CloudRegion, "cloud-region", "", "The cloud.region resource attribute of the instrumented pods. It is detected from the nodes when empty.")
+	pflag.StringVar(&clusterAttributes.CloudAccountID, "cloud-account-id", "", "The cloud.account.id resource attribute of the instrumented pods.")
 	pflag.Parse()
 
 	logger := zap.New(zap.UseFlagOptions(&opts))
@@ -86,20 +127,59 @@ func main() {
 		"amazon-cloudwatch-agent-operator", v.Operator,
 		"amazon-cloudwatch-agent", agentImage,
 		"auto-instrumentation-java", autoInstrumentationJava,
+		"auto-instrumentation-python", autoInstrumentationPython,
+		"auto-instrumentation-nodejs", autoInstrumentationNodeJS,
+		"auto-instrumentation-dotnet", autoInstrumentationDotNet,
+		"auto-instrumentation-go", autoInstrumentationGo,
+		"auto-instrumentation-apache-httpd", autoInstrumentationApacheHttpd,
+		"auto-instrumentation-nginx", autoInstrumentationNginx,
 		"build-date", v.BuildDate,
 		"go-version", v.Go,
 		"go-arch", runtime.GOARCH,
 		"go-os", runtime.GOOS,
 	)
 
-	// set java instrumentation java image in environment variable to be used for default instrumentation
+	// set instrumentation images in environment variables to be used for default instrumentation
 	os.Setenv("AUTO_INSTRUMENTATION_JAVA", autoInstrumentationJava)
+	os.Setenv("AUTO_INSTRUMENTATION_PYTHON", autoInstrumentationPython)
+	os.Setenv("AUTO_INSTRUMENTATION_NODEJS", autoInstrumentationNodeJS)
+	os.Setenv("AUTO_INSTRUMENTATION_DOTNET", autoInstrumentationDotNet)
+	os.Setenv("AUTO_INSTRUMENTATION_GO", autoInstrumentationGo)
+	os.Setenv("AUTO_INSTRUMENTATION_APACHE_HTTPD", autoInstrumentationApacheHttpd)
+	os.Setenv("AUTO_INSTRUMENTATION_NGINX", autoInstrumentationNginx)
+
+	var languageDetectionRules []config.LanguageDetectionRule
+	if languageDetectionRulesFile != "" {
+		var err error
+		if languageDetectionRules, err = config.LoadLanguageDetectionRules(languageDetectionRulesFile); err != nil {
+			setupLog.Error(err, "unable to load the language detection rules")
+			os.Exit(1)
+		}
+	}
+
+	var operatorConfig *config.OperatorConfig
+	if operatorConfigFile != "" {
+		var err error
+		if operatorConfig, err = config.LoadOperatorConfig(operatorConfigFile); err != nil {
+			setupLog.Error(err, "unable to load the operator configuration")
+			os.Exit(1)
+		}
+	}
 
 	cfg := config.New(
 		config.WithLogger(ctrl.Log.WithName("config")),
 		config.WithVersion(v),
 		config.WithCollectorImage(agentImage),
 		config.WithAutoInstrumentationJavaImage(autoInstrumentationJava),
+		config.WithAutoInstrumentationPythonImage(autoInstrumentationPython),
+		config.WithAutoInstrumentationNodeJSImage(autoInstrumentationNodeJS),
+		config.WithAutoInstrumentationDotNetImage(autoInstrumentationDotNet),
+		config.WithAutoInstrumentationGoImage(autoInstrumentationGo),
+		config.WithAutoInstrumentationApacheHttpdImage(autoInstrumentationApacheHttpd),
+		config.WithAutoInstrumentationNginxImage(autoInstrumentationNginx),
+		config.WithLanguageDetectionRules(languageDetectionRules),
+		config.WithOperatorConfig(operatorConfig),
+		config.WithClusterAttributes(clusterAttributes),
 	)
 
 	watchNamespace, found := os.LookupEnv("WATCH_NAMESPACE")
@@ -137,6 +217,16 @@ func main() {
 	}
 
 	ctx := ctrl.SetupSignalHandler()
+
+	if dcl, dclErr := discovery.NewDiscoveryClientForConfig(mgr.GetConfig()); dclErr != nil {
+		setupLog.Error(dclErr, "failed to create the discovery client, the agents are delivered by init containers")
+	} else if err = cfg.DetectImageVolumes(dcl); err != nil {
+		setupLog.Error(err, "failed to detect image volumes support, the agents are delivered by init containers")
+	}
+	if err = cfg.DetectClusterAttributes(ctx, mgr.GetAPIReader()); err != nil {
+		setupLog.Error(err, "failed to detect the cloud and cluster attributes, only the configured ones are used")
+	}
+
 	if err = controllers.NewReconciler(controllers.Params{
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controllers").WithName("AmazonCloudWatchAgent"),
@@ -148,6 +238,61 @@ func main() {
 		os.Exit(1)
 	}
 
+	if operatorConfigFile != "" {
+		if err = mgr.Add(manager.RunnableFunc(func(c context.Context) error {
+			return cfg.WatchOperatorConfig(c, operatorConfigFile)
+		})); err != nil {
+			setupLog.Error(err, "failed to add the operator configuration watcher")
+			os.Exit(1)
+		}
+	}
+
+	if enableInstrumentationRollout {
+		rollout := &instrumentationrollout.InstrumentationRollout{
+			Client:   mgr.GetClient(),
+			Logger:   ctrl.Log.WithName("instrumentation-rollout"),
+			Recorder: mgr.GetEventRecorderFor("amazon-cloudwatch-agent-operator"),
+			// the dry-run of the webhook must not record events on the pod templates
+			Mutator:     instrumentation.NewMutator(ctrl.Log.WithName("instrumentation-rollout"), cfg, mgr.GetClient(), &record.FakeRecorder{}),
+			Interval:    instrumentationRolloutInterval,
+			MaxRestarts: instrumentationRolloutMax,
+		}
+		// the default Instrumentation and the language detection of the configuration apply to all the namespaces
+		cfg.OnOperatorConfigChange(func() error {
+			rollout.RecheckAll()
+			return nil
+		})
+		if err = mgr.Add(rollout); err != nil {
+			setupLog.Error(err, "failed to add the instrumentation rollout runnable")
+			os.Exit(1)
+		}
+	}
+
+	// adds the upgrade mechanism to be executed once the manager is ready, and again whenever the operator
+	// configuration is reloaded, as it may change the default images
+	if err = mgr.Add(manager.RunnableFunc(func(c context.Context) error {
+		upgradeInstrumentations := func() error {
+			u := &instrumentationupgrade.InstrumentationUpgrade{
+				Logger:                     ctrl.Log.WithName("instrumentation-upgrade"),
+				DefaultAutoInstJava:        cfg.AutoInstrumentationJavaImage(),
+				DefaultAutoInstPython:      cfg.AutoInstrumentationPythonImage(),
+				DefaultAutoInstNodeJS:      cfg.AutoInstrumentationNodeJSImage(),
+				DefaultAutoInstDotNet:      cfg.AutoInstrumentationDotNetImage(),
+				DefaultAutoInstGo:          cfg.AutoInstrumentationGoImage(),
+				DefaultAutoInstApacheHttpd: cfg.AutoInstrumentationApacheHttpdImage(),
+				DefaultAutoInstNginx:       cfg.AutoInstrumentationNginxImage(),
+				Client:                     mgr.GetClient(),
+				Recorder:                   mgr.GetEventRecorderFor("amazon-cloudwatch-agent-operator"),
+			}
+			return u.ManagedInstances(c)
+		}
+		cfg.OnOperatorConfigChange(upgradeInstrumentations)
+		return upgradeInstrumentations()
+	})); err != nil {
+		setupLog.Error(err, "failed to add the instrumentation upgrade runnable")
+		os.Exit(1)
+	}
+
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		// Create webhook to create cloudwatch agent operator resources
 		if err = (&cwv1alphav1.AmazonCloudWatchAgent{}).SetupWebhookWithManager(mgr); err != nil {
@@ -156,22 +301,35 @@ func main() {
 		}
 
 		// Create webhook to install instrumentation sdk to pods
-		if err = (&cwv1alphav1.Instrumentation{
-			ObjectMeta: metav1.ObjectMeta{
-				Annotations: map[string]string{
-					cwv1alphav1.AnnotationDefaultAutoInstrumentationJava: autoInstrumentationJava,
-				},
-			},
-		}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&cwv1alphav1.Instrumentation{}).SetupWebhookWithManager(mgr, func() map[string]string {
+			return map[string]string{
+				cwv1alphav1.AnnotationDefaultAutoInstrumentationJava:        cfg.AutoInstrumentationJavaImage(),
+				cwv1alphav1.AnnotationDefaultAutoInstrumentationPython:      cfg.AutoInstrumentationPythonImage(),
+				cwv1alphav1.AnnotationDefaultAutoInstrumentationNodeJS:      cfg.AutoInstrumentationNodeJSImage(),
+				cwv1alphav1.AnnotationDefaultAutoInstrumentationDotNet:      cfg.AutoInstrumentationDotNetImage(),
+				cwv1alphav1.AnnotationDefaultAutoInstrumentationGo:          cfg.AutoInstrumentationGoImage(),
+				cwv1alphav1.AnnotationDefaultAutoInstrumentationApacheHttpd: cfg.AutoInstrumentationApacheHttpdImage(),
+				cwv1alphav1.AnnotationDefaultAutoInstrumentationNginx:       cfg.AutoInstrumentationNginxImage(),
+			}
+		}); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Instrumentation")
 			os.Exit(1)
 		}
+		if err = (&cwv1alphav1.ClusterInstrumentation{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterInstrumentation")
+			os.Exit(1)
+		}
+		// the owners of the admitted pods are looked up from metadata-only informers, synced with the cache
+		if err = owners.RegisterInformers(ctx, mgr.GetCache()); err != nil {
+			setupLog.Error(err, "failed to register the informers of the pod webhook")
+			os.Exit(1)
+		}
 		decoder := admission.NewDecoder(mgr.GetScheme())
 		mgr.GetWebhookServer().Register("/mutate-v1-pod", &webhook.Admission{
-			Handler: webhookhandler.NewWebhookHandler(cfg, ctrl.Log.WithName("pod-webhook"), decoder, mgr.GetClient(),
+			Handler: webhookhandler.NewWebhookHandler(cfg, ctrl.Log.WithName("pod-webhook"), decoder, mgr.GetClient(), mgr.GetEventRecorderFor("amazon-cloudwatch-agent-operator"),
 				[]webhookhandler.PodMutator{
 					sidecar.NewMutator(logger, cfg, mgr.GetClient()),
-					instrumentation.NewMutator(logger, mgr.GetClient(), mgr.GetEventRecorderFor("opentelemetry-operator")),
+					instrumentation.NewMutator(logger, cfg, mgr.GetClient(), mgr.GetEventRecorderFor("opentelemetry-operator")),
 				}),
 		})
 	} else {
@@ -92,6 +92,11 @@ func Container(cfg config.Config, logger logr.Logger, agent v1alpha1.AmazonCloud
 		logger.Error(err, "error parsing config")
 	}
 
+	resources := agent.Spec.Resources
+	if resources.Limits == nil && resources.Requests == nil && cfg.AgentResources() != nil {
+		resources = *cfg.AgentResources().DeepCopy()
+	}
+
 	return corev1.Container{
 		Name:            naming.Container(),
 		Image:           image,
@@ -100,7 +105,7 @@ func Container(cfg config.Config, logger logr.Logger, agent v1alpha1.AmazonCloud
 		Args:            args,
 		Env:             envVars,
 		EnvFrom:         agent.Spec.EnvFrom,
-		Resources:       agent.Spec.Resources,
+		Resources:       resources,
 		Ports:           portMapToList(ports),
 	}
 }
@@ -0,0 +1,19 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package featuregate registers the feature gates that are specific to this operator. They live in the same
+// global registry as the gates inherited from the OpenTelemetry operator, so they are toggled through the
+// same --feature-gates flag.
+package featuregate
+
+import (
+	"go.opentelemetry.io/collector/featuregate"
+)
+
+var (
+	// EnableNginxAutoInstrumentationSupport is the feature gate that enables the operator to support Nginx auto-instrumentation.
+	EnableNginxAutoInstrumentationSupport = featuregate.GlobalRegistry().MustRegister(
+		"operator.autoinstrumentation.nginx",
+		featuregate.StageAlpha,
+		featuregate.WithRegisterDescription("controls whether the operator supports Nginx auto-instrumentation"))
+)
@@ -0,0 +1,225 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-telemetry/opentelemetry-operator/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/collector"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/collector/adapters"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/naming"
+)
+
+const (
+	agentOTLPGRPCPortName = "otlp-grpc"
+	agentOTLPHTTPPortName = "otlp-http"
+	agentAWSProxyPortName = "aws-proxy"
+
+	envHostIP = "HOST_IP"
+
+	// the agent the default endpoints point to
+	defaultAgentName      = "cloudwatch-agent"
+	defaultAgentNamespace = "amazon-cloudwatch"
+)
+
+// agentEndpoints holds the address of the Service of an AmazonCloudWatchAgent and the ports it exposes.
+type agentEndpoints struct {
+	host      string
+	grpcPort  int32
+	httpPort  int32
+	proxyPort int32
+}
+
+// agentEndpointsFor computes the endpoints of the Service created for the agent. The ports are the ones the
+// operator exposes by default, unless the agent overrides them by name in its spec.
+func agentEndpointsFor(agent v1alpha1.AmazonCloudWatchAgent) agentEndpoints {
+	ports := map[string]int32{}
+	for _, port := range collector.CloudwatchAgentPorts {
+		ports[port.Name] = port.Port
+	}
+	for _, port := range agent.Spec.Ports {
+		ports[port.Name] = port.Port
+	}
+	return agentEndpoints{
+		host:      fmt.Sprintf("%s.%s", naming.Service(agent), agent.Namespace),
+		grpcPort:  ports[agentOTLPGRPCPortName],
+		httpPort:  ports[agentOTLPHTTPPortName],
+		proxyPort: ports[agentAWSProxyPortName],
+	}
+}
+
+func (e agentEndpoints) otlpEndpoint(protocol v1alpha1.ExporterProtocol) string {
+	if protocol == v1alpha1.ExporterProtocolHTTPProtobuf {
+		return fmt.Sprintf("http://%s:%d", e.host, e.httpPort)
+	}
+	return fmt.Sprintf("http://%s:%d", e.host, e.grpcPort)
+}
+
+func (e agentEndpoints) tracesEndpoint(protocol v1alpha1.ExporterProtocol) string {
+	if protocol == v1alpha1.ExporterProtocolHTTPProtobuf {
+		return fmt.Sprintf("http://%s:%d/v1/traces", e.host, e.httpPort)
+	}
+	return fmt.Sprintf("http://%s:%d", e.host, e.grpcPort)
+}
+
+func (e agentEndpoints) smpEndpoint(protocol v1alpha1.ExporterProtocol) string {
+	if protocol == v1alpha1.ExporterProtocolHTTPProtobuf {
+		return fmt.Sprintf("http://%s:%d/v1/metrics", e.host, e.httpPort)
+	}
+	return fmt.Sprintf("http://%s:%d", e.host, e.grpcPort)
+}
+
+func (e agentEndpoints) samplerArg() string {
+	return fmt.Sprintf("endpoint=http://%s:%d", e.host, e.proxyPort)
+}
+
+// resolvesAgentEndpoints tells whether the exporter endpoints are computed by the operator.
+func resolvesAgentEndpoints(exporter v1alpha1.Exporter) bool {
+	return exporter.Agent != nil || exporter.Mode == v1alpha1.ExporterModeNodeLocal
+}
+
+// withAgentExporter points the exporters of the Instrumentation to the AmazonCloudWatchAgent it references, or to
+// the default agent in nodeLocal mode.
+func (pm *instPodMutator) withAgentExporter(ctx context.Context, inst *v1alpha1.Instrumentation) (*v1alpha1.Instrumentation, error) {
+	if !resolvesAgentEndpoints(inst.Spec.Exporter) {
+		return inst, nil
+	}
+	ref := inst.Spec.Exporter.Agent
+	if ref == nil {
+		resolved := inst.DeepCopy()
+		applyAgentEndpoints(&resolved.Spec, agentEndpointsFor(v1alpha1.AmazonCloudWatchAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: defaultAgentName, Namespace: defaultAgentNamespace},
+		}))
+		return resolved, nil
+	}
+	agentNamespacedName := types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}
+	if agentNamespacedName.Namespace == "" {
+		agentNamespacedName.Namespace = inst.Namespace
+	}
+	agent := v1alpha1.AmazonCloudWatchAgent{}
+	if err := pm.Client.Get(ctx, agentNamespacedName, &agent); err != nil {
+		return nil, fmt.Errorf("failed to get the AmazonCloudWatchAgent referenced by the Instrumentation %s/%s: %w", inst.Namespace, inst.Name, err)
+	}
+	if !otlpReceiverEnabled(agent) {
+		msg := fmt.Sprintf("the AmazonCloudWatchAgent %s has no OTLP receiver enabled, the telemetry of instrumented pods will be dropped", agentNamespacedName)
+		pm.Logger.Info(msg, "namespace", inst.Namespace, "instrumentation", inst.Name)
+		if pm.Recorder != nil {
+			pm.Recorder.Event(inst.DeepCopy(), "Warning", "AgentWithoutOTLPReceiver", msg)
+		}
+	}
+
+	resolved := inst.DeepCopy()
+	applyAgentEndpoints(&resolved.Spec, agentEndpointsFor(agent))
+	return resolved, nil
+}
+
+// applyAgentEndpoints sets the exporter env vars of each language from the agent endpoints. The protocol follows the
+// exporter configuration, or the one of the SDK when it isn't set, and env vars already set on the Instrumentation
+// are kept. In nodeLocal mode the endpoints point to the host IP of the pod, exposed through the downward API.
+func applyAgentEndpoints(spec *v1alpha1.InstrumentationSpec, endpoints agentEndpoints) {
+	setEndpoint := spec.Exporter.Endpoint == ""
+	setTraces := setEndpoint && spec.Exporter.TracesEndpoint == ""
+	// the web server modules write the endpoint to their configuration file, where env vars aren't expanded
+	webServerEndpoint := endpoints.tracesEndpoint(v1alpha1.ExporterProtocolGRPC)
+	nodeLocal := spec.Exporter.Mode == v1alpha1.ExporterModeNodeLocal
+	if nodeLocal {
+		endpoints.host = fmt.Sprintf("$(%s)", envHostIP)
+	}
+	protocolOr := func(sdkProtocol v1alpha1.ExporterProtocol) v1alpha1.ExporterProtocol {
+		if spec.Exporter.Protocol != "" {
+			return spec.Exporter.Protocol
+		}
+		return sdkProtocol
+	}
+
+	languages := []struct {
+		env      *[]corev1.EnvVar
+		protocol v1alpha1.ExporterProtocol
+	}{
+		{env: &spec.Java.Env, protocol: protocolOr(v1alpha1.ExporterProtocolGRPC)},
+		{env: &spec.Python.Env, protocol: protocolOr(v1alpha1.ExporterProtocolHTTPProtobuf)},
+		{env: &spec.NodeJS.Env, protocol: protocolOr(v1alpha1.ExporterProtocolHTTPProtobuf)},
+		{env: &spec.DotNet.Env, protocol: protocolOr(v1alpha1.ExporterProtocolHTTPProtobuf)},
+	}
+	for _, language := range languages {
+		if nodeLocal {
+			prependHostIPEnv(language.env)
+		}
+		if setEndpoint {
+			setLanguageEnvIfMissing(spec, language.env, constants.EnvOTELExporterOTLPEndpoint, endpoints.otlpEndpoint(language.protocol))
+		}
+		if setTraces {
+			setLanguageEnvIfMissing(spec, language.env, otelExporterTracesEndpointKey, endpoints.tracesEndpoint(language.protocol))
+		}
+		setLanguageEnvIfMissing(spec, language.env, otelExporterSmpEndpointKey, endpoints.smpEndpoint(language.protocol))
+		setLanguageEnvIfMissing(spec, language.env, otelTracesSamplerArgKey, endpoints.samplerArg())
+	}
+
+	if setTraces {
+		// the Go agent and the web server modules only export traces over OTLP/gRPC
+		if nodeLocal {
+			prependHostIPEnv(&spec.Go.Env)
+		}
+		setLanguageEnvIfMissing(spec, &spec.Go.Env, otelExporterTracesEndpointKey, endpoints.tracesEndpoint(v1alpha1.ExporterProtocolGRPC))
+		if getIndexOfEnv(spec.ApacheHttpd.Attrs, "ApacheModuleOtelExporterEndpoint") == -1 {
+			spec.ApacheHttpd.Attrs = append(spec.ApacheHttpd.Attrs, corev1.EnvVar{Name: "ApacheModuleOtelExporterEndpoint", Value: webServerEndpoint})
+		}
+		if getIndexOfEnv(spec.Nginx.Attrs, "NginxModuleOtelExporterEndpoint") == -1 {
+			spec.Nginx.Attrs = append(spec.Nginx.Attrs, corev1.EnvVar{Name: "NginxModuleOtelExporterEndpoint", Value: webServerEndpoint})
+		}
+	}
+}
+
+// setLanguageEnvIfMissing adds the env var to the language env, unless it is already set for the language or for
+// all of them.
+func setLanguageEnvIfMissing(spec *v1alpha1.InstrumentationSpec, env *[]corev1.EnvVar, name, value string) {
+	if getIndexOfEnv(spec.Env, name) != -1 || getIndexOfEnv(*env, name) != -1 {
+		return
+	}
+	*env = append(*env, corev1.EnvVar{Name: name, Value: value})
+}
+
+// prependHostIPEnv adds the HOST_IP env var first, so that the kubelet can expand it in the env vars that follow.
+func prependHostIPEnv(env *[]corev1.EnvVar) {
+	if getIndexOfEnv(*env, envHostIP) != -1 {
+		return
+	}
+	*env = append([]corev1.EnvVar{{
+		Name:      envHostIP,
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"}},
+	}}, *env...)
+}
+
+// otlpReceiverEnabled tells whether the agent configuration enables a receiver listening for OTLP, either directly
+// or through Application Signals.
+func otlpReceiverEnabled(agent v1alpha1.AmazonCloudWatchAgent) bool {
+	cfg, err := adapters.ConfigFromJSONString(agent.Spec.Config)
+	if err != nil {
+		return false
+	}
+	sections := [][2]string{{"traces", "traces_collected"}, {"logs", "metrics_collected"}, {"metrics", "metrics_collected"}}
+	for _, section := range sections {
+		top, ok := cfg[section[0]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		collected, ok := top[section[1]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, receiver := range []string{"otlp", "application_signals", "app_signals"} {
+			if _, ok := collected[receiver]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+)
+
+const appSignalsAgentConfig = `{"traces":{"traces_collected":{"application_signals":{}}},"logs":{"metrics_collected":{"application_signals":{}}}}`
+
+func TestAgentEndpointsFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    []corev1.ServicePort
+		expected agentEndpoints
+	}{
+		{
+			name:     "default ports",
+			expected: agentEndpoints{host: "cloudwatch-agent.amazon-cloudwatch", grpcPort: 4315, httpPort: 4316, proxyPort: 2000},
+		},
+		{
+			name:     "ports overridden by name",
+			ports:    []corev1.ServicePort{{Name: "otlp-grpc", Port: 4317}, {Name: "statsd", Port: 8125}},
+			expected: agentEndpoints{host: "cloudwatch-agent.amazon-cloudwatch", grpcPort: 4317, httpPort: 4316, proxyPort: 2000},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent := v1alpha1.AmazonCloudWatchAgent{
+				ObjectMeta: metav1.ObjectMeta{Name: "cloudwatch-agent", Namespace: "amazon-cloudwatch"},
+				Spec:       v1alpha1.AmazonCloudWatchAgentSpec{Ports: test.ports},
+			}
+			assert.Equal(t, test.expected, agentEndpointsFor(agent))
+		})
+	}
+}
+
+func TestApplyAgentEndpoints(t *testing.T) {
+	hostIPEnv := corev1.EnvVar{
+		Name:      "HOST_IP",
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"}},
+	}
+	endpoints := agentEndpoints{host: "agent.monitoring", grpcPort: 4315, httpPort: 4316, proxyPort: 2000}
+
+	tests := []struct {
+		name       string
+		spec       v1alpha1.InstrumentationSpec
+		javaEnv    []corev1.EnvVar
+		pythonEnv  []corev1.EnvVar
+		goEnv      []corev1.EnvVar
+		nginxAttrs []corev1.EnvVar
+	}{
+		{
+			name: "protocol of each sdk",
+			spec: v1alpha1.InstrumentationSpec{},
+			javaEnv: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent.monitoring:4315"},
+				{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "http://agent.monitoring:4315"},
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://agent.monitoring:4315"},
+				{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "endpoint=http://agent.monitoring:2000"},
+			},
+			pythonEnv: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent.monitoring:4316"},
+				{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "http://agent.monitoring:4316/v1/traces"},
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://agent.monitoring:4316/v1/metrics"},
+				{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "endpoint=http://agent.monitoring:2000"},
+			},
+			goEnv: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "http://agent.monitoring:4315"},
+			},
+			nginxAttrs: []corev1.EnvVar{
+				{Name: "NginxModuleOtelExporterEndpoint", Value: "http://agent.monitoring:4315"},
+			},
+		},
+		{
+			name: "configured protocol and user values",
+			spec: v1alpha1.InstrumentationSpec{
+				Exporter: v1alpha1.Exporter{Protocol: v1alpha1.ExporterProtocolHTTPProtobuf, TracesEndpoint: "http://collector:4318/v1/traces"},
+				Env:      []corev1.EnvVar{{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "0.5"}},
+				Java: v1alpha1.Java{
+					Env: []corev1.EnvVar{{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://smp:4315"}},
+				},
+			},
+			javaEnv: []corev1.EnvVar{
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://smp:4315"},
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent.monitoring:4316"},
+			},
+			pythonEnv: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent.monitoring:4316"},
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://agent.monitoring:4316/v1/metrics"},
+			},
+		},
+		{
+			name: "node local",
+			spec: v1alpha1.InstrumentationSpec{
+				Exporter: v1alpha1.Exporter{Mode: v1alpha1.ExporterModeNodeLocal, Endpoint: "http://collector:4317"},
+			},
+			javaEnv: []corev1.EnvVar{
+				hostIPEnv,
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://$(HOST_IP):4315"},
+				{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "endpoint=http://$(HOST_IP):2000"},
+			},
+			pythonEnv: []corev1.EnvVar{
+				hostIPEnv,
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://$(HOST_IP):4316/v1/metrics"},
+				{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "endpoint=http://$(HOST_IP):2000"},
+			},
+		},
+		{
+			name: "node local keeps the service endpoint of the web servers",
+			spec: v1alpha1.InstrumentationSpec{
+				Exporter: v1alpha1.Exporter{Mode: v1alpha1.ExporterModeNodeLocal},
+				Go:       v1alpha1.Go{Env: []corev1.EnvVar{{Name: "OTEL_GO_AUTO_TARGET_EXE", Value: "/app"}}},
+			},
+			javaEnv: []corev1.EnvVar{
+				hostIPEnv,
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://$(HOST_IP):4315"},
+				{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "http://$(HOST_IP):4315"},
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://$(HOST_IP):4315"},
+				{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "endpoint=http://$(HOST_IP):2000"},
+			},
+			pythonEnv: []corev1.EnvVar{
+				hostIPEnv,
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://$(HOST_IP):4316"},
+				{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "http://$(HOST_IP):4316/v1/traces"},
+				{Name: "OTEL_AWS_SMP_EXPORTER_ENDPOINT", Value: "http://$(HOST_IP):4316/v1/metrics"},
+				{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "endpoint=http://$(HOST_IP):2000"},
+			},
+			goEnv: []corev1.EnvVar{
+				hostIPEnv,
+				{Name: "OTEL_GO_AUTO_TARGET_EXE", Value: "/app"},
+				{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "http://$(HOST_IP):4315"},
+			},
+			nginxAttrs: []corev1.EnvVar{
+				{Name: "NginxModuleOtelExporterEndpoint", Value: "http://agent.monitoring:4315"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := test.spec
+			applyAgentEndpoints(&spec, endpoints)
+			assert.Equal(t, test.javaEnv, spec.Java.Env)
+			assert.Equal(t, test.pythonEnv, spec.Python.Env)
+			assert.Equal(t, test.goEnv, spec.Go.Env)
+			assert.Equal(t, test.nginxAttrs, spec.Nginx.Attrs)
+		})
+	}
+}
+
+func TestOTLPReceiverEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected bool
+	}{
+		{name: "application signals", config: appSignalsAgentConfig, expected: true},
+		{name: "otlp traces", config: `{"traces":{"traces_collected":{"otlp":{}}}}`, expected: true},
+		{name: "xray only", config: `{"traces":{"traces_collected":{"xray":{}}}}`},
+		{name: "no config"},
+		{name: "invalid config", config: `{`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent := v1alpha1.AmazonCloudWatchAgent{Spec: v1alpha1.AmazonCloudWatchAgentSpec{Config: test.config}}
+			assert.Equal(t, test.expected, otlpReceiverEnabled(agent))
+		})
+	}
+}
+
+func TestWithAgentExporter(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	agent := &v1alpha1.AmazonCloudWatchAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "monitoring"},
+		Spec:       v1alpha1.AmazonCloudWatchAgentSpec{Config: `{"traces":{"traces_collected":{"xray":{}}}}`},
+	}
+	recorder := record.NewFakeRecorder(10)
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(agent).Build(), recorder)
+
+	inst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "inst", Namespace: "apps"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Exporter: v1alpha1.Exporter{Agent: &v1alpha1.AgentReference{Name: "agent", Namespace: "monitoring"}},
+		},
+	}
+	resolved, err := podMutator.withAgentExporter(context.Background(), inst)
+	require.NoError(t, err)
+	assert.Contains(t, resolved.Spec.Java.Env, corev1.EnvVar{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "http://agent.monitoring:4315"})
+	assert.Empty(t, inst.Spec.Java.Env)
+	assert.Len(t, recorder.Events, 1)
+
+	inst.Spec.Exporter.Agent.Namespace = ""
+	_, err = podMutator.withAgentExporter(context.Background(), inst)
+	assert.Error(t, err)
+
+	inst.Spec.Exporter = v1alpha1.Exporter{Mode: v1alpha1.ExporterModeNodeLocal}
+	resolved, err = podMutator.withAgentExporter(context.Background(), inst)
+	require.NoError(t, err)
+	assert.Contains(t, resolved.Spec.Java.Env, corev1.EnvVar{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "endpoint=http://$(HOST_IP):2000"})
+	assert.Contains(t, resolved.Spec.Nginx.Attrs, corev1.EnvVar{Name: "NginxModuleOtelExporterEndpoint", Value: "http://cloudwatch-agent.amazon-cloudwatch:4315"})
+}
@@ -13,8 +13,25 @@ const (
 	// annotationInjectJava indicates whether java auto-instrumentation should be injected or not.
 	// Possible values are "true", "false" or "<Instrumentation>" name.
 	annotationInjectJava          = "instrumentation.opentelemetry.io/inject-java"
+	annotationGoExecPath          = "instrumentation.opentelemetry.io/otel-go-auto-target-exe"
+	annotationInjectNginx         = "instrumentation.opentelemetry.io/inject-nginx"
+	annotationInjectApacheHttpd   = "instrumentation.opentelemetry.io/inject-apache-httpd"
+	annotationInjectGo            = "instrumentation.opentelemetry.io/inject-go"
+	annotationInjectDotNet        = "instrumentation.opentelemetry.io/inject-dotnet"
+	annotationInjectNodeJS        = "instrumentation.opentelemetry.io/inject-nodejs"
+	annotationInjectPython        = "instrumentation.opentelemetry.io/inject-python"
 	annotationInjectSdk           = "instrumentation.opentelemetry.io/inject-sdk"
 	annotationInjectContainerName = "instrumentation.opentelemetry.io/container-names"
+	// annotationInjectAuto injects the instrumentation matching the runtime detected for each container.
+	// Possible values are "true", "false" or "<Instrumentation>" name, on the pod or on the namespace.
+	annotationInjectAuto = "instrumentation.opentelemetry.io/inject-auto"
+	// annotationInjectAutoDecision records the detected language of each container and the reason for it.
+	annotationInjectAutoDecision = "instrumentation.opentelemetry.io/inject-auto-decision"
+	// annotationDotNetRuntime selects the .NET runtime build of the CLR profiler, "linux-x64" (default) or "linux-musl-x64".
+	annotationDotNetRuntime = "instrumentation.opentelemetry.io/otel-dotnet-auto-runtime"
+	// annotationEnvPolicyReport records, for each container, the env vars kept, overridden or appended according
+	// to the env policy of the Instrumentation.
+	annotationEnvPolicyReport = "instrumentation.opentelemetry.io/env-policy-report"
 )
 
 // annotationValue returns the effective annotationInjectJava value, based on the annotations from the pod and namespace.
@@ -0,0 +1,99 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectApacheHttpdagent(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:           "app",
+					LivenessProbe:  &corev1.Probe{},
+					ReadinessProbe: &corev1.Probe{},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "httpd-conf", MountPath: "/opt/httpd/conf"},
+					},
+				},
+			},
+		},
+	}
+	resourceMap := map[string]string{"k8s.namespace.name": "apps", "k8s.deployment.name": "web"}
+
+	tests := []struct {
+		name       string
+		spec       v1alpha1.ApacheHttpd
+		confDir    string
+		moduleFile string
+	}{
+		{
+			name:       "defaults",
+			spec:       v1alpha1.ApacheHttpd{Image: "foo/bar:1"},
+			confDir:    "/usr/local/apache2/conf",
+			moduleFile: "libmod_apache_otel.so",
+		},
+		{
+			name:       "version and config path override",
+			spec:       v1alpha1.ApacheHttpd{Image: "foo/bar:1", Version: "2.2", ConfigPath: "/opt/httpd/conf/"},
+			confDir:    "/opt/httpd/conf",
+			moduleFile: "libmod_apache_otel22.so",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := injectApacheHttpdagent(test.spec, *pod.DeepCopy(), 0, "", resourceMap, "web")
+
+			assert.Len(t, got.Spec.InitContainers, 2)
+			clone := got.Spec.InitContainers[0]
+			assert.Equal(t, apacheAgentCloneContainerName, clone.Name)
+			assert.Equal(t, []string{"cp -r " + test.confDir + "/* " + apacheAgentConfDirFull}, clone.Args)
+			assert.Nil(t, clone.LivenessProbe)
+			assert.Nil(t, clone.ReadinessProbe)
+
+			agent := got.Spec.InitContainers[1]
+			assert.Equal(t, apacheAgentInitContainerName, agent.Name)
+			assert.Equal(t, "foo/bar:1", agent.Image)
+			assert.Contains(t, agent.Args[0], "echo 'Include "+test.confDir+"/"+apacheAgentConfigFile+"' >> "+apacheAgentConfDirFull+"/"+apacheConfigFile)
+			conf := agent.Env[0].Value
+			assert.Contains(t, conf, "LoadModule otel_apache_module /opt/opentelemetry-webserver/agent/WebServerModule/Apache/"+test.moduleFile+"\n")
+			assert.Contains(t, conf, "ApacheModuleOtelExporterEndpoint "+otelExporterTracesEndpointDefaultValue+"\n")
+			assert.Contains(t, conf, "ApacheModuleServiceName web\n")
+			assert.Contains(t, conf, "ApacheModuleServiceNamespace apps\n")
+
+			assert.Equal(t, []corev1.VolumeMount{
+				{Name: apacheAgentVolume, MountPath: apacheAgentDirFull},
+				{Name: apacheAgentConfigVolume, MountPath: test.confDir},
+			}, got.Spec.Containers[0].VolumeMounts[len(got.Spec.Containers[0].VolumeMounts)-2:])
+		})
+	}
+}
+
+func TestGetApacheOtelConfigAttrs(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	spec := v1alpha1.ApacheHttpd{
+		Attrs: []corev1.EnvVar{
+			{Name: "ApacheModuleOtelMaxQueueSize", Value: "4096"},
+			{Name: "ApacheModuleResolveBackends", Value: "OFF"},
+		},
+	}
+
+	conf := getApacheOtelConfig(pod, spec, "http://collector:4317", map[string]string{}, "app")
+
+	assert.Contains(t, conf, "ApacheModuleOtelExporterEndpoint http://collector:4317\n")
+	assert.Contains(t, conf, "ApacheModuleOtelMaxQueueSize 4096\n")
+	assert.Contains(t, conf, "ApacheModuleResolveBackends OFF\n")
+	assert.Contains(t, conf, "ApacheModuleServiceName app\n")
+	assert.Contains(t, conf, "ApacheModuleServiceInstanceId "+apacheServiceInstanceId+"\n")
+}
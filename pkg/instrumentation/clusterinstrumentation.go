@@ -0,0 +1,151 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+)
+
+var (
+	envVarSliceType = reflect.TypeOf([]corev1.EnvVar{})
+	apiPkgPath      = reflect.TypeOf(v1alpha1.InstrumentationSpec{}).PkgPath()
+	// wholeValueTypes are the API structs whose fields depend on each other, such as a sampler argument only making
+	// sense for its type, or the agent reference identifying a single object: they are inherited as a whole.
+	wholeValueTypes = map[reflect.Type]bool{
+		reflect.TypeOf(v1alpha1.Sampler{}):         true,
+		reflect.TypeOf(v1alpha1.EnvPolicy{}):       true,
+		reflect.TypeOf(&v1alpha1.ExporterTLS{}):    true,
+		reflect.TypeOf(&v1alpha1.AgentReference{}): true,
+	}
+)
+
+// selectClusterInstrumentation returns the ClusterInstrumentation that applies to the pod, if any. The selection
+// follows the same selector and priority rules as the namespaced Instrumentations.
+func (pm *instPodMutator) selectClusterInstrumentation(ctx context.Context, pod corev1.Pod, requireSelector bool) (*v1alpha1.ClusterInstrumentation, error) {
+	var clusterInsts v1alpha1.ClusterInstrumentationList
+	if err := pm.Client.List(ctx, &clusterInsts); err != nil {
+		return nil, err
+	}
+	candidates := make([]v1alpha1.Instrumentation, len(clusterInsts.Items))
+	for i, clusterInst := range clusterInsts.Items {
+		candidates[i] = v1alpha1.Instrumentation{ObjectMeta: clusterInst.ObjectMeta, Spec: clusterInst.Spec}
+	}
+	selected := selectInstrumentation(candidates, pod, requireSelector)
+	if selected == nil {
+		return nil, nil
+	}
+	for i := range clusterInsts.Items {
+		if clusterInsts.Items[i].Name == selected.Name {
+			return &clusterInsts.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// withClusterDefaults merges the ClusterInstrumentation that applies to the pod underneath the given Instrumentation.
+func (pm *instPodMutator) withClusterDefaults(ctx context.Context, pod corev1.Pod, inst *v1alpha1.Instrumentation) (*v1alpha1.Instrumentation, error) {
+	clusterInst, err := pm.selectClusterInstrumentation(ctx, pod, false)
+	if err != nil || clusterInst == nil {
+		return inst, err
+	}
+	pm.Logger.V(1).Info("inheriting defaults from ClusterInstrumentation", "clusterinstrumentation", clusterInst.Name, "instrumentation-namespace", inst.Namespace, "instrumentation", inst.Name)
+	merged := inst.DeepCopy()
+	webhookDefaults := v1alpha1.DefaultedSpec(inst.Annotations)
+	clearWebhookDefaults(reflect.ValueOf(&merged.Spec).Elem(), reflect.ValueOf(webhookDefaults))
+	mergeInstrumentationSpec(&merged.Spec, clusterInst.Spec)
+	mergeInstrumentationSpec(&merged.Spec, webhookDefaults)
+	return merged, nil
+}
+
+// clearWebhookDefaults unsets the per-language values equal to the ones the webhook defaults them to, so that the
+// ClusterInstrumentation values are inherited in their place; the defaults only apply to what remains unset. As for
+// the upgrade of the images, a value equal to its default is taken for a defaulted one.
+func clearWebhookDefaults(spec, defaults reflect.Value) {
+	for i := 0; i < spec.NumField(); i++ {
+		language := spec.Field(i)
+		if language.Kind() != reflect.Struct || defaults.Field(i).IsZero() {
+			continue
+		}
+		for j := 0; j < language.NumField(); j++ {
+			defaultValue := defaults.Field(i).Field(j)
+			if !defaultValue.IsZero() && equality.Semantic.DeepEqual(language.Field(j).Interface(), defaultValue.Interface()) {
+				language.Field(j).Set(reflect.Zero(defaultValue.Type()))
+			}
+		}
+	}
+}
+
+// defaultInstrumentationWithCluster returns the operator default Instrumentation, overridden by the given ClusterInstrumentation.
+func defaultInstrumentationWithCluster(cfg config.Config, clusterInst *v1alpha1.ClusterInstrumentation) (*v1alpha1.Instrumentation, error) {
+	inst, err := getDefaultInstrumentation(cfg)
+	if err != nil || clusterInst == nil {
+		return inst, err
+	}
+	spec := *clusterInst.Spec.DeepCopy()
+	mergeInstrumentationSpec(&spec, inst.Spec)
+	inst.Spec = spec
+	return inst, nil
+}
+
+// mergeInstrumentationSpec fills dst with the values inherited from defaults, following the merge rules documented
+// on ClusterInstrumentation: set fields in dst win, env vars are merged by name, maps are merged by key, and any
+// other list, as well as the structs in wholeValueTypes, is replaced as a whole. A field is set when it isn't the zero value, which is why the scalars that can
+// be overridden with false, such as the TLS insecure flag, are pointers.
+func mergeInstrumentationSpec(dst *v1alpha1.InstrumentationSpec, defaults v1alpha1.InstrumentationSpec) {
+	mergeValue(reflect.ValueOf(dst).Elem(), reflect.ValueOf(*defaults.DeepCopy()))
+}
+
+func mergeValue(dst, src reflect.Value) {
+	switch {
+	case dst.Type() == envVarSliceType:
+		merged := dst.Interface().([]corev1.EnvVar)
+		for _, env := range src.Interface().([]corev1.EnvVar) {
+			if getIndexOfEnv(merged, env.Name) == -1 {
+				merged = append(merged, env)
+			}
+		}
+		dst.Set(reflect.ValueOf(merged))
+	case dst.Kind() == reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			if !dst.MapIndex(iter.Key()).IsValid() {
+				dst.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+	case dst.Kind() == reflect.Struct && dst.Type().PkgPath() == apiPkgPath && !wholeValueTypes[dst.Type()]:
+		// only the API's own structs are merged field by field, the Kubernetes types are treated as a whole
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Type().Field(i).IsExported() {
+				mergeValue(dst.Field(i), src.Field(i))
+			}
+		}
+	case dst.Kind() == reflect.Pointer && dst.Type().Elem().PkgPath() == apiPkgPath && dst.Type().Elem().Kind() == reflect.Struct &&
+		!wholeValueTypes[dst.Type()]:
+		if src.IsNil() {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(src)
+			return
+		}
+		mergeValue(dst.Elem(), src.Elem())
+	default:
+		if dst.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
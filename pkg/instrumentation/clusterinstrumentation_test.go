@@ -0,0 +1,223 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+)
+
+func TestMergeInstrumentationSpec(t *testing.T) {
+	clusterSpec := v1alpha1.InstrumentationSpec{
+		Exporter:    v1alpha1.Exporter{Endpoint: "http://cluster:4317"},
+		Propagators: []v1alpha1.Propagator{v1alpha1.TraceContext, v1alpha1.Baggage},
+		Sampler:     v1alpha1.Sampler{Type: v1alpha1.ParentBasedTraceIDRatio, Argument: "0.1"},
+		Resource:    v1alpha1.Resource{Attributes: map[string]string{"env": "prod", "owner": "platform"}},
+		Env: []corev1.EnvVar{
+			{Name: "OTEL_LOG_LEVEL", Value: "info"},
+			{Name: "OTEL_BSP_MAX_QUEUE_SIZE", Value: "2048"},
+		},
+		Java: v1alpha1.Java{
+			Image: "cluster/java:1",
+			Env:   []corev1.EnvVar{{Name: "OTEL_INSTRUMENTATION_JDBC_ENABLED", Value: "false"}},
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+			},
+		},
+		Python: v1alpha1.Python{Image: "cluster/python:1"},
+	}
+	spec := v1alpha1.InstrumentationSpec{
+		Propagators: []v1alpha1.Propagator{v1alpha1.XRay},
+		Resource:    v1alpha1.Resource{Attributes: map[string]string{"owner": "payments"}},
+		Env:         []corev1.EnvVar{{Name: "OTEL_LOG_LEVEL", Value: "debug"}},
+		Java: v1alpha1.Java{
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+			},
+		},
+		Python: v1alpha1.Python{Image: "team/python:2"},
+	}
+
+	mergeInstrumentationSpec(&spec, clusterSpec)
+
+	assert.Equal(t, "http://cluster:4317", spec.Endpoint)
+	assert.Equal(t, []v1alpha1.Propagator{v1alpha1.XRay}, spec.Propagators)
+	assert.Equal(t, v1alpha1.Sampler{Type: v1alpha1.ParentBasedTraceIDRatio, Argument: "0.1"}, spec.Sampler)
+	assert.Equal(t, map[string]string{"env": "prod", "owner": "payments"}, spec.Resource.Attributes)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "OTEL_LOG_LEVEL", Value: "debug"},
+		{Name: "OTEL_BSP_MAX_QUEUE_SIZE", Value: "2048"},
+	}, spec.Env)
+	assert.Equal(t, "cluster/java:1", spec.Java.Image)
+	assert.Equal(t, []corev1.EnvVar{{Name: "OTEL_INSTRUMENTATION_JDBC_ENABLED", Value: "false"}}, spec.Java.Env)
+	// resource requirements are inherited as a whole, never key by key
+	assert.Equal(t, corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")}, spec.Java.Resources.Limits)
+	assert.Equal(t, "team/python:2", spec.Python.Image)
+	// the defaults must not be modified
+	assert.Equal(t, map[string]string{"env": "prod", "owner": "platform"}, clusterSpec.Resource.Attributes)
+}
+
+func TestGetInstrumentationInstanceWithClusterDefaults(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	t.Setenv("AUTO_INSTRUMENTATION_JAVA", "java:1")
+	t.Setenv("AUTO_INSTRUMENTATION_PYTHON", "python:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NODEJS", "nodejs:1")
+	t.Setenv("AUTO_INSTRUMENTATION_DOTNET", "dotnet:1")
+	t.Setenv("AUTO_INSTRUMENTATION_GO", "go:1")
+	t.Setenv("AUTO_INSTRUMENTATION_APACHE_HTTPD", "apache-httpd:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NGINX", "nginx:1")
+
+	clusterInst := &v1alpha1.ClusterInstrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-defaults"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Exporter: v1alpha1.Exporter{Endpoint: "http://cluster:4317"},
+			Java:     v1alpha1.Java{Image: "cluster/java:1"},
+		},
+	}
+	nsInst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "team"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Java: v1alpha1.Java{Image: "team/java:2"},
+		},
+	}
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(clusterInst, nsInst).Build(), record.NewFakeRecorder(10))
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "app",
+		Annotations: map[string]string{annotationInjectJava: "true"},
+	}}
+
+	// a namespace without Instrumentation gets the ClusterInstrumentation over the operator defaults
+	inst, err := podMutator.getInstrumentationInstance(context.Background(), corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}, pod, annotationInjectJava)
+	require.NoError(t, err)
+	assert.Equal(t, "http://cluster:4317", inst.Spec.Endpoint)
+	assert.Equal(t, "cluster/java:1", inst.Spec.Java.Image)
+	assert.Equal(t, "python:1", inst.Spec.Python.Image)
+
+	// a namespaced Instrumentation overrides the ClusterInstrumentation
+	inst, err = podMutator.getInstrumentationInstance(context.Background(), corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}}, pod, annotationInjectJava)
+	require.NoError(t, err)
+	assert.Equal(t, "team", inst.Name)
+	assert.Equal(t, "http://cluster:4317", inst.Spec.Endpoint)
+	assert.Equal(t, "team/java:2", inst.Spec.Java.Image)
+	assert.Empty(t, inst.Spec.Python.Image)
+}
+
+func TestGetInstrumentationInstanceInheritsClusterLanguageDefaults(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	clusterResources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+	}
+	clusterInst := &v1alpha1.ClusterInstrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-defaults"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Java:   v1alpha1.Java{Image: "cluster/java:1", Resources: clusterResources},
+			Python: v1alpha1.Python{Image: "cluster/python:1"},
+		},
+	}
+	nsInst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "team",
+			Namespace: "team",
+			Annotations: map[string]string{
+				v1alpha1.AnnotationDefaultAutoInstrumentationJava:   "java:1",
+				v1alpha1.AnnotationDefaultAutoInstrumentationNodeJS: "nodejs:1",
+			},
+		},
+		Spec: v1alpha1.InstrumentationSpec{
+			Python: v1alpha1.Python{Image: "team/python:2"},
+		},
+	}
+	// the Instrumentation is stored as defaulted by its webhook
+	nsInst.Default()
+	require.Equal(t, "java:1", nsInst.Spec.Java.Image)
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(clusterInst, nsInst).Build(), record.NewFakeRecorder(10))
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "app",
+		Annotations: map[string]string{annotationInjectJava: "true"},
+	}}
+
+	inst, err := podMutator.getInstrumentationInstance(context.Background(), corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}}, pod, annotationInjectJava)
+	require.NoError(t, err)
+
+	// the defaulted image and resources give way to the ClusterInstrumentation
+	assert.Equal(t, "cluster/java:1", inst.Spec.Java.Image)
+	assert.Equal(t, clusterResources, inst.Spec.Java.Resources)
+	// the values set in the Instrumentation win
+	assert.Equal(t, "team/python:2", inst.Spec.Python.Image)
+	// what neither sets keeps its default
+	assert.Equal(t, "nodejs:1", inst.Spec.NodeJS.Image)
+	assert.Equal(t, nsInst.Spec.Python.Resources, inst.Spec.Python.Resources)
+	assert.Equal(t, "/etc/nginx/nginx.conf", inst.Spec.Nginx.ConfigFile)
+}
+
+func TestMergeInstrumentationSpecFalseOverride(t *testing.T) {
+	enabled, disabled := true, false
+	clusterSpec := v1alpha1.InstrumentationSpec{
+		Exporter: v1alpha1.Exporter{
+			TLS:   &v1alpha1.ExporterTLS{Insecure: &enabled, ClientCertSecret: "cluster-client"},
+			Agent: &v1alpha1.AgentReference{Name: "cluster-agent", Namespace: "monitoring"},
+		},
+		ApplicationSignals: &v1alpha1.ApplicationSignals{Enabled: &enabled, Endpoint: "http://cluster:4316/v1/metrics"},
+	}
+	spec := v1alpha1.InstrumentationSpec{
+		Exporter: v1alpha1.Exporter{
+			TLS:   &v1alpha1.ExporterTLS{Insecure: &disabled},
+			Agent: &v1alpha1.AgentReference{Name: "team-agent"},
+		},
+		ApplicationSignals: &v1alpha1.ApplicationSignals{Enabled: &disabled},
+	}
+
+	mergeInstrumentationSpec(&spec, clusterSpec)
+
+	// false set in the namespaced spec wins over true, the other fields of the same struct are inherited
+	assert.Equal(t, &v1alpha1.ApplicationSignals{Enabled: &disabled, Endpoint: "http://cluster:4316/v1/metrics"}, spec.ApplicationSignals)
+	// the TLS settings and the agent reference are inherited as a whole
+	assert.Equal(t, &v1alpha1.ExporterTLS{Insecure: &disabled}, spec.TLS)
+	assert.Equal(t, &v1alpha1.AgentReference{Name: "team-agent"}, spec.Agent)
+	assert.True(t, *clusterSpec.TLS.Insecure)
+}
+
+func TestMergeInstrumentationSpecSampler(t *testing.T) {
+	clusterSampler := v1alpha1.Sampler{Type: v1alpha1.ParentBasedTraceIDRatio, Argument: "0.1"}
+	tests := []struct {
+		name    string
+		sampler v1alpha1.Sampler
+		want    v1alpha1.Sampler
+	}{
+		{
+			name: "unset",
+			want: clusterSampler,
+		},
+		{
+			name:    "type without argument",
+			sampler: v1alpha1.Sampler{Type: v1alpha1.AlwaysOn},
+			want:    v1alpha1.Sampler{Type: v1alpha1.AlwaysOn},
+		},
+		{
+			name:    "argument only",
+			sampler: v1alpha1.Sampler{Argument: "0.5"},
+			want:    v1alpha1.Sampler{Argument: "0.5"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := v1alpha1.InstrumentationSpec{Sampler: test.sampler}
+			mergeInstrumentationSpec(&spec, v1alpha1.InstrumentationSpec{Sampler: clusterSampler})
+			assert.Equal(t, test.want, spec.Sampler)
+		})
+	}
+}
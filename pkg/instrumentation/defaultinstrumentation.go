@@ -4,37 +4,85 @@
 package instrumentation
 
 import (
-	"errors"
-	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"fmt"
+	"os"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"os"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
 )
 
 const (
-	defaultAPIVersion                      = "cloudwatch.aws.amazon.com/v1alpha1"
-	defaultInstrumenation                  = "java-instrumentation"
-	defaultNamespace                       = "default"
-	defaultKind                            = "Instrumentation"
-	otelSampleEnabledKey                   = "OTEL_SMP_ENABLED"
-	otelSampleEnabledDefaultValue          = "true"
-	otelTracesSamplerArgKey                = "OTEL_TRACES_SAMPLER_ARG"
-	otelTracesSamplerArgDefaultValue       = "endpoint=http://cloudwatch-agent.amazon-cloudwatch:2000"
-	otelTracesSamplerKey                   = "OTEL_TRACES_SAMPLER"
-	otelTracesSamplerDefaultValue          = "xray"
-	otelExporterTracesEndpointKey          = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
-	otelExporterTracesEndpointDefaultValue = "http://cloudwatch-agent.amazon-cloudwatch:4315"
-	otelExporterSmpEndpointKey             = "OTEL_AWS_SMP_EXPORTER_ENDPOINT"
-	otelExporterSmpEndpointDefaultValue    = "http://cloudwatch-agent.amazon-cloudwatch:4315"
-	otelExporterMetricKey                  = "OTEL_METRICS_EXPORTER"
-	otelExporterMetricDefaultValue         = "none"
+	defaultAPIVersion                          = "cloudwatch.aws.amazon.com/v1alpha1"
+	defaultInstrumenation                      = "java-instrumentation"
+	defaultNamespace                           = "default"
+	defaultKind                                = "Instrumentation"
+	otelSampleEnabledKey                       = "OTEL_SMP_ENABLED"
+	otelSampleEnabledDefaultValue              = true
+	otelTracesSamplerArgKey                    = "OTEL_TRACES_SAMPLER_ARG"
+	otelTracesSamplerArgDefaultValue           = "endpoint=http://cloudwatch-agent.amazon-cloudwatch:2000"
+	otelTracesSamplerKey                       = "OTEL_TRACES_SAMPLER"
+	otelTracesSamplerDefaultValue              = "xray"
+	otelExporterTracesEndpointKey              = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	otelExporterTracesEndpointDefaultValue     = "http://cloudwatch-agent.amazon-cloudwatch:4315"
+	otelExporterSmpEndpointKey                 = "OTEL_AWS_SMP_EXPORTER_ENDPOINT"
+	otelExporterSmpEndpointDefaultValue        = "http://cloudwatch-agent.amazon-cloudwatch:4315"
+	otelExporterMetricDefaultValue             = v1alpha1.SignalExporterNone
+	otelPythonDistroKey                        = "OTEL_PYTHON_DISTRO"
+	otelPythonDistroDefaultValue               = "aws_distro"
+	otelPythonConfiguratorKey                  = "OTEL_PYTHON_CONFIGURATOR"
+	otelPythonConfiguratorDefaultValue         = "aws_configurator"
+	otelHTTPExporterTracesEndpointDefaultValue = "http://cloudwatch-agent.amazon-cloudwatch:4316/v1/traces"
+	otelHTTPExporterSmpEndpointDefaultValue    = "http://cloudwatch-agent.amazon-cloudwatch:4316/v1/metrics"
+	otelDotNetAutoPluginsKey                   = "OTEL_DOTNET_AUTO_PLUGINS"
+	otelDotNetAutoPluginsDefaultValue          = "AWS.Distro.OpenTelemetry.AutoInstrumentation.Plugin, AWS.Distro.OpenTelemetry.AutoInstrumentation"
 )
 
-func getDefaultInstrumentation() (*v1alpha1.Instrumentation, error) {
-	instrumentationImage, ok := os.LookupEnv("AUTO_INSTRUMENTATION_JAVA")
-	if !ok {
-		return nil, errors.New("unable to determine instrumentation image")
+// getDefaultInstrumentation returns the Instrumentation used when none exists in the namespace. Its images and
+// exporter settings come from the operator configuration, falling back to the built-in defaults.
+func getDefaultInstrumentation(cfg config.Config) (*v1alpha1.Instrumentation, error) {
+	javaInstrumentationImage, err := defaultImage(cfg.AutoInstrumentationJavaImage(), "AUTO_INSTRUMENTATION_JAVA", "java")
+	if err != nil {
+		return nil, err
+	}
+	pythonInstrumentationImage, err := defaultImage(cfg.AutoInstrumentationPythonImage(), "AUTO_INSTRUMENTATION_PYTHON", "python")
+	if err != nil {
+		return nil, err
+	}
+	nodeJSInstrumentationImage, err := defaultImage(cfg.AutoInstrumentationNodeJSImage(), "AUTO_INSTRUMENTATION_NODEJS", "nodejs")
+	if err != nil {
+		return nil, err
 	}
+	dotNetInstrumentationImage, err := defaultImage(cfg.AutoInstrumentationDotNetImage(), "AUTO_INSTRUMENTATION_DOTNET", "dotnet")
+	if err != nil {
+		return nil, err
+	}
+	goInstrumentationImage, err := defaultImage(cfg.AutoInstrumentationGoImage(), "AUTO_INSTRUMENTATION_GO", "go")
+	if err != nil {
+		return nil, err
+	}
+	apacheHttpdInstrumentationImage, err := defaultImage(cfg.AutoInstrumentationApacheHttpdImage(), "AUTO_INSTRUMENTATION_APACHE_HTTPD", "apache httpd")
+	if err != nil {
+		return nil, err
+	}
+	nginxInstrumentationImage, err := defaultImage(cfg.AutoInstrumentationNginxImage(), "AUTO_INSTRUMENTATION_NGINX", "nginx")
+	if err != nil {
+		return nil, err
+	}
+	defaults := cfg.DefaultInstrumentation()
+	samplerArg := orDefault(defaults.SamplerArg, otelTracesSamplerArgDefaultValue)
+	sampler := orDefault(defaults.Sampler, otelTracesSamplerDefaultValue)
+	smpEnabled := otelSampleEnabledDefaultValue
+	if defaults.SmpEnabled != nil {
+		smpEnabled = *defaults.SmpEnabled
+	}
+	tracesEndpoint := orDefault(defaults.TracesEndpoint, otelExporterTracesEndpointDefaultValue)
+	smpEndpoint := orDefault(defaults.SmpEndpoint, otelExporterSmpEndpointDefaultValue)
+	httpTracesEndpoint := orDefault(defaults.HTTPTracesEndpoint, otelHTTPExporterTracesEndpointDefaultValue)
+	httpSmpEndpoint := orDefault(defaults.HTTPSmpEndpoint, otelHTTPExporterSmpEndpointDefaultValue)
+	metricsExporter := v1alpha1.SignalExporter(orDefault(defaults.MetricsExporter, string(otelExporterMetricDefaultValue)))
 	return &v1alpha1.Instrumentation{
 		Status: v1alpha1.InstrumentationStatus{},
 		TypeMeta: metav1.TypeMeta{
@@ -52,17 +100,84 @@ func getDefaultInstrumentation() (*v1alpha1.Instrumentation, error) {
 				v1alpha1.B3,
 				v1alpha1.XRay,
 			},
+			// The SMP endpoints depend on the protocol of each SDK, so they are set in the env of the languages.
+			Metrics:            v1alpha1.Signal{Exporter: metricsExporter},
+			ApplicationSignals: &v1alpha1.ApplicationSignals{Enabled: &smpEnabled},
 			Java: v1alpha1.Java{
-				Image: instrumentationImage,
+				Image: javaInstrumentationImage,
+				Env: []corev1.EnvVar{
+					{Name: otelTracesSamplerArgKey, Value: samplerArg},
+					{Name: otelTracesSamplerKey, Value: sampler},
+					{Name: otelExporterTracesEndpointKey, Value: tracesEndpoint},
+					{Name: otelExporterSmpEndpointKey, Value: smpEndpoint},
+				},
+			},
+			// The ADOT Python distro only ships the OTLP/HTTP exporters, so it talks to the agent's otlp-http port.
+			Python: v1alpha1.Python{
+				Image: pythonInstrumentationImage,
+				Env: []corev1.EnvVar{
+					{Name: otelTracesSamplerArgKey, Value: samplerArg},
+					{Name: otelTracesSamplerKey, Value: sampler},
+					{Name: otelExporterTracesEndpointKey, Value: httpTracesEndpoint},
+					{Name: otelExporterSmpEndpointKey, Value: httpSmpEndpoint},
+					{Name: otelPythonDistroKey, Value: otelPythonDistroDefaultValue},
+					{Name: otelPythonConfiguratorKey, Value: otelPythonConfiguratorDefaultValue},
+				},
+			},
+			// The NodeJS SDK defaults to OTLP/HTTP as well.
+			NodeJS: v1alpha1.NodeJS{
+				Image: nodeJSInstrumentationImage,
+				Env: []corev1.EnvVar{
+					{Name: otelTracesSamplerArgKey, Value: samplerArg},
+					{Name: otelTracesSamplerKey, Value: sampler},
+					{Name: otelExporterTracesEndpointKey, Value: httpTracesEndpoint},
+					{Name: otelExporterSmpEndpointKey, Value: httpSmpEndpoint},
+				},
+			},
+			DotNet: v1alpha1.DotNet{
+				Image: dotNetInstrumentationImage,
 				Env: []corev1.EnvVar{
-					{Name: otelSampleEnabledKey, Value: otelSampleEnabledDefaultValue},
-					{Name: otelTracesSamplerArgKey, Value: otelTracesSamplerArgDefaultValue},
-					{Name: otelTracesSamplerKey, Value: otelTracesSamplerDefaultValue},
-					{Name: otelExporterTracesEndpointKey, Value: otelExporterTracesEndpointDefaultValue},
-					{Name: otelExporterSmpEndpointKey, Value: otelExporterSmpEndpointDefaultValue},
-					{Name: otelExporterMetricKey, Value: otelExporterMetricDefaultValue},
+					{Name: otelTracesSamplerArgKey, Value: samplerArg},
+					{Name: otelTracesSamplerKey, Value: sampler},
+					{Name: otelExporterTracesEndpointKey, Value: httpTracesEndpoint},
+					{Name: otelExporterSmpEndpointKey, Value: httpSmpEndpoint},
+					{Name: otelDotNetAutoPluginsKey, Value: otelDotNetAutoPluginsDefaultValue},
 				},
 			},
+			// The Go eBPF agent only exports traces over OTLP/gRPC.
+			Go: v1alpha1.Go{
+				Image: goInstrumentationImage,
+				Env: []corev1.EnvVar{
+					{Name: otelExporterTracesEndpointKey, Value: tracesEndpoint},
+				},
+			},
+			// The web server modules are configured through a generated file and export traces to the
+			// default agent OTLP/gRPC endpoint.
+			ApacheHttpd: v1alpha1.ApacheHttpd{
+				Image: apacheHttpdInstrumentationImage,
+			},
+			Nginx: v1alpha1.Nginx{
+				Image: nginxInstrumentationImage,
+			},
 		},
 	}, nil
 }
+
+// defaultImage returns the image from the operator configuration, or from the env var set by the operator at startup.
+func defaultImage(image, envName, language string) (string, error) {
+	if image != "" {
+		return image, nil
+	}
+	image, ok := os.LookupEnv(envName)
+	if !ok {
+		return "", fmt.Errorf("unable to determine %s instrumentation image", language)
+	}
+	return image, nil
+}
+
+func orDefault(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	return defaultValue
+}
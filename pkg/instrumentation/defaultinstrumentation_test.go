@@ -0,0 +1,51 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+)
+
+func TestGetDefaultInstrumentationFromOperatorConfig(t *testing.T) {
+	t.Setenv("AUTO_INSTRUMENTATION_JAVA", "java:1")
+	t.Setenv("AUTO_INSTRUMENTATION_PYTHON", "python:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NODEJS", "nodejs:1")
+	t.Setenv("AUTO_INSTRUMENTATION_DOTNET", "dotnet:1")
+	t.Setenv("AUTO_INSTRUMENTATION_GO", "go:1")
+	t.Setenv("AUTO_INSTRUMENTATION_APACHE_HTTPD", "apache-httpd:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NGINX", "nginx:1")
+
+	smpEnabled := false
+	cfg := config.New(config.WithOperatorConfig(&config.OperatorConfig{
+		Images: config.Images{Java: "java:2"},
+		DefaultInstrumentation: config.DefaultInstrumentation{
+			SamplerArg:         "endpoint=http://agent.monitoring:2000",
+			SmpEnabled:         &smpEnabled,
+			HTTPTracesEndpoint: "http://agent.monitoring:4316/v1/traces",
+		},
+	}))
+	inst, err := getDefaultInstrumentation(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "java:2", inst.Spec.Java.Image)
+	assert.Equal(t, "python:1", inst.Spec.Python.Image)
+	assert.Contains(t, inst.Spec.Java.Env, corev1.EnvVar{Name: otelTracesSamplerArgKey, Value: "endpoint=http://agent.monitoring:2000"})
+	assert.Equal(t, &v1alpha1.ApplicationSignals{Enabled: &smpEnabled}, inst.Spec.ApplicationSignals)
+	assert.Equal(t, v1alpha1.SignalExporterNone, inst.Spec.Metrics.Exporter)
+	assert.Contains(t, inst.Spec.Java.Env, corev1.EnvVar{Name: otelExporterTracesEndpointKey, Value: otelExporterTracesEndpointDefaultValue})
+	assert.Contains(t, inst.Spec.Python.Env, corev1.EnvVar{Name: otelExporterTracesEndpointKey, Value: "http://agent.monitoring:4316/v1/traces"})
+}
+
+func TestGetDefaultInstrumentationWithoutImage(t *testing.T) {
+	t.Setenv("AUTO_INSTRUMENTATION_JAVA", "java:1")
+	_, err := getDefaultInstrumentation(config.New())
+	assert.Error(t, err)
+}
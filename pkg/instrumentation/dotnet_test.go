@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectDotNetSDK(t *testing.T) {
+	tests := []struct {
+		name         string
+		runtime      string
+		env          []corev1.EnvVar
+		profilerPath string
+		startupHooks string
+		err          string
+	}{
+		{
+			name:         "default runtime",
+			profilerPath: "/otel-auto-instrumentation-dotnet/linux-x64/OpenTelemetry.AutoInstrumentation.Native.so",
+			startupHooks: "/otel-auto-instrumentation-dotnet/net/OpenTelemetry.AutoInstrumentation.StartupHook.dll",
+		},
+		{
+			name:         "musl runtime",
+			runtime:      "linux-musl-x64",
+			profilerPath: "/otel-auto-instrumentation-dotnet/linux-musl-x64/OpenTelemetry.AutoInstrumentation.Native.so",
+			startupHooks: "/otel-auto-instrumentation-dotnet/net/OpenTelemetry.AutoInstrumentation.StartupHook.dll",
+		},
+		{
+			name:         "existing startup hooks are kept",
+			runtime:      "linux-x64",
+			env:          []corev1.EnvVar{{Name: envDotNetStartupHook, Value: "/app/hook.dll"}},
+			profilerPath: "/otel-auto-instrumentation-dotnet/linux-x64/OpenTelemetry.AutoInstrumentation.Native.so",
+			startupHooks: "/app/hook.dll:/otel-auto-instrumentation-dotnet/net/OpenTelemetry.AutoInstrumentation.StartupHook.dll",
+		},
+		{
+			name:    "unsupported runtime",
+			runtime: "win-x64",
+			err:     "provided instrumentation.opentelemetry.io/otel-dotnet-auto-runtime annotation value 'win-x64' is not supported",
+		},
+		{
+			name: "OTEL_DOTNET_AUTO_HOME already set",
+			env:  []corev1.EnvVar{{Name: envDotNetOTelAutoHome, Value: "/otel"}},
+			err:  "OTEL_DOTNET_AUTO_HOME environment variable is already set in the container",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Env: test.env}},
+				},
+			}
+			pod, err := injectDotNetSDK(v1alpha1.DotNet{Image: "dotnet:1"}, pod, 0, test.runtime)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				assert.Empty(t, pod.Spec.InitContainers)
+				return
+			}
+			assert.NoError(t, err)
+
+			envs := map[string]string{}
+			for _, env := range pod.Spec.Containers[0].Env {
+				envs[env.Name] = env.Value
+			}
+			assert.Equal(t, "1", envs[envDotNetCoreClrEnableProfiling])
+			assert.Equal(t, dotNetCoreClrProfilerID, envs[envDotNetCoreClrProfiler])
+			assert.Equal(t, test.profilerPath, envs[envDotNetCoreClrProfilerPath])
+			assert.Equal(t, test.startupHooks, envs[envDotNetStartupHook])
+			assert.Equal(t, "/otel-auto-instrumentation-dotnet/AdditionalDeps", envs[envDotNetAdditionalDeps])
+			assert.Equal(t, "/otel-auto-instrumentation-dotnet/store", envs[envDotNetSharedStore])
+			assert.Len(t, pod.Spec.InitContainers, 1)
+			assert.Equal(t, "dotnet:1", pod.Spec.InitContainers[0].Image)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"encoding/json"
+
+	"github.com/open-telemetry/opentelemetry-operator/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+// envPolicyReport lists the env vars set by the instrumentation in a container: the ones the container already
+// defined, which were kept or overridden, and the ones appended to it.
+type envPolicyReport struct {
+	Kept       []string `json:"kept,omitempty"`
+	Overridden []string `json:"overridden,omitempty"`
+	Appended   []string `json:"appended,omitempty"`
+}
+
+// injectWithEnvPolicy injects the instrumentation into the pod, then applies the env policy to the container at index.
+// The injectors never replace an env var the container defines, so the values the instrumentation would set are
+// found by injecting it again into the container without any env var. They replace the values of the container for
+// the keys enforced by the policy, unless the injector extended the value of the container instead of ignoring it.
+func injectWithEnvPolicy(policy v1alpha1.EnvPolicy, pod corev1.Pod, index int, inject func(corev1.Pod) corev1.Pod) corev1.Pod {
+	if policy.Mode == "" && len(policy.EnforceKeys) == 0 {
+		return inject(pod)
+	}
+	original := pod.DeepCopy()
+	injected := inject(pod)
+	container := &injected.Spec.Containers[index]
+	containerEnv := original.Spec.Containers[index].Env
+	if apiequality.Semantic.DeepEqual(containerEnv, container.Env) {
+		// the instrumentation wasn't injected
+		return injected
+	}
+
+	withoutEnv := *original.DeepCopy()
+	withoutEnv.Spec.Containers[index].Env = nil
+	var report envPolicyReport
+	for _, env := range inject(withoutEnv).Spec.Containers[index].Env {
+		containerIdx := getIndexOfEnv(containerEnv, env.Name)
+		if containerIdx == -1 {
+			report.Appended = append(report.Appended, env.Name)
+			continue
+		}
+		idx := getIndexOfEnv(container.Env, env.Name)
+		if idx == -1 || !apiequality.Semantic.DeepEqual(container.Env[idx], containerEnv[containerIdx]) ||
+			apiequality.Semantic.DeepEqual(container.Env[idx], env) {
+			// the injector extended the value of the container, or would set the same one
+			continue
+		}
+		if !enforcesEnv(policy, env.Name) {
+			report.Kept = append(report.Kept, env.Name)
+			continue
+		}
+		// the new value is moved after the env vars set by the instrumentation, as it may reference them
+		container.Env = append(append(container.Env[:idx], container.Env[idx+1:]...), env)
+		report.Overridden = append(report.Overridden, env.Name)
+	}
+	container.Env = moveEnvToListEnd(container.Env, getIndexOfEnv(container.Env, constants.EnvOTELResourceAttrs))
+	return withEnvPolicyReport(injected, container.Name, report)
+}
+
+// enforcesEnv tells whether the env var set by the instrumentation replaces the one of the container.
+func enforcesEnv(policy v1alpha1.EnvPolicy, name string) bool {
+	if policy.Mode == v1alpha1.EnvPolicyModeOverride {
+		return true
+	}
+	for _, key := range policy.EnforceKeys {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withEnvPolicyReport adds the report of the container to the annotation of the pod, merging it with the report of
+// the instrumentations injected before into the same container.
+func withEnvPolicyReport(pod corev1.Pod, containerName string, report envPolicyReport) corev1.Pod {
+	reports := map[string]envPolicyReport{}
+	if value, ok := pod.Annotations[annotationEnvPolicyReport]; ok {
+		// a malformed annotation, which can only be set by the user, is replaced
+		_ = json.Unmarshal([]byte(value), &reports)
+	}
+	previous := reports[containerName]
+	reports[containerName] = envPolicyReport{
+		Kept:       appendMissing(previous.Kept, report.Kept),
+		Overridden: appendMissing(previous.Overridden, report.Overridden),
+		Appended:   appendMissing(previous.Appended, report.Appended),
+	}
+	value, err := json.Marshal(reports)
+	if err != nil {
+		return pod
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[annotationEnvPolicyReport] = string(value)
+	return pod
+}
+
+func appendMissing(values []string, added []string) []string {
+	for _, value := range added {
+		found := false
+		for _, v := range values {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,211 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
+)
+
+func TestInjectWithEnvPolicy(t *testing.T) {
+	// inject behaves like the injectors: it never replaces an env var, but extends JAVA_TOOL_OPTIONS
+	inject := func(pod corev1.Pod) corev1.Pod {
+		container := &pod.Spec.Containers[0]
+		for _, env := range []corev1.EnvVar{
+			{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent:4317"},
+			{Name: "OTEL_TRACES_SAMPLER", Value: "xray"},
+			{Name: "OTEL_SERVICE_NAME", Value: "checkout"},
+			{Name: "OTEL_PROPAGATORS", Value: "tracecontext"},
+		} {
+			setEnvIfMissing(container, env.Name, env.Value)
+		}
+		if idx := getIndexOfEnv(container.Env, "JAVA_TOOL_OPTIONS"); idx != -1 {
+			container.Env[idx].Value += " -javaagent:/otel/javaagent.jar"
+		} else {
+			container.Env = append(container.Env, corev1.EnvVar{Name: "JAVA_TOOL_OPTIONS", Value: " -javaagent:/otel/javaagent.jar"})
+		}
+		container.Env = append(container.Env, corev1.EnvVar{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "k8s.pod.name=checkout-1"})
+		return pod
+	}
+	newPod := func() corev1.Pod {
+		return corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://collector:4317"},
+				{Name: "OTEL_TRACES_SAMPLER", Value: "always_on"},
+				{Name: "OTEL_SERVICE_NAME", Value: "checkout"},
+				{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g"},
+			},
+		}}}}
+	}
+
+	tests := []struct {
+		name           string
+		policy         v1alpha1.EnvPolicy
+		expectedEnv    []corev1.EnvVar
+		expectedReport *envPolicyReport
+	}{
+		{
+			name: "no policy",
+			expectedEnv: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://collector:4317"},
+				{Name: "OTEL_TRACES_SAMPLER", Value: "always_on"},
+				{Name: "OTEL_SERVICE_NAME", Value: "checkout"},
+				{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g -javaagent:/otel/javaagent.jar"},
+				{Name: "OTEL_PROPAGATORS", Value: "tracecontext"},
+				{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "k8s.pod.name=checkout-1"},
+			},
+		},
+		{
+			name:   "preserve",
+			policy: v1alpha1.EnvPolicy{Mode: v1alpha1.EnvPolicyModePreserve},
+			expectedEnv: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://collector:4317"},
+				{Name: "OTEL_TRACES_SAMPLER", Value: "always_on"},
+				{Name: "OTEL_SERVICE_NAME", Value: "checkout"},
+				{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g -javaagent:/otel/javaagent.jar"},
+				{Name: "OTEL_PROPAGATORS", Value: "tracecontext"},
+				{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "k8s.pod.name=checkout-1"},
+			},
+			expectedReport: &envPolicyReport{
+				Kept:     []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_TRACES_SAMPLER"},
+				Appended: []string{"OTEL_PROPAGATORS", "OTEL_RESOURCE_ATTRIBUTES"},
+			},
+		},
+		{
+			name:   "enforced keys",
+			policy: v1alpha1.EnvPolicy{EnforceKeys: []string{"OTEL_EXPORTER_OTLP_ENDPOINT"}},
+			expectedEnv: []corev1.EnvVar{
+				{Name: "OTEL_TRACES_SAMPLER", Value: "always_on"},
+				{Name: "OTEL_SERVICE_NAME", Value: "checkout"},
+				{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g -javaagent:/otel/javaagent.jar"},
+				{Name: "OTEL_PROPAGATORS", Value: "tracecontext"},
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent:4317"},
+				{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "k8s.pod.name=checkout-1"},
+			},
+			expectedReport: &envPolicyReport{
+				Kept:       []string{"OTEL_TRACES_SAMPLER"},
+				Overridden: []string{"OTEL_EXPORTER_OTLP_ENDPOINT"},
+				Appended:   []string{"OTEL_PROPAGATORS", "OTEL_RESOURCE_ATTRIBUTES"},
+			},
+		},
+		{
+			name:   "override",
+			policy: v1alpha1.EnvPolicy{Mode: v1alpha1.EnvPolicyModeOverride},
+			expectedEnv: []corev1.EnvVar{
+				{Name: "OTEL_SERVICE_NAME", Value: "checkout"},
+				{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g -javaagent:/otel/javaagent.jar"},
+				{Name: "OTEL_PROPAGATORS", Value: "tracecontext"},
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent:4317"},
+				{Name: "OTEL_TRACES_SAMPLER", Value: "xray"},
+				{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "k8s.pod.name=checkout-1"},
+			},
+			expectedReport: &envPolicyReport{
+				Overridden: []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_TRACES_SAMPLER"},
+				Appended:   []string{"OTEL_PROPAGATORS", "OTEL_RESOURCE_ATTRIBUTES"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := injectWithEnvPolicy(tt.policy, newPod(), 0, inject)
+			assert.Equal(t, tt.expectedEnv, pod.Spec.Containers[0].Env)
+
+			value, ok := pod.Annotations[annotationEnvPolicyReport]
+			if tt.expectedReport == nil {
+				assert.False(t, ok)
+				return
+			}
+			var reports map[string]envPolicyReport
+			require.NoError(t, json.Unmarshal([]byte(value), &reports))
+			assert.Equal(t, map[string]envPolicyReport{"app": *tt.expectedReport}, reports)
+		})
+	}
+}
+
+func TestInjectWithEnvPolicyNotInjected(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+		Name: "app",
+		Env:  []corev1.EnvVar{{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://collector:4317"}},
+	}}}}
+	policy := v1alpha1.EnvPolicy{Mode: v1alpha1.EnvPolicyModeOverride}
+
+	injected := injectWithEnvPolicy(policy, pod, 0, func(pod corev1.Pod) corev1.Pod {
+		if len(pod.Spec.Containers[0].Env) > 0 {
+			// the injectors skip the containers whose env they can't extend
+			return pod
+		}
+		pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://agent:4317"}}
+		return pod
+	})
+	assert.Equal(t, []corev1.EnvVar{{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://collector:4317"}}, injected.Spec.Containers[0].Env)
+	assert.NotContains(t, injected.Annotations, annotationEnvPolicyReport)
+}
+
+func TestWithEnvPolicyReport(t *testing.T) {
+	pod := withEnvPolicyReport(corev1.Pod{}, "app", envPolicyReport{Kept: []string{"OTEL_SERVICE_NAME"}, Appended: []string{"OTEL_PROPAGATORS"}})
+	pod = withEnvPolicyReport(pod, "app", envPolicyReport{Overridden: []string{"OTEL_TRACES_SAMPLER"}, Appended: []string{"OTEL_PROPAGATORS", "NODE_OPTIONS"}})
+	pod = withEnvPolicyReport(pod, "worker", envPolicyReport{Appended: []string{"OTEL_SERVICE_NAME"}})
+
+	assert.JSONEq(t, `{
+		"app": {"kept": ["OTEL_SERVICE_NAME"], "overridden": ["OTEL_TRACES_SAMPLER"], "appended": ["OTEL_PROPAGATORS", "NODE_OPTIONS"]},
+		"worker": {"appended": ["OTEL_SERVICE_NAME"]}
+	}`, pod.Annotations[annotationEnvPolicyReport])
+}
+
+func TestInjectJavaWithEnvPolicy(t *testing.T) {
+	inst := v1alpha1.Instrumentation{
+		Spec: v1alpha1.InstrumentationSpec{
+			Java:      v1alpha1.Java{Image: "java:1"},
+			Exporter:  v1alpha1.Exporter{Endpoint: "http://agent:4317"},
+			Sampler:   v1alpha1.Sampler{Type: v1alpha1.XRaySampler},
+			EnvPolicy: v1alpha1.EnvPolicy{EnforceKeys: []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_TRACES_SAMPLER"}},
+		},
+	}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://collector:4317"},
+				{Name: "OTEL_TRACES_SAMPLER", Value: "always_on"},
+				{Name: "OTEL_SERVICE_NAME", Value: "payments"},
+				{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g"},
+			},
+		}}},
+	}
+	injector := sdkInjector{
+		logger: logr.Discard(),
+		owners: owners.NewResolver(fake.NewClientBuilder().Build(), logr.Discard()),
+		config: config.New(),
+	}
+
+	pod, err := injector.inject(context.Background(), languageInstrumentations{Java: &inst}, corev1.Namespace{}, pod, "")
+	require.NoError(t, err)
+	env := pod.Spec.Containers[0].Env
+	assert.Equal(t, "http://agent:4317", env[getIndexOfEnv(env, "OTEL_EXPORTER_OTLP_ENDPOINT")].Value)
+	assert.Equal(t, "xray", env[getIndexOfEnv(env, "OTEL_TRACES_SAMPLER")].Value)
+	assert.Equal(t, "payments", env[getIndexOfEnv(env, "OTEL_SERVICE_NAME")].Value)
+	assert.Equal(t, "-Xmx1g -javaagent:/otel-auto-instrumentation/javaagent.jar", env[getIndexOfEnv(env, "JAVA_TOOL_OPTIONS")].Value)
+	assert.Equal(t, "OTEL_RESOURCE_ATTRIBUTES", env[len(env)-1].Name)
+	assert.Len(t, pod.Spec.InitContainers, 1)
+
+	var reports map[string]envPolicyReport
+	require.NoError(t, json.Unmarshal([]byte(pod.Annotations[annotationEnvPolicyReport]), &reports))
+	assert.Equal(t, []string{"OTEL_SERVICE_NAME"}, reports["app"].Kept)
+	assert.Equal(t, []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_TRACES_SAMPLER"}, reports["app"].Overridden)
+	assert.Contains(t, reports["app"].Appended, "OTEL_RESOURCE_ATTRIBUTES")
+}
@@ -0,0 +1,149 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+const (
+	envOTELExporterOTLPProtocol          = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	envOTELExporterOTLPTracesEndpoint    = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	envOTELExporterOTLPMetricsEndpoint   = "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"
+	envOTELExporterOTLPLogsEndpoint      = "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"
+	envOTELExporterOTLPHeaders           = "OTEL_EXPORTER_OTLP_HEADERS"
+	envOTELExporterOTLPHeaderPrefix      = "OTEL_EXPORTER_OTLP_HEADER_"
+	envOTELExporterOTLPTimeout           = "OTEL_EXPORTER_OTLP_TIMEOUT"
+	envOTELExporterOTLPCompression       = "OTEL_EXPORTER_OTLP_COMPRESSION"
+	envOTELExporterOTLPCertificate       = "OTEL_EXPORTER_OTLP_CERTIFICATE"
+	envOTELExporterOTLPClientCertificate = "OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"
+	envOTELExporterOTLPClientKey         = "OTEL_EXPORTER_OTLP_CLIENT_KEY"
+	envOTELExporterOTLPInsecure          = "OTEL_EXPORTER_OTLP_INSECURE"
+
+	exporterCAVolumeName         = "otel-exporter-ca"
+	exporterCAMountPath          = "/otel-auto-instrumentation-exporter/ca"
+	exporterClientCertVolumeName = "otel-exporter-client-cert"
+	exporterClientCertMountPath  = "/otel-auto-instrumentation-exporter/client"
+)
+
+var nonEnvNameChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// injectExporterConfig turns the structured exporter configuration into the OTEL_EXPORTER_OTLP_* env vars of the
+// container, and mounts the Secrets and ConfigMaps it references. As for every other setting, env vars already
+// defined on the container take precedence.
+func injectExporterConfig(exporter v1alpha1.Exporter, pod corev1.Pod, index int) corev1.Pod {
+	container := &pod.Spec.Containers[index]
+
+	setEnvIfMissing(container, envOTELExporterOTLPProtocol, string(exporter.Protocol))
+	setEnvIfMissing(container, envOTELExporterOTLPTracesEndpoint, exporter.TracesEndpoint)
+	setEnvIfMissing(container, envOTELExporterOTLPMetricsEndpoint, exporter.MetricsEndpoint)
+	setEnvIfMissing(container, envOTELExporterOTLPLogsEndpoint, exporter.LogsEndpoint)
+	setEnvIfMissing(container, envOTELExporterOTLPCompression, string(exporter.Compression))
+	if exporter.Timeout != nil {
+		setEnvIfMissing(container, envOTELExporterOTLPTimeout, strconv.FormatInt(exporter.Timeout.Milliseconds(), 10))
+	}
+
+	if len(exporter.Headers) > 0 && getIndexOfEnv(container.Env, envOTELExporterOTLPHeaders) == -1 {
+		var headers []string
+		for _, header := range exporter.Headers {
+			if header.ValueFrom == nil {
+				headers = append(headers, fmt.Sprintf("%s=%s", header.Name, header.Value))
+				continue
+			}
+			// the secret value is exposed through its own env var, which is then expanded by the kubelet,
+			// so it has to be declared before the headers env var
+			envName := envOTELExporterOTLPHeaderPrefix + nonEnvNameChars.ReplaceAllString(strings.ToUpper(header.Name), "_")
+			if getIndexOfEnv(container.Env, envName) == -1 {
+				container.Env = append(container.Env, corev1.EnvVar{
+					Name:      envName,
+					ValueFrom: &corev1.EnvVarSource{SecretKeyRef: header.ValueFrom.DeepCopy()},
+				})
+			}
+			headers = append(headers, fmt.Sprintf("%s=$(%s)", header.Name, envName))
+		}
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  envOTELExporterOTLPHeaders,
+			Value: strings.Join(headers, ","),
+		})
+	}
+
+	if exporter.TLS == nil {
+		return pod
+	}
+	if exporter.TLS.Insecure != nil && *exporter.TLS.Insecure {
+		setEnvIfMissing(container, envOTELExporterOTLPInsecure, "true")
+	}
+	if ca := exporter.TLS.CA; ca != nil {
+		pod.Spec.Volumes = appendVolumeIfMissing(pod.Spec.Volumes, corev1.Volume{
+			Name: exporterCAVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: ca.LocalObjectReference,
+					Items:                []corev1.KeyToPath{{Key: ca.Key, Path: ca.Key}},
+				},
+			},
+		})
+		container.VolumeMounts = appendVolumeMountIfMissing(container.VolumeMounts, corev1.VolumeMount{
+			Name:      exporterCAVolumeName,
+			MountPath: exporterCAMountPath,
+			ReadOnly:  true,
+		})
+		setEnvIfMissing(container, envOTELExporterOTLPCertificate, exporterCAMountPath+"/"+ca.Key)
+	}
+	if exporter.TLS.ClientCertSecret != "" {
+		pod.Spec.Volumes = appendVolumeIfMissing(pod.Spec.Volumes, corev1.Volume{
+			Name: exporterClientCertVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: exporter.TLS.ClientCertSecret,
+					Items: []corev1.KeyToPath{
+						{Key: corev1.TLSCertKey, Path: corev1.TLSCertKey},
+						{Key: corev1.TLSPrivateKeyKey, Path: corev1.TLSPrivateKeyKey},
+					},
+				},
+			},
+		})
+		container.VolumeMounts = appendVolumeMountIfMissing(container.VolumeMounts, corev1.VolumeMount{
+			Name:      exporterClientCertVolumeName,
+			MountPath: exporterClientCertMountPath,
+			ReadOnly:  true,
+		})
+		setEnvIfMissing(container, envOTELExporterOTLPClientCertificate, exporterClientCertMountPath+"/"+corev1.TLSCertKey)
+		setEnvIfMissing(container, envOTELExporterOTLPClientKey, exporterClientCertMountPath+"/"+corev1.TLSPrivateKeyKey)
+	}
+	return pod
+}
+
+// setEnvIfMissing adds the env var to the container unless it is already defined or the value is empty.
+func setEnvIfMissing(container *corev1.Container, name, value string) {
+	if value == "" || getIndexOfEnv(container.Env, name) > -1 {
+		return
+	}
+	container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+}
+
+func appendVolumeIfMissing(volumes []corev1.Volume, volume corev1.Volume) []corev1.Volume {
+	for _, v := range volumes {
+		if v.Name == volume.Name {
+			return volumes
+		}
+	}
+	return append(volumes, volume)
+}
+
+func appendVolumeMountIfMissing(volumeMounts []corev1.VolumeMount, volumeMount corev1.VolumeMount) []corev1.VolumeMount {
+	for _, m := range volumeMounts {
+		if m.Name == volumeMount.Name {
+			return volumeMounts
+		}
+	}
+	return append(volumeMounts, volumeMount)
+}
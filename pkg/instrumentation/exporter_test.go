@@ -0,0 +1,104 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectExporterConfig(t *testing.T) {
+	exporter := v1alpha1.Exporter{
+		Protocol:        v1alpha1.ExporterProtocolHTTPProtobuf,
+		TracesEndpoint:  "https://collector:4318/v1/traces",
+		MetricsEndpoint: "https://collector:4318/v1/metrics",
+		Headers: []v1alpha1.ExporterHeader{
+			{Name: "x-tenant", Value: "payments"},
+			{Name: "api-key", ValueFrom: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "otlp-auth"},
+				Key:                  "key",
+			}},
+		},
+		Timeout:     &metav1.Duration{Duration: 5 * time.Second},
+		Compression: "gzip",
+		TLS: &v1alpha1.ExporterTLS{
+			CA:               &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "otlp-ca"}, Key: "ca.crt"},
+			ClientCertSecret: "otlp-client",
+		},
+	}
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "app",
+					Env:  []corev1.EnvVar{{Name: "OTEL_EXPORTER_OTLP_COMPRESSION", Value: "none"}},
+				},
+			},
+		},
+	}
+
+	got := injectExporterConfig(exporter, pod, 0)
+
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "OTEL_EXPORTER_OTLP_COMPRESSION", Value: "none"},
+		{Name: "OTEL_EXPORTER_OTLP_PROTOCOL", Value: "http/protobuf"},
+		{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Value: "https://collector:4318/v1/traces"},
+		{Name: "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", Value: "https://collector:4318/v1/metrics"},
+		{Name: "OTEL_EXPORTER_OTLP_TIMEOUT", Value: "5000"},
+		{Name: "OTEL_EXPORTER_OTLP_HEADER_API_KEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "otlp-auth"},
+			Key:                  "key",
+		}}},
+		{Name: "OTEL_EXPORTER_OTLP_HEADERS", Value: "x-tenant=payments,api-key=$(OTEL_EXPORTER_OTLP_HEADER_API_KEY)"},
+		{Name: "OTEL_EXPORTER_OTLP_CERTIFICATE", Value: "/otel-auto-instrumentation-exporter/ca/ca.crt"},
+		{Name: "OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", Value: "/otel-auto-instrumentation-exporter/client/tls.crt"},
+		{Name: "OTEL_EXPORTER_OTLP_CLIENT_KEY", Value: "/otel-auto-instrumentation-exporter/client/tls.key"},
+	}, got.Spec.Containers[0].Env)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: exporterCAVolumeName, MountPath: exporterCAMountPath, ReadOnly: true},
+		{Name: exporterClientCertVolumeName, MountPath: exporterClientCertMountPath, ReadOnly: true},
+	}, got.Spec.Containers[0].VolumeMounts)
+	assert.Equal(t, []corev1.Volume{
+		{
+			Name: exporterCAVolumeName,
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "otlp-ca"},
+				Items:                []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+			}},
+		},
+		{
+			Name: exporterClientCertVolumeName,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: "otlp-client",
+				Items: []corev1.KeyToPath{
+					{Key: "tls.crt", Path: "tls.crt"},
+					{Key: "tls.key", Path: "tls.key"},
+				},
+			}},
+		},
+	}, got.Spec.Volumes)
+
+	// injecting into a second container shares the volumes
+	pod.Spec.Containers = append(got.Spec.Containers, corev1.Container{Name: "worker"})
+	pod.Spec.Volumes = got.Spec.Volumes
+	got = injectExporterConfig(exporter, pod, 1)
+	assert.Len(t, got.Spec.Volumes, 2)
+	assert.Len(t, got.Spec.Containers[1].VolumeMounts, 2)
+}
+
+func TestInjectExporterConfigInsecure(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+
+	insecure := true
+	got := injectExporterConfig(v1alpha1.Exporter{TLS: &v1alpha1.ExporterTLS{Insecure: &insecure}}, pod, 0)
+
+	assert.Equal(t, []corev1.EnvVar{{Name: "OTEL_EXPORTER_OTLP_INSECURE", Value: "true"}}, got.Spec.Containers[0].Env)
+	assert.Empty(t, got.Spec.Volumes)
+}
@@ -0,0 +1,63 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+// AnnotationInjectionFingerprint records on the pod a hash of the Instrumentations it was injected with. A pod whose
+// fingerprint differs from the one its template would get today runs an outdated instrumentation.
+const AnnotationInjectionFingerprint = "instrumentation.opentelemetry.io/injection-fingerprint"
+
+type fingerprintedInstrumentation struct {
+	Namespace string                       `json:"namespace"`
+	Name      string                       `json:"name"`
+	Spec      v1alpha1.InstrumentationSpec `json:"spec"`
+}
+
+// injectionFingerprint hashes the resolved Instrumentations by the role they are injected for. Only their identity
+// and spec are taken into account, so that status or metadata updates don't make pods outdated.
+func injectionFingerprint(insts map[string]*v1alpha1.Instrumentation) string {
+	resolved := map[string]fingerprintedInstrumentation{}
+	for role, inst := range insts {
+		if inst != nil {
+			resolved[role] = fingerprintedInstrumentation{Namespace: inst.Namespace, Name: inst.Name, Spec: inst.Spec}
+		}
+	}
+	// maps are marshaled with sorted keys, which keeps the hash stable
+	content, err := json.Marshal(resolved)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func (insts languageInstrumentations) fingerprint() string {
+	return injectionFingerprint(map[string]*v1alpha1.Instrumentation{
+		"java":         insts.Java,
+		"python":       insts.Python,
+		"nodejs":       insts.NodeJS,
+		"dotnet":       insts.DotNet,
+		"go":           insts.Go,
+		"apache-httpd": insts.ApacheHttpd,
+		"nginx":        insts.Nginx,
+		"sdk":          insts.Sdk,
+	})
+}
+
+func withInjectionFingerprint(pod corev1.Pod, fingerprint string) corev1.Pod {
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[AnnotationInjectionFingerprint] = fingerprint
+	return pod
+}
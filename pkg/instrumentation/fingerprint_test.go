@@ -0,0 +1,33 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectionFingerprint(t *testing.T) {
+	inst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "inst", Namespace: "apps", ResourceVersion: "1"},
+		Spec:       v1alpha1.InstrumentationSpec{Java: v1alpha1.Java{Image: "java:1"}},
+	}
+	fingerprint := languageInstrumentations{Java: inst}.fingerprint()
+	assert.Len(t, fingerprint, 16)
+
+	metadataUpdate := inst.DeepCopy()
+	metadataUpdate.ResourceVersion = "2"
+	metadataUpdate.Labels = map[string]string{"team": "payments"}
+	assert.Equal(t, fingerprint, languageInstrumentations{Java: metadataUpdate}.fingerprint())
+
+	imageUpdate := inst.DeepCopy()
+	imageUpdate.Spec.Java.Image = "java:2"
+	assert.NotEqual(t, fingerprint, languageInstrumentations{Java: imageUpdate}.fingerprint())
+
+	assert.NotEqual(t, fingerprint, languageInstrumentations{Python: inst}.fingerprint())
+}
@@ -0,0 +1,110 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+const (
+	envOtelTargetExe = "OTEL_GO_AUTO_TARGET_EXE"
+
+	kernelDebugVolumeName = "kernel-debug"
+	kernelDebugVolumePath = "/sys/kernel/debug"
+
+	// labelPodSecurityEnforce is the Pod Security Admission label that sets the enforced Pod Security Standard of a namespace.
+	labelPodSecurityEnforce = "pod-security.kubernetes.io/enforce"
+)
+
+// validateGoInjection checks that the Go instrumentation sidecar can be added to the pod.
+// The eBPF agent runs as a privileged root container sharing the process namespace of the application,
+// which only the "privileged" Pod Security Standard admits. It also needs to know which executable to instrument.
+func validateGoInjection(ns corev1.Namespace, pod corev1.Pod) error {
+	switch level := ns.Labels[labelPodSecurityEnforce]; level {
+	case "", "privileged":
+	default:
+		return fmt.Errorf("namespace %s enforces the %q pod security standard, which does not allow the privileged Go instrumentation sidecar", ns.Name, level)
+	}
+
+	// skip instrumentation if share process namespaces is explicitly disabled
+	if pod.Spec.ShareProcessNamespace != nil && !*pod.Spec.ShareProcessNamespace {
+		return fmt.Errorf("shared process namespace has been explicitly disabled")
+	}
+
+	execPath, ok := pod.Annotations[annotationGoExecPath]
+	if !ok {
+		return fmt.Errorf("the %s annotation is required for Go instrumentation", annotationGoExecPath)
+	}
+	if !path.IsAbs(execPath) || path.Clean(execPath) != execPath {
+		return fmt.Errorf("the %s annotation must be a clean absolute path to the executable: %q", annotationGoExecPath, execPath)
+	}
+	return nil
+}
+
+func injectGoSDK(goSpec v1alpha1.Go, pod corev1.Pod) (corev1.Pod, error) {
+	// skip instrumentation if share process namespaces is explicitly disabled
+	if pod.Spec.ShareProcessNamespace != nil && !*pod.Spec.ShareProcessNamespace {
+		return pod, fmt.Errorf("shared process namespace has been explicitly disabled")
+	}
+
+	containerNames, ok := pod.Annotations[annotationInjectContainerName]
+	if ok && len(strings.Split(containerNames, ",")) > 1 {
+		return pod, fmt.Errorf("go instrumentation cannot be injected into a pod using %s with more than 1 container", annotationInjectContainerName)
+	}
+
+	privileged := true
+	rootUser := int64(0)
+	pod.Spec.ShareProcessNamespace = &privileged
+
+	goAgent := corev1.Container{
+		Name:      sideCarName,
+		Image:     goSpec.Image,
+		Resources: goSpec.Resources,
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:  &rootUser,
+			Privileged: &privileged,
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				MountPath: kernelDebugVolumePath,
+				Name:      kernelDebugVolumeName,
+			},
+		},
+	}
+
+	// Annotation takes precedence for OTEL_GO_AUTO_TARGET_EXE
+	execPath, ok := pod.Annotations[annotationGoExecPath]
+	if ok {
+		goAgent.Env = append(goAgent.Env, corev1.EnvVar{
+			Name:  envOtelTargetExe,
+			Value: execPath,
+		})
+	}
+
+	// Inject Go instrumentation spec env vars.
+	// For Go, env vars must be added to the agent container.
+	for _, env := range goSpec.Env {
+		idx := getIndexOfEnv(goAgent.Env, env.Name)
+		if idx == -1 {
+			goAgent.Env = append(goAgent.Env, env)
+		}
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, goAgent)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: kernelDebugVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: kernelDebugVolumePath,
+			},
+		},
+	})
+	return pod, nil
+}
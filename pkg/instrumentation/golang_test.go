@@ -0,0 +1,82 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateGoInjection(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name     string
+		nsLabels map[string]string
+		pod      corev1.Pod
+		err      string
+	}{
+		{
+			name: "valid",
+			pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{annotationGoExecPath: "/app/server"},
+			}},
+		},
+		{
+			name:     "privileged namespace",
+			nsLabels: map[string]string{labelPodSecurityEnforce: "privileged"},
+			pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{annotationGoExecPath: "/app/server"},
+			}},
+		},
+		{
+			name:     "restricted namespace",
+			nsLabels: map[string]string{labelPodSecurityEnforce: "restricted"},
+			pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{annotationGoExecPath: "/app/server"},
+			}},
+			err: "namespace apps enforces the \"restricted\" pod security standard, which does not allow the privileged Go instrumentation sidecar",
+		},
+		{
+			name: "shared process namespace disabled",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotationGoExecPath: "/app/server"}},
+				Spec:       corev1.PodSpec{ShareProcessNamespace: &disabled},
+			},
+			err: "shared process namespace has been explicitly disabled",
+		},
+		{
+			name: "missing target exe",
+			err:  "the instrumentation.opentelemetry.io/otel-go-auto-target-exe annotation is required for Go instrumentation",
+		},
+		{
+			name: "relative target exe",
+			pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{annotationGoExecPath: "app/server"},
+			}},
+			err: "the instrumentation.opentelemetry.io/otel-go-auto-target-exe annotation must be a clean absolute path to the executable: \"app/server\"",
+		},
+		{
+			name: "unclean target exe",
+			pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{annotationGoExecPath: "/app/../bin/server"},
+			}},
+			err: "the instrumentation.opentelemetry.io/otel-go-auto-target-exe annotation must be a clean absolute path to the executable: \"/app/../bin/server\"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: test.nsLabels}}
+			err := validateGoInjection(ns, test.pod)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -7,23 +7,22 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-// Calculate if we already inject InitContainers.
-func isInitContainerMissing(pod corev1.Pod) bool {
-	for _, initContainer := range pod.Spec.InitContainers {
-		if initContainer.Name == initContainerName {
-			return false
-		}
-	}
-	return true
-}
-
 // Checks if Pod is already instrumented by checking Instrumentation InitContainer presence.
 func isAutoInstrumentationInjected(pod corev1.Pod) bool {
 	for _, cont := range pod.Spec.InitContainers {
-		if cont.Name == initContainerName {
+		switch cont.Name {
+		// initContainerName was shared by all the languages before they each had their own
+		case initContainerName,
+			javaInitContainerName, pythonInitContainerName, nodejsInitContainerName, dotnetInitContainerName,
+			apacheAgentInitContainerName, apacheAgentCloneContainerName,
+			nginxAgentInitContainerName, nginxAgentCloneContainerName:
 			return true
 		}
 	}
+	// agents delivered with image volumes have no init container
+	if hasVolume(pod, javaVolumeName) {
+		return true
+	}
 	// Go uses a side car
 	for _, cont := range pod.Spec.Containers {
 		if cont.Name == sideCarName {
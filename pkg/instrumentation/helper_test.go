@@ -0,0 +1,52 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestIsAutoInstrumentationInjected(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		expected bool
+	}{
+		{
+			name: "not instrumented",
+			pod: corev1.Pod{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "migrations"}},
+				Containers:     []corev1.Container{{Name: "app"}},
+			}},
+		},
+		{
+			name:     "sdk init container",
+			pod:      corev1.Pod{Spec: corev1.PodSpec{InitContainers: []corev1.Container{{Name: initContainerName}}}},
+			expected: true,
+		},
+		{
+			name:     "apache",
+			pod:      corev1.Pod{Spec: corev1.PodSpec{InitContainers: []corev1.Container{{Name: apacheAgentCloneContainerName}, {Name: apacheAgentInitContainerName}}}},
+			expected: true,
+		},
+		{
+			name:     "nginx",
+			pod:      corev1.Pod{Spec: corev1.PodSpec{InitContainers: []corev1.Container{{Name: nginxAgentCloneContainerName}, {Name: nginxAgentInitContainerName}}}},
+			expected: true,
+		},
+		{
+			name:     "go sidecar",
+			pod:      corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: sideCarName}}}},
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isAutoInstrumentationInjected(tt.pod))
+		})
+	}
+}
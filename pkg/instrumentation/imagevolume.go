@@ -0,0 +1,82 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/webhookhandler"
+)
+
+// agentDelivery is the resolved way auto-instrumentation agents are made available to the instrumented containers.
+type agentDelivery int
+
+const (
+	agentDeliveryInitContainer agentDelivery = iota
+	agentDeliveryImageVolume
+	agentDeliveryCSI
+
+	csiImageAttribute      = "image"
+	csiPullAlwaysAttribute = "pullAlways"
+)
+
+// resolveAgentDelivery picks Kubernetes image volumes when the cluster enables them, then the CSI driver if any, and
+// falls back to init containers.
+func resolveAgentDelivery(delivery v1alpha1.AgentDelivery, csiDriver string, imageVolumesAvailable bool) agentDelivery {
+	if delivery != v1alpha1.AgentDeliveryImageVolume {
+		return agentDeliveryInitContainer
+	}
+	if imageVolumesAvailable {
+		return agentDeliveryImageVolume
+	}
+	if csiDriver != "" {
+		return agentDeliveryCSI
+	}
+	return agentDeliveryInitContainer
+}
+
+// withImageVolume adds a read-only volume with the content of the image. Kubernetes image volumes have no source in
+// the API types used by the operator, so the volume is added without one and listed in an annotation, from which
+// the webhook sets its source.
+func withImageVolume(pod corev1.Pod, name string, image string, pullPolicy corev1.PullPolicy, delivery agentDelivery, csiDriver string) corev1.Pod {
+	if delivery == agentDeliveryCSI {
+		readOnly := true
+		attributes := map[string]string{csiImageAttribute: image}
+		if pullPolicy == corev1.PullAlways {
+			attributes[csiPullAlwaysAttribute] = "true"
+		}
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				CSI: &corev1.CSIVolumeSource{Driver: csiDriver, ReadOnly: &readOnly, VolumeAttributes: attributes},
+			},
+		})
+		return pod
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: name})
+	imageVolumes := map[string]webhookhandler.ImageVolumeSource{}
+	if pod.Annotations[webhookhandler.AnnotationImageVolumes] != "" {
+		_ = json.Unmarshal([]byte(pod.Annotations[webhookhandler.AnnotationImageVolumes]), &imageVolumes)
+	}
+	imageVolumes[name] = webhookhandler.ImageVolumeSource{Reference: image, PullPolicy: pullPolicy}
+	content, _ := json.Marshal(imageVolumes)
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[webhookhandler.AnnotationImageVolumes] = string(content)
+	return pod
+}
+
+func hasVolume(pod corev1.Pod, name string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == name {
+			return true
+		}
+	}
+	return false
+}
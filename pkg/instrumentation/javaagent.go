@@ -4,6 +4,10 @@
 package instrumentation
 
 import (
+	"fmt"
+	"path"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
@@ -11,16 +15,51 @@ import (
 
 const (
 	envJavaToolsOptions = "JAVA_TOOL_OPTIONS"
-	javaJVMArgument     = " -javaagent:/otel-auto-instrumentation/javaagent.jar"
+	// envJVMOptionsOriginalPrefix prefixes the name given to a JVM options env var defined via ValueFrom, which the
+	// composed variable then references.
+	envJVMOptionsOriginalPrefix = "OTEL_ORIGINAL_"
+
+	javaDefaultAgentPath   = "/javaagent.jar"
+	javaDefaultMountPath   = "/otel-auto-instrumentation"
+	javaAgentFileName      = "javaagent.jar"
+	javaExtensionsDir      = "extensions"
+	javaExtensionContainer = javaInitContainerName + "-extension-%d"
+	// javaExtensionVolumeName and javaExtensionMountPath are used for the extensions mounted from their images, the
+	// volumes of the images being read-only, their mount points can't be nested.
+	javaExtensionVolumeName = javaVolumeName + "-extension-%d"
+	javaExtensionMountPath  = "%s-extension-%d"
 )
 
-func injectJavaagent(javaSpec v1alpha1.Java, pod corev1.Pod, index int) (corev1.Pod, error) {
+func injectJavaagent(javaSpec v1alpha1.Java, pod corev1.Pod, index int, delivery agentDelivery) (corev1.Pod, error) {
 	// caller checks if there is at least one container.
 	container := &pod.Spec.Containers[index]
 
-	err := validateContainerEnv(container.Env, envJavaToolsOptions)
-	if err != nil {
-		return pod, err
+	jvmOptionsEnvName := javaSpec.JVMOptionsEnvName
+	if jvmOptionsEnvName == "" {
+		jvmOptionsEnvName = envJavaToolsOptions
+	}
+	mountPath := javaSpec.MountPath
+	if mountPath == "" {
+		mountPath = javaDefaultMountPath
+	}
+	agentPath := javaSpec.AgentPath
+	if agentPath == "" {
+		agentPath = javaDefaultAgentPath
+	}
+	var jvmArgument string
+	if delivery == agentDeliveryInitContainer {
+		var extensionPaths []string
+		if len(javaSpec.Extensions) > 0 {
+			extensionPaths = []string{path.Join(mountPath, javaExtensionsDir)}
+		}
+		jvmArgument = javaagentArgument(path.Join(mountPath, javaAgentFileName), extensionPaths)
+	} else {
+		// the images are mounted as they are, so the agent and the extensions are loaded from their own paths
+		var extensionPaths []string
+		for i, extension := range javaSpec.Extensions {
+			extensionPaths = append(extensionPaths, path.Join(fmt.Sprintf(javaExtensionMountPath, mountPath, i), extension.Path))
+		}
+		jvmArgument = javaagentArgument(path.Join(mountPath, agentPath), extensionPaths)
 	}
 
 	// inject Java instrumentation spec env vars.
@@ -31,39 +70,111 @@ func injectJavaagent(javaSpec v1alpha1.Java, pod corev1.Pod, index int) (corev1.
 		}
 	}
 
-	idx := getIndexOfEnv(container.Env, envJavaToolsOptions)
-	if idx == -1 {
+	idx := getIndexOfEnv(container.Env, jvmOptionsEnvName)
+	switch {
+	case idx == -1:
 		container.Env = append(container.Env, corev1.EnvVar{
-			Name:  envJavaToolsOptions,
-			Value: javaJVMArgument,
+			Name:  jvmOptionsEnvName,
+			Value: jvmArgument,
 		})
-	} else {
-		container.Env[idx].Value = container.Env[idx].Value + javaJVMArgument
+	case container.Env[idx].ValueFrom != nil:
+		// the value is only known by the kubelet: the original variable is renamed, and the composed one expands it.
+		// It is defined after the original one, which dependent env vars expansion requires.
+		original := envJVMOptionsOriginalPrefix + jvmOptionsEnvName
+		container.Env[idx].Name = original
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  jvmOptionsEnvName,
+			Value: fmt.Sprintf("$(%s)%s", original, jvmArgument),
+		})
+	default:
+		container.Env[idx].Value = container.Env[idx].Value + jvmArgument
+	}
+
+	if delivery != agentDeliveryInitContainer {
+		return injectJavaagentVolumes(javaSpec, pod, index, delivery, mountPath), nil
 	}
 
 	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
-		Name:      volumeName,
-		MountPath: "/otel-auto-instrumentation",
+		Name:      javaVolumeName,
+		MountPath: mountPath,
 	})
 
 	// We just inject Volumes and init containers for the first processed container.
-	if isInitContainerMissing(pod) {
+	if isInitContainerMissingByName(pod, javaInitContainerName) {
 		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
-			Name: volumeName,
+			Name: javaVolumeName,
 			VolumeSource: corev1.VolumeSource{
-				EmptyDir: &corev1.EmptyDirVolumeSource{},
+				EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: javaSpec.VolumeSizeLimit},
 			}})
 
 		pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
-			Name:      initContainerName,
-			Image:     javaSpec.Image,
-			Command:   []string{"cp", "/javaagent.jar", "/otel-auto-instrumentation/javaagent.jar"},
-			Resources: javaSpec.Resources,
+			Name:            javaInitContainerName,
+			Image:           javaSpec.Image,
+			ImagePullPolicy: javaSpec.ImagePullPolicy,
+			Command:         []string{"cp", agentPath, path.Join(mountPath, javaAgentFileName)},
+			Resources:       javaSpec.Resources,
 			VolumeMounts: []corev1.VolumeMount{{
-				Name:      volumeName,
-				MountPath: "/otel-auto-instrumentation",
+				Name:      javaVolumeName,
+				MountPath: mountPath,
 			}},
 		})
+
+		// the extensions directory is a sub path of the volume, which the kubelet creates
+		extensionsPath := path.Join(mountPath, javaExtensionsDir)
+		for i, extension := range javaSpec.Extensions {
+			command := []string{"cp", "-a", strings.TrimSuffix(extension.Path, "/") + "/.", extensionsPath + "/"}
+			if strings.HasSuffix(extension.Path, ".jar") {
+				command = []string{"cp", extension.Path, extensionsPath + "/"}
+			}
+			pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+				Name:            fmt.Sprintf(javaExtensionContainer, i),
+				Image:           extension.Image,
+				ImagePullPolicy: javaSpec.ImagePullPolicy,
+				Command:         command,
+				Resources:       javaSpec.Resources,
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      javaVolumeName,
+					MountPath: extensionsPath,
+					SubPath:   javaExtensionsDir,
+				}},
+			})
+		}
+	}
+	return pod, nil
+}
+
+// injectJavaagentVolumes mounts the images of the javaagent and its extensions as read-only volumes.
+func injectJavaagentVolumes(javaSpec v1alpha1.Java, pod corev1.Pod, index int, delivery agentDelivery, mountPath string) corev1.Pod {
+	container := &pod.Spec.Containers[index]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      javaVolumeName,
+		MountPath: mountPath,
+		ReadOnly:  true,
+	})
+	for i := range javaSpec.Extensions {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      fmt.Sprintf(javaExtensionVolumeName, i),
+			MountPath: fmt.Sprintf(javaExtensionMountPath, mountPath, i),
+			ReadOnly:  true,
+		})
+	}
+
+	// We just inject Volumes for the first processed container.
+	if hasVolume(pod, javaVolumeName) {
+		return pod
+	}
+	pod = withImageVolume(pod, javaVolumeName, javaSpec.Image, javaSpec.ImagePullPolicy, delivery, javaSpec.CSIDriver)
+	for i, extension := range javaSpec.Extensions {
+		pod = withImageVolume(pod, fmt.Sprintf(javaExtensionVolumeName, i), extension.Image, javaSpec.ImagePullPolicy, delivery, javaSpec.CSIDriver)
+	}
+	return pod
+}
+
+// javaagentArgument returns the JVM options loading the javaagent and its extensions.
+func javaagentArgument(agentPath string, extensionPaths []string) string {
+	argument := " -javaagent:" + agentPath
+	if len(extensionPaths) > 0 {
+		argument += " -Dotel.javaagent.extensions=" + strings.Join(extensionPaths, ",")
 	}
-	return pod, err
+	return argument
 }
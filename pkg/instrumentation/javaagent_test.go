@@ -0,0 +1,184 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectJavaagent(t *testing.T) {
+	javaJVMArgument := " -javaagent:/otel-auto-instrumentation/javaagent.jar"
+	jvmOptionsRef := &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "jvm"},
+		Key:                  "options",
+	}}
+
+	tests := []struct {
+		name     string
+		java     v1alpha1.Java
+		env      []corev1.EnvVar
+		expected []corev1.EnvVar
+	}{
+		{
+			name:     "JAVA_TOOL_OPTIONS not defined",
+			expected: []corev1.EnvVar{{Name: envJavaToolsOptions, Value: javaJVMArgument}},
+		},
+		{
+			name:     "JAVA_TOOL_OPTIONS defined",
+			env:      []corev1.EnvVar{{Name: envJavaToolsOptions, Value: "-Xmx1g"}},
+			expected: []corev1.EnvVar{{Name: envJavaToolsOptions, Value: "-Xmx1g" + javaJVMArgument}},
+		},
+		{
+			name: "JAVA_TOOL_OPTIONS defined via ValueFrom",
+			env:  []corev1.EnvVar{{Name: envJavaToolsOptions, ValueFrom: jvmOptionsRef}, {Name: "PORT", Value: "8080"}},
+			expected: []corev1.EnvVar{
+				{Name: "OTEL_ORIGINAL_JAVA_TOOL_OPTIONS", ValueFrom: jvmOptionsRef},
+				{Name: "PORT", Value: "8080"},
+				{Name: envJavaToolsOptions, Value: "$(OTEL_ORIGINAL_JAVA_TOOL_OPTIONS)" + javaJVMArgument},
+			},
+		},
+		{
+			name:     "custom JVM options env var",
+			java:     v1alpha1.Java{JVMOptionsEnvName: "JDK_JAVA_OPTIONS"},
+			env:      []corev1.EnvVar{{Name: envJavaToolsOptions, Value: "-Xmx1g"}},
+			expected: []corev1.EnvVar{{Name: envJavaToolsOptions, Value: "-Xmx1g"}, {Name: "JDK_JAVA_OPTIONS", Value: javaJVMArgument}},
+		},
+		{
+			name: "custom JVM options env var defined via ValueFrom",
+			java: v1alpha1.Java{JVMOptionsEnvName: "APP_JVM_OPTS"},
+			env:  []corev1.EnvVar{{Name: "APP_JVM_OPTS", ValueFrom: jvmOptionsRef}},
+			expected: []corev1.EnvVar{
+				{Name: "OTEL_ORIGINAL_APP_JVM_OPTS", ValueFrom: jvmOptionsRef},
+				{Name: "APP_JVM_OPTS", Value: "$(OTEL_ORIGINAL_APP_JVM_OPTS)" + javaJVMArgument},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Env: test.env}},
+				},
+			}
+			test.java.Image = "java:1"
+			pod, err := injectJavaagent(test.java, pod, 0, agentDeliveryInitContainer)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, pod.Spec.Containers[0].Env)
+			assert.Len(t, pod.Spec.InitContainers, 1)
+		})
+	}
+}
+
+func TestInjectJavaagentExtensions(t *testing.T) {
+	sizeLimit := resource.MustParse("200Mi")
+	java := v1alpha1.Java{
+		Image:           "java:1",
+		AgentPath:       "/opt/agent/opentelemetry-javaagent.jar",
+		MountPath:       "/otel-java",
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		VolumeSizeLimit: &sizeLimit,
+		Extensions: []v1alpha1.JavaExtension{
+			{Image: "processors:1", Path: "/extensions/span-processors.jar"},
+			{Image: "samplers:1", Path: "/extensions/"},
+		},
+	}
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+
+	pod, err := injectJavaagent(java, pod, 0, agentDeliveryInitContainer)
+	require.NoError(t, err)
+
+	container := pod.Spec.Containers[0]
+	assert.Equal(t, []corev1.EnvVar{{
+		Name:  envJavaToolsOptions,
+		Value: " -javaagent:/otel-java/javaagent.jar -Dotel.javaagent.extensions=/otel-java/extensions",
+	}}, container.Env)
+	assert.Equal(t, []corev1.VolumeMount{{Name: javaVolumeName, MountPath: "/otel-java"}}, container.VolumeMounts)
+	assert.Equal(t, &sizeLimit, pod.Spec.Volumes[0].EmptyDir.SizeLimit)
+
+	require.Len(t, pod.Spec.InitContainers, 3)
+	agent := pod.Spec.InitContainers[0]
+	assert.Equal(t, []string{"cp", "/opt/agent/opentelemetry-javaagent.jar", "/otel-java/javaagent.jar"}, agent.Command)
+	assert.Equal(t, corev1.PullIfNotPresent, agent.ImagePullPolicy)
+
+	processors := pod.Spec.InitContainers[1]
+	assert.Equal(t, "opentelemetry-auto-instrumentation-java-extension-0", processors.Name)
+	assert.Equal(t, "processors:1", processors.Image)
+	assert.Equal(t, []string{"cp", "/extensions/span-processors.jar", "/otel-java/extensions/"}, processors.Command)
+	assert.Equal(t, []corev1.VolumeMount{{Name: javaVolumeName, MountPath: "/otel-java/extensions", SubPath: "extensions"}}, processors.VolumeMounts)
+	assert.Equal(t, corev1.PullIfNotPresent, processors.ImagePullPolicy)
+
+	samplers := pod.Spec.InitContainers[2]
+	assert.Equal(t, []string{"cp", "-a", "/extensions/.", "/otel-java/extensions/"}, samplers.Command)
+}
+
+func TestInjectJavaagentImageVolumes(t *testing.T) {
+	java := v1alpha1.Java{
+		Image:           "java:1",
+		Delivery:        v1alpha1.AgentDeliveryImageVolume,
+		CSIDriver:       "csi-image.warm-metal.tech",
+		ImagePullPolicy: corev1.PullAlways,
+		Extensions:      []v1alpha1.JavaExtension{{Image: "processors:1", Path: "/extensions/span-processors.jar"}},
+	}
+	expectedEnv := []corev1.EnvVar{{
+		Name:  envJavaToolsOptions,
+		Value: " -javaagent:/otel-auto-instrumentation/javaagent.jar -Dotel.javaagent.extensions=/otel-auto-instrumentation-extension-0/extensions/span-processors.jar",
+	}}
+	expectedMounts := []corev1.VolumeMount{
+		{Name: javaVolumeName, MountPath: "/otel-auto-instrumentation", ReadOnly: true},
+		{Name: "opentelemetry-auto-instrumentation-java-extension-0", MountPath: "/otel-auto-instrumentation-extension-0", ReadOnly: true},
+	}
+
+	t.Run("image volumes", func(t *testing.T) {
+		pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "worker"}}}}
+		pod, err := injectJavaagent(java, pod, 0, agentDeliveryImageVolume)
+		require.NoError(t, err)
+		pod, err = injectJavaagent(java, pod, 1, agentDeliveryImageVolume)
+		require.NoError(t, err)
+
+		assert.Empty(t, pod.Spec.InitContainers)
+		assert.Equal(t, []corev1.Volume{{Name: javaVolumeName}, {Name: "opentelemetry-auto-instrumentation-java-extension-0"}}, pod.Spec.Volumes)
+		assert.JSONEq(t, `{
+			"opentelemetry-auto-instrumentation-java": {"reference": "java:1", "pullPolicy": "Always"},
+			"opentelemetry-auto-instrumentation-java-extension-0": {"reference": "processors:1", "pullPolicy": "Always"}
+		}`, pod.Annotations["instrumentation.opentelemetry.io/image-volumes"])
+		for _, container := range pod.Spec.Containers {
+			assert.Equal(t, expectedEnv, container.Env)
+			assert.Equal(t, expectedMounts, container.VolumeMounts)
+		}
+		assert.True(t, isAutoInstrumentationInjected(pod))
+	})
+
+	t.Run("CSI", func(t *testing.T) {
+		pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+		pod, err := injectJavaagent(java, pod, 0, agentDeliveryCSI)
+		require.NoError(t, err)
+
+		assert.Empty(t, pod.Spec.InitContainers)
+		assert.Empty(t, pod.Annotations)
+		require.Len(t, pod.Spec.Volumes, 2)
+		csi := pod.Spec.Volumes[0].CSI
+		require.NotNil(t, csi)
+		assert.Equal(t, "csi-image.warm-metal.tech", csi.Driver)
+		assert.Equal(t, map[string]string{"image": "java:1", "pullAlways": "true"}, csi.VolumeAttributes)
+		assert.True(t, *csi.ReadOnly)
+		assert.Equal(t, "processors:1", pod.Spec.Volumes[1].CSI.VolumeAttributes["image"])
+		assert.Equal(t, expectedEnv, pod.Spec.Containers[0].Env)
+	})
+}
+
+func TestResolveAgentDelivery(t *testing.T) {
+	assert.Equal(t, agentDeliveryInitContainer, resolveAgentDelivery("", "csi-image.warm-metal.tech", true))
+	assert.Equal(t, agentDeliveryImageVolume, resolveAgentDelivery(v1alpha1.AgentDeliveryImageVolume, "csi-image.warm-metal.tech", true))
+	assert.Equal(t, agentDeliveryCSI, resolveAgentDelivery(v1alpha1.AgentDeliveryImageVolume, "csi-image.warm-metal.tech", false))
+	assert.Equal(t, agentDeliveryInitContainer, resolveAgentDelivery(v1alpha1.AgentDeliveryImageVolume, "", false))
+}
@@ -0,0 +1,90 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+)
+
+// languageDetection is the outcome of inferring the runtime of a single container.
+type languageDetection struct {
+	container string
+	language  string
+	reason    string
+}
+
+func (d languageDetection) String() string {
+	language := d.language
+	if language == "" {
+		language = "none"
+	}
+	return fmt.Sprintf("%s=%s (%s)", d.container, language, d.reason)
+}
+
+// detectLanguage infers the runtime of the container using the given rules. Signals are evaluated in order of
+// reliability: the command and args first, then well-known env vars, then the image name. Within a signal the
+// first matching rule wins.
+func detectLanguage(rules []config.LanguageDetectionRule, container corev1.Container) languageDetection {
+	detection := languageDetection{container: container.Name}
+
+	executables := containerExecutables(container)
+	for _, rule := range rules {
+		for _, command := range rule.Commands {
+			for _, executable := range executables {
+				if matchesExecutable(executable, command) {
+					detection.language = rule.Language
+					detection.reason = fmt.Sprintf("command %q", executable)
+					return detection
+				}
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		for _, envVar := range rule.EnvVars {
+			if getIndexOfEnv(container.Env, envVar) > -1 {
+				detection.language = rule.Language
+				detection.reason = fmt.Sprintf("env %s", envVar)
+				return detection
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.MatchesImage(container.Image) {
+			detection.language = rule.Language
+			detection.reason = fmt.Sprintf("image %q", container.Image)
+			return detection
+		}
+	}
+
+	detection.reason = "no rule matched"
+	return detection
+}
+
+// containerExecutables returns the base names of the words of the command and args, so that wrappers such as
+// `sh -c "exec java -jar app.jar"` are recognised as well.
+func containerExecutables(container corev1.Container) []string {
+	var executables []string
+	for _, arg := range append(append([]string{}, container.Command...), container.Args...) {
+		for _, word := range strings.Fields(arg) {
+			executables = append(executables, path.Base(strings.Trim(word, `"'`)))
+		}
+	}
+	return executables
+}
+
+// matchesExecutable reports whether the executable is the command, optionally followed by a version, e.g. python3.11.
+func matchesExecutable(executable, command string) bool {
+	if !strings.HasPrefix(executable, command) {
+		return false
+	}
+	return strings.Trim(strings.TrimPrefix(executable, command), "0123456789.") == ""
+}
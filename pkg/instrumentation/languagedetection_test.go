@@ -0,0 +1,155 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name      string
+		container corev1.Container
+		language  string
+		reason    string
+	}{
+		{
+			name:      "java command",
+			container: corev1.Container{Name: "app", Command: []string{"/usr/bin/java"}, Args: []string{"-jar", "app.jar"}},
+			language:  "java",
+			reason:    `command "java"`,
+		},
+		{
+			name:      "versioned python in a shell wrapper",
+			container: corev1.Container{Name: "app", Command: []string{"sh", "-c"}, Args: []string{"exec python3.11 main.py"}},
+			language:  "python",
+			reason:    `command "python3.11"`,
+		},
+		{
+			name:      "javac is not java",
+			container: corev1.Container{Name: "app", Command: []string{"javac"}},
+			reason:    "no rule matched",
+		},
+		{
+			name:      "command wins over env",
+			container: corev1.Container{Name: "app", Command: []string{"node"}, Env: []corev1.EnvVar{{Name: "JAVA_HOME", Value: "/opt/java"}}},
+			language:  "nodejs",
+			reason:    `command "node"`,
+		},
+		{
+			name:      "env",
+			container: corev1.Container{Name: "app", Env: []corev1.EnvVar{{Name: "PYTHONPATH", Value: "/app"}}},
+			language:  "python",
+			reason:    "env PYTHONPATH",
+		},
+		{
+			name:      "image",
+			container: corev1.Container{Name: "app", Image: "mcr.microsoft.com/dotnet/aspnet:7.0"},
+			language:  "dotnet",
+			reason:    `image "mcr.microsoft.com/dotnet/aspnet:7.0"`,
+		},
+		{
+			name:      "unrelated image",
+			container: corev1.Container{Name: "app", Image: "my-registry/nodemon-dashboard/app:1"},
+			reason:    "no rule matched",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			detection := detectLanguage(config.DefaultLanguageDetectionRules(), test.container)
+			assert.Equal(t, test.language, detection.language)
+			assert.Equal(t, test.reason, detection.reason)
+		})
+	}
+}
+
+func TestDetectLanguageCustomRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("- language: java\n  images: ['^registry.example.com/jvm/']\n"), 0600))
+	rules, err := config.LoadLanguageDetectionRules(path)
+	require.NoError(t, err)
+
+	detection := detectLanguage(rules, corev1.Container{Name: "app", Image: "registry.example.com/jvm/orders:3", Command: []string{"python"}})
+
+	assert.Equal(t, "java", detection.language)
+	assert.Equal(t, `image "registry.example.com/jvm/orders:3"`, detection.reason)
+}
+
+func TestMutateInjectAuto(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	inst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-inst", Namespace: "apps"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Java:   v1alpha1.Java{Image: "java:1"},
+			Python: v1alpha1.Python{Image: "python:1"},
+		},
+	}
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(inst).Build(), record.NewFakeRecorder(10))
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "apps",
+		Annotations: map[string]string{annotationInjectAuto: "true"},
+	}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "api", Command: []string{"java", "-jar", "app.jar"}},
+				{Name: "worker", Image: "python:3.11-slim"},
+				{Name: "proxy", Image: "envoyproxy/envoy:v1.27"},
+			},
+		},
+	}
+
+	got, err := podMutator.Mutate(context.Background(), ns, *pod.DeepCopy())
+	require.NoError(t, err)
+
+	assert.Equal(t, `api=java (command "java"), worker=python (image "python:3.11-slim"), proxy=none (no rule matched)`, got.Annotations[annotationInjectAutoDecision])
+	assert.NotEqual(t, -1, getIndexOfEnv(got.Spec.Containers[0].Env, envJavaToolsOptions))
+	assert.NotEqual(t, -1, getIndexOfEnv(got.Spec.Containers[1].Env, envPythonPath))
+	assert.Empty(t, got.Spec.Containers[2].Env)
+	assertLanguageVolumes(t, got, map[string]string{"api": javaVolumeName, "worker": pythonVolumeName})
+
+	// the Java container coming after the Python one gets its own agent as well
+	pod.Spec.Containers[0], pod.Spec.Containers[1] = pod.Spec.Containers[1], pod.Spec.Containers[0]
+	got, err = podMutator.Mutate(context.Background(), ns, *pod.DeepCopy())
+	require.NoError(t, err)
+	assertLanguageVolumes(t, got, map[string]string{"api": javaVolumeName, "worker": pythonVolumeName})
+}
+
+// assertLanguageVolumes checks that each instrumented container mounts the volume of its language, which is created
+// with the init container copying the agent into it.
+func assertLanguageVolumes(t *testing.T, pod corev1.Pod, volumes map[string]string) {
+	initContainers := map[string]string{}
+	for _, initContainer := range pod.Spec.InitContainers {
+		require.Len(t, initContainer.VolumeMounts, 1)
+		initContainers[initContainer.VolumeMounts[0].Name] = initContainer.Image
+	}
+	assert.Equal(t, map[string]string{javaVolumeName: "java:1", pythonVolumeName: "python:1"}, initContainers)
+	assert.Len(t, pod.Spec.Volumes, len(volumes))
+	for _, container := range pod.Spec.Containers {
+		volume, ok := volumes[container.Name]
+		if !ok {
+			assert.Empty(t, container.VolumeMounts)
+			continue
+		}
+		assert.True(t, hasVolume(pod, volume))
+		require.Len(t, container.VolumeMounts, 1)
+		assert.Equal(t, volume, container.VolumeMounts[0].Name)
+	}
+}
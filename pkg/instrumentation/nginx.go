@@ -0,0 +1,193 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+const (
+	nginxDefaultConfigFile       = "/etc/nginx/nginx.conf"
+	nginxAgentConfigFile         = "opentelemetry_agent.conf"
+	nginxAgentDirectory          = "/opt/opentelemetry-webserver"
+	nginxAgentSubDirectory       = "/agent"
+	nginxAgentDirFull            = nginxAgentDirectory + nginxAgentSubDirectory
+	nginxAgentConfigDirectory    = "/source-conf"
+	nginxAgentConfDirFull        = nginxAgentDirectory + nginxAgentConfigDirectory
+	nginxAgentInitContainerName  = "otel-agent-attach-nginx"
+	nginxAgentCloneContainerName = "otel-agent-source-container-clone-nginx"
+	nginxAgentConfigVolume       = "otel-nginx-conf-dir"
+	nginxAgentVolume             = "otel-nginx-agent"
+	nginxAttributesEnvVar        = "OTEL_NGINX_AGENT_CONF"
+	nginxServiceInstanceId       = "<<SID-PLACEHOLDER>>"
+	nginxServiceInstanceIdEnvVar = "OTEL_NGINX_SERVICE_INSTANCE_ID"
+	nginxLibraryPathEnv          = "LD_LIBRARY_PATH"
+)
+
+/*
+	Nginx injection follows the same approach as Apache HTTPD (see apachehttpd.go), with two differences:
+	- the OpenTelemetry module is built per Nginx version, so the clone of the application container also
+	  records the output of `nginx -v` next to the copied configuration
+	- the module is loaded with a `load_module` directive prepended to the configuration file and its
+	  configuration is included inside the `http` block
+*/
+
+func injectNginxSDK(nginxSpec v1alpha1.Nginx, pod corev1.Pod, index int, otlpEndpoint string, resourceMap map[string]string, serviceName string) corev1.Pod {
+
+	// caller checks if there is at least one container
+	container := &pod.Spec.Containers[index]
+
+	// inject env vars
+	for _, env := range nginxSpec.Env {
+		idx := getIndexOfEnv(container.Env, env.Name)
+		if idx == -1 {
+			container.Env = append(container.Env, env)
+		}
+	}
+
+	nginxConfFile := getNginxConfFile(nginxSpec.ConfigFile)
+	nginxConfDir := filepath.Dir(nginxConfFile)
+
+	// First make a clone of the instrumented container to take the existing Nginx configuration
+	// and version from, and create init container from it
+	if isInitContainerMissingByName(pod, nginxAgentCloneContainerName) {
+		// Inject volume for original Nginx configuration
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: nginxAgentConfigVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			}})
+
+		cloneContainer := container.DeepCopy()
+		cloneContainer.Name = nginxAgentCloneContainerName
+		cloneContainer.Command = []string{"/bin/sh", "-c"}
+		cloneContainer.Args = []string{
+			"cp -r " + nginxConfDir + "/* " + nginxAgentConfDirFull + " && " +
+				"export NGINX_VERSION=$( { nginx -v ; } 2>&1 ) && " +
+				"echo ${NGINX_VERSION##*/} > " + nginxAgentConfDirFull + "/version.txt",
+		}
+		cloneContainer.VolumeMounts = append(cloneContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      nginxAgentConfigVolume,
+			MountPath: nginxAgentConfDirFull,
+		})
+		// remove resource requirements since those are then reserved for the lifetime of a pod
+		// and we definitely do not need them for the init container for cp command
+		cloneContainer.Resources = nginxSpec.Resources
+		// probes and lifecycle hooks are not allowed on init containers
+		cloneContainer.LivenessProbe = nil
+		cloneContainer.ReadinessProbe = nil
+		cloneContainer.StartupProbe = nil
+		cloneContainer.Lifecycle = nil
+
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, *cloneContainer)
+
+		// drop volume mount with volume-provided Nginx config from original container
+		// since it could over-write configuration provided by the injection
+		container.VolumeMounts = removeVolumeMountsUnder(container.VolumeMounts, nginxConfDir)
+
+		// Inject volumes info instrumented container - Nginx config dir + Nginx agent
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      nginxAgentVolume,
+			MountPath: nginxAgentDirFull,
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      nginxAgentConfigVolume,
+			MountPath: nginxConfDir,
+		})
+
+		// the module links against the webserver SDK libraries shipped with the agent
+		libraryPath := nginxAgentDirFull + "/sdk_lib/lib"
+		idx := getIndexOfEnv(container.Env, nginxLibraryPathEnv)
+		if idx == -1 {
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name:  nginxLibraryPathEnv,
+				Value: libraryPath,
+			})
+		} else if container.Env[idx].ValueFrom == nil {
+			container.Env[idx].Value = container.Env[idx].Value + ":" + libraryPath
+		}
+	}
+
+	// Inject second init container with instrumentation image
+	// Create / update config files
+	// Copy OTEL module to a shared volume
+	if isInitContainerMissingByName(pod, nginxAgentInitContainerName) {
+		// Inject volume for agent
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: nginxAgentVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			}})
+
+		sourceConfFile := nginxAgentConfDirFull + "/" + filepath.Base(nginxConfFile)
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+			Name:    nginxAgentInitContainerName,
+			Image:   nginxSpec.Image,
+			Command: []string{"/bin/sh", "-c"},
+			Args: []string{
+				// Copy agent binaries to shared volume
+				"cp -ar /opt/opentelemetry/* " + nginxAgentDirFull + " && " +
+					// Pick up the Nginx version recorded by the clone of the application container
+					"export NGINX_VERSION=$(cat " + nginxAgentConfDirFull + "/version.txt) && " +
+					// Create agent configuration file by pasting content of env var to a file
+					"echo \"$" + nginxAttributesEnvVar + "\" > " + nginxAgentConfDirFull + "/" + nginxAgentConfigFile + " && " +
+					"sed -i 's/" + nginxServiceInstanceId + "/'${" + nginxServiceInstanceIdEnvVar + "}'/g' " + nginxAgentConfDirFull + "/" + nginxAgentConfigFile + " && " +
+					// Load the module matching the Nginx version and include its configuration in the http block
+					"sed -i \"1s,^,load_module " + nginxAgentDirFull + "/WebServerModule/Nginx/${NGINX_VERSION}/ngx_http_opentelemetry_module.so;\\n,\" " + sourceConfFile + " && " +
+					"sed -i \"0,/http[[:space:]]*{/s,http[[:space:]]*{,http {\\n    include " + nginxConfDir + "/" + nginxAgentConfigFile + ";,\" " + sourceConfFile,
+			},
+			Env: []corev1.EnvVar{
+				{
+					Name:  nginxAttributesEnvVar,
+					Value: getNginxOtelConfig(pod, nginxSpec, otlpEndpoint, resourceMap, serviceName),
+				},
+				{Name: nginxServiceInstanceIdEnvVar,
+					ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{
+							FieldPath: "metadata.name",
+						},
+					},
+				},
+			},
+			Resources: nginxSpec.Resources,
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      nginxAgentVolume,
+					MountPath: nginxAgentDirFull,
+				},
+				{
+					Name:      nginxAgentConfigVolume,
+					MountPath: nginxAgentConfDirFull,
+				},
+			},
+		})
+	}
+
+	return pod
+}
+
+// getNginxConfFile returns the Nginx configuration file, falling back to the one used by the official image.
+func getNginxConfFile(configFile string) string {
+	if configFile == "" {
+		return nginxDefaultConfigFile
+	}
+	return filepath.Clean(configFile)
+}
+
+// Calculate Nginx agent configuration file based on attributes provided by the injection rules
+// and by the pod values.
+func getNginxOtelConfig(pod corev1.Pod, nginxSpec v1alpha1.Nginx, otelEndpoint string, resourceMap map[string]string, serviceName string) string {
+	attrMap := webServerModuleAttributes("NginxModule", pod, otelEndpoint, resourceMap, serviceName, nginxServiceInstanceId)
+	attrMap["NginxModuleResolveBackends"] = "ON"
+	attrMap["NginxModuleTraceAsError"] = "ON"
+	for _, attr := range nginxSpec.Attrs {
+		attrMap[attr.Name] = attr.Value
+	}
+
+	return webServerModuleDirectives(attrMap, "%s %s;\n")
+}
@@ -0,0 +1,73 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectNginxSDK(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       v1alpha1.Nginx
+		env        []corev1.EnvVar
+		confDir    string
+		confFile   string
+		libraryEnv string
+	}{
+		{
+			name:       "defaults",
+			spec:       v1alpha1.Nginx{Image: "foo/bar:1"},
+			confDir:    "/etc/nginx",
+			confFile:   "nginx.conf",
+			libraryEnv: "/opt/opentelemetry-webserver/agent/sdk_lib/lib",
+		},
+		{
+			name:       "config file override",
+			spec:       v1alpha1.Nginx{Image: "foo/bar:1", ConfigFile: "/opt/nginx/custom.conf"},
+			env:        []corev1.EnvVar{{Name: "LD_LIBRARY_PATH", Value: "/usr/lib"}},
+			confDir:    "/opt/nginx",
+			confFile:   "custom.conf",
+			libraryEnv: "/usr/lib:/opt/opentelemetry-webserver/agent/sdk_lib/lib",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Env: test.env}},
+				},
+			}
+
+			got := injectNginxSDK(test.spec, pod, 0, "http://collector:4317", map[string]string{"k8s.namespace.name": "apps"}, "app")
+
+			assert.Len(t, got.Spec.InitContainers, 2)
+			clone := got.Spec.InitContainers[0]
+			assert.Equal(t, nginxAgentCloneContainerName, clone.Name)
+			assert.Contains(t, clone.Args[0], "cp -r "+test.confDir+"/* "+nginxAgentConfDirFull)
+
+			agent := got.Spec.InitContainers[1]
+			assert.Equal(t, nginxAgentInitContainerName, agent.Name)
+			assert.Contains(t, agent.Args[0], "ngx_http_opentelemetry_module.so;\\n,\" "+nginxAgentConfDirFull+"/"+test.confFile)
+			assert.Contains(t, agent.Args[0], "include "+test.confDir+"/"+nginxAgentConfigFile+";")
+			conf := agent.Env[0].Value
+			assert.Contains(t, conf, "NginxModuleOtelExporterEndpoint http://collector:4317;\n")
+			assert.Contains(t, conf, "NginxModuleServiceName app;\n")
+			assert.Contains(t, conf, "NginxModuleServiceNamespace apps;\n")
+
+			container := got.Spec.Containers[0]
+			assert.Equal(t, []corev1.VolumeMount{
+				{Name: nginxAgentVolume, MountPath: nginxAgentDirFull},
+				{Name: nginxAgentConfigVolume, MountPath: test.confDir},
+			}, container.VolumeMounts)
+			assert.Equal(t, test.libraryEnv, container.Env[getIndexOfEnv(container.Env, nginxLibraryPathEnv)].Value)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectNodeJSSDK(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         []corev1.EnvVar
+		nodeOptions string
+		err         string
+	}{
+		{
+			name:        "NODE_OPTIONS not defined",
+			nodeOptions: " --require /otel-auto-instrumentation-nodejs/autoinstrumentation.js",
+		},
+		{
+			name:        "NODE_OPTIONS defined",
+			env:         []corev1.EnvVar{{Name: envNodeOptions, Value: "--max-old-space-size=4096"}},
+			nodeOptions: "--max-old-space-size=4096 --require /otel-auto-instrumentation-nodejs/autoinstrumentation.js",
+		},
+		{
+			name: "NODE_OPTIONS defined via ValueFrom",
+			env: []corev1.EnvVar{{Name: envNodeOptions, ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "options"},
+			}}},
+			err: "the container defines env var value via ValueFrom, envVar: NODE_OPTIONS",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Env: test.env}},
+				},
+			}
+			pod, err := injectNodeJSSDK(v1alpha1.NodeJS{Image: "nodejs:1"}, pod, 0)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				assert.Empty(t, pod.Spec.InitContainers)
+				return
+			}
+			assert.NoError(t, err)
+
+			container := pod.Spec.Containers[0]
+			idx := getIndexOfEnv(container.Env, envNodeOptions)
+			assert.NotEqual(t, -1, idx)
+			assert.Equal(t, test.nodeOptions, container.Env[idx].Value)
+			assert.Len(t, pod.Spec.InitContainers, 1)
+			assert.Equal(t, "nodejs:1", pod.Spec.InitContainers[0].Image)
+		})
+	}
+}
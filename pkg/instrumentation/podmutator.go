@@ -5,22 +5,23 @@ package instrumentation
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/open-telemetry/opentelemetry-operator/pkg/featuregate"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/webhookhandler"
-)
-
-var (
-	errMultipleInstancesPossible = errors.New("multiple OpenTelemetry Instrumentation instances available, cannot determine which one to select")
+	cwfeaturegate "github.com/aws/amazon-cloudwatch-agent-operator/pkg/featuregate"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
 )
 
 type instPodMutator struct {
@@ -28,22 +29,60 @@ type instPodMutator struct {
 	sdkInjector *sdkInjector
 	Logger      logr.Logger
 	Recorder    record.EventRecorder
+	config      config.Config
 }
 
 type languageInstrumentations struct {
-	Java *v1alpha1.Instrumentation
-	Sdk  *v1alpha1.Instrumentation
+	Java        *v1alpha1.Instrumentation
+	Python      *v1alpha1.Instrumentation
+	NodeJS      *v1alpha1.Instrumentation
+	DotNet      *v1alpha1.Instrumentation
+	Go          *v1alpha1.Instrumentation
+	ApacheHttpd *v1alpha1.Instrumentation
+	Nginx       *v1alpha1.Instrumentation
+	Sdk         *v1alpha1.Instrumentation
+}
+
+// instrumentedLanguage ties the annotation requesting the instrumentation of a language to the feature gate enabling
+// its support.
+type instrumentedLanguage struct {
+	// name of the language in the messages
+	name string
+	// detected is the language of the detection rules, empty when the language isn't detected
+	detected   string
+	annotation string
+	enabled    func() bool
+	set        func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation)
+}
+
+var instrumentedLanguages = []instrumentedLanguage{
+	{name: "Java", detected: "java", annotation: annotationInjectJava, enabled: featuregate.EnableJavaAutoInstrumentationSupport.IsEnabled,
+		set: func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation) { insts.Java = inst }},
+	{name: "Python", detected: "python", annotation: annotationInjectPython, enabled: featuregate.EnablePythonAutoInstrumentationSupport.IsEnabled,
+		set: func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation) { insts.Python = inst }},
+	{name: "NodeJS", detected: "nodejs", annotation: annotationInjectNodeJS, enabled: featuregate.EnableNodeJSAutoInstrumentationSupport.IsEnabled,
+		set: func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation) { insts.NodeJS = inst }},
+	{name: ".NET", detected: "dotnet", annotation: annotationInjectDotNet, enabled: featuregate.EnableDotnetAutoInstrumentationSupport.IsEnabled,
+		set: func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation) { insts.DotNet = inst }},
+	{name: "Go", annotation: annotationInjectGo, enabled: featuregate.EnableGoAutoInstrumentationSupport.IsEnabled,
+		set: func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation) { insts.Go = inst }},
+	{name: "Apache HTTPD", annotation: annotationInjectApacheHttpd, enabled: featuregate.EnableApacheHTTPAutoInstrumentationSupport.IsEnabled,
+		set: func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation) { insts.ApacheHttpd = inst }},
+	{name: "Nginx", annotation: annotationInjectNginx, enabled: cwfeaturegate.EnableNginxAutoInstrumentationSupport.IsEnabled,
+		set: func(insts *languageInstrumentations, inst *v1alpha1.Instrumentation) { insts.Nginx = inst }},
 }
 
 var _ webhookhandler.PodMutator = (*instPodMutator)(nil)
 
-func NewMutator(logger logr.Logger, client client.Client, recorder record.EventRecorder) *instPodMutator {
+func NewMutator(logger logr.Logger, config config.Config, client client.Client, recorder record.EventRecorder) *instPodMutator {
 	return &instPodMutator{
 		Logger: logger,
 		Client: client,
+		config: config,
 		sdkInjector: &sdkInjector{
 			logger: logger,
-			client: client,
+			owners: owners.NewResolver(client, logger),
+			config: config,
 		},
 		Recorder: recorder,
 	}
@@ -64,27 +103,52 @@ func (pm *instPodMutator) Mutate(ctx context.Context, namespace corev1.Namespace
 	insts := languageInstrumentations{}
 
 	// We bail out if any annotation fails to process.
+	for _, language := range instrumentedLanguages {
+		if inst, err = pm.getInstrumentationInstance(ctx, namespace, pod, language.annotation); err != nil {
+			// we still allow the pod to be created, but we log a message to the operator's logs
+			logger.Error(err, "failed to select an OpenTelemetry Instrumentation instance for this pod")
+			return pod, err
+		}
+		if language.enabled() || inst == nil {
+			language.set(&insts, inst)
+		} else {
+			msg := fmt.Sprintf("support for %s auto instrumentation is not enabled", language.name)
+			logger.Error(nil, msg)
+			pm.Recorder.Event(pod.DeepCopy(), "Warning", "InstrumentationRequestRejected", msg)
+		}
+	}
+	if insts.Go != nil {
+		if err = validateGoInjection(namespace, pod); err != nil {
+			logger.Error(err, "Go auto instrumentation cannot be injected into this pod")
+			pm.Recorder.Event(pod.DeepCopy(), "Warning", "InstrumentationRequestRejected", err.Error())
+			insts.Go = nil
+		}
+	}
 
-	if inst, err = pm.getInstrumentationInstance(ctx, namespace, pod, annotationInjectJava); err != nil {
+	if inst, err = pm.getInstrumentationInstance(ctx, namespace, pod, annotationInjectSdk); err != nil {
 		// we still allow the pod to be created, but we log a message to the operator's logs
 		logger.Error(err, "failed to select an OpenTelemetry Instrumentation instance for this pod")
 		return pod, err
 	}
-	if featuregate.EnableJavaAutoInstrumentationSupport.IsEnabled() || inst == nil {
-		insts.Java = inst
-	} else {
-		logger.Error(nil, "support for Java auto instrumentation is not enabled")
-		pm.Recorder.Event(pod.DeepCopy(), "Warning", "InstrumentationRequestRejected", "support for Java auto instrumentation is not enabled")
-	}
+	insts.Sdk = inst
 
-	if inst, err = pm.getInstrumentationInstance(ctx, namespace, pod, annotationInjectSdk); err != nil {
+	var autoInst *v1alpha1.Instrumentation
+	if autoInst, err = pm.getInstrumentationInstance(ctx, namespace, pod, annotationInjectAuto); err != nil {
 		// we still allow the pod to be created, but we log a message to the operator's logs
 		logger.Error(err, "failed to select an OpenTelemetry Instrumentation instance for this pod")
 		return pod, err
 	}
-	insts.Sdk = inst
 
-	if insts.Java == nil && insts.Sdk == nil {
+	// without any annotation, a pod can still be selected by an Instrumentation, unless it opted out of auto-detection
+	if insts.Java == nil && insts.Python == nil && insts.NodeJS == nil && insts.DotNet == nil && insts.Go == nil && insts.ApacheHttpd == nil && insts.Nginx == nil && insts.Sdk == nil && autoInst == nil &&
+		annotationValue(namespace.ObjectMeta, pod.ObjectMeta, annotationInjectAuto) == "" {
+		if autoInst, err = pm.selectInstrumentationInstanceBySelector(ctx, namespace, pod); err != nil {
+			logger.Error(err, "failed to select an OpenTelemetry Instrumentation instance for this pod")
+			return pod, err
+		}
+	}
+
+	if insts.Java == nil && insts.Python == nil && insts.NodeJS == nil && insts.DotNet == nil && insts.Go == nil && insts.ApacheHttpd == nil && insts.Nginx == nil && insts.Sdk == nil && autoInst == nil {
 		logger.V(1).Info("annotation not present in deployment, skipping instrumentation injection")
 		return pod, nil
 	}
@@ -92,16 +156,109 @@ func (pm *instPodMutator) Mutate(ctx context.Context, namespace corev1.Namespace
 	// We retrieve the annotation for podname
 	var targetContainers = annotationValue(namespace.ObjectMeta, pod.ObjectMeta, annotationInjectContainerName)
 
+	if autoInst != nil {
+		// an explicit language annotation is more specific than the detection, so it takes precedence
+		if insts.Java != nil || insts.Python != nil || insts.NodeJS != nil || insts.DotNet != nil || insts.Go != nil || insts.ApacheHttpd != nil || insts.Nginx != nil {
+			logger.V(1).Info("ignoring language auto-detection since a language specific annotation is present")
+		} else {
+			fingerprint := injectionFingerprint(map[string]*v1alpha1.Instrumentation{"auto": autoInst, "sdk": insts.Sdk})
+			modifiedPod, err := pm.injectDetectedLanguages(ctx, namespace, pod, autoInst, insts.Sdk, targetContainers)
+			if err != nil {
+				logger.Error(err, "failed to inject the instrumentation into this pod")
+				return pod, err
+			}
+			return withInjectionFingerprint(modifiedPod, fingerprint), nil
+		}
+	}
+
 	// once it's been determined that instrumentation is desired, none exists yet, and we know which instance it should talk to,
 	// we should inject the instrumentation.
 	modifiedPod := pod
 	for _, currentContainer := range strings.Split(targetContainers, ",") {
-		modifiedPod = pm.sdkInjector.inject(ctx, insts, namespace, modifiedPod, strings.TrimSpace(currentContainer))
+		if modifiedPod, err = pm.sdkInjector.inject(ctx, insts, namespace, modifiedPod, strings.TrimSpace(currentContainer)); rejectsPod(namespace, pod, err) {
+			logger.Error(err, "failed to inject the instrumentation into this pod")
+			return pod, err
+		}
+	}
+
+	return withInjectionFingerprint(modifiedPod, insts.fingerprint()), nil
+}
+
+// injectDetectedLanguages injects the instrumentation matching the runtime inferred for each target container,
+// and records every decision with its reason in an annotation on the pod.
+func (pm *instPodMutator) injectDetectedLanguages(ctx context.Context, namespace corev1.Namespace, pod corev1.Pod, inst *v1alpha1.Instrumentation, sdkInst *v1alpha1.Instrumentation, targetContainers string) (corev1.Pod, error) {
+	logger := pm.Logger.WithValues("namespace", pod.Namespace, "name", pod.Name)
+
+	var containerNames []string
+	if len(targetContainers) > 0 {
+		for _, name := range strings.Split(targetContainers, ",") {
+			containerNames = append(containerNames, strings.TrimSpace(name))
+		}
+	} else {
+		for _, container := range pod.Spec.Containers {
+			containerNames = append(containerNames, container.Name)
+		}
 	}
 
+	var decisions []string
+	modifiedPod := pod
+	for _, name := range containerNames {
+		var err error
+		container, found := getContainerByName(pod, name)
+		if !found {
+			decisions = append(decisions, languageDetection{container: name, reason: "container not found"}.String())
+			continue
+		}
+		detection := detectLanguage(pm.config.LanguageDetectionRules(), container)
+		if detection.language != "" {
+			insts, enabled := languageInstrumentationsFor(detection.language, inst)
+			if !enabled {
+				msg := fmt.Sprintf("support for %s auto instrumentation is not enabled", detection.language)
+				logger.Error(nil, msg)
+				pm.Recorder.Event(pod.DeepCopy(), "Warning", "InstrumentationRequestRejected", msg)
+				detection.reason += "; skipped, " + msg
+			} else {
+				logger.V(1).Info("detected container language", "container", name, "language", detection.language, "reason", detection.reason)
+				insts.Sdk = sdkInst
+				modifiedPod, err = pm.sdkInjector.inject(ctx, insts, namespace, modifiedPod, name)
+			}
+		} else if sdkInst != nil {
+			modifiedPod, err = pm.sdkInjector.inject(ctx, languageInstrumentations{Sdk: sdkInst}, namespace, modifiedPod, name)
+		}
+		if rejectsPod(namespace, pod, err) {
+			return pod, err
+		}
+		decisions = append(decisions, detection.String())
+	}
+
+	if modifiedPod.Annotations == nil {
+		modifiedPod.Annotations = map[string]string{}
+	}
+	modifiedPod.Annotations[annotationInjectAutoDecision] = strings.Join(decisions, ", ")
 	return modifiedPod, nil
 }
 
+// languageInstrumentationsFor selects the injector for a detected language, and reports whether its support is enabled.
+func languageInstrumentationsFor(language string, inst *v1alpha1.Instrumentation) (languageInstrumentations, bool) {
+	insts := languageInstrumentations{}
+	for _, l := range instrumentedLanguages {
+		if l.detected != "" && l.detected == language {
+			l.set(&insts, inst)
+			return insts, l.enabled()
+		}
+	}
+	return insts, false
+}
+
+func getContainerByName(pod corev1.Pod, name string) (corev1.Container, bool) {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return container, true
+		}
+	}
+	return corev1.Container{}, false
+}
+
 func (pm *instPodMutator) getInstrumentationInstance(ctx context.Context, namespace corev1.Namespace, pod corev1.Pod, instAnnotation string) (*v1alpha1.Instrumentation, error) {
 	instValue := annotationValue(namespace.ObjectMeta, pod.ObjectMeta, instAnnotation)
 
@@ -110,7 +267,7 @@ func (pm *instPodMutator) getInstrumentationInstance(ctx context.Context, namesp
 	}
 
 	if strings.EqualFold(instValue, "true") {
-		return pm.selectInstrumentationInstanceFromNamespace(ctx, namespace)
+		return pm.selectInstrumentationInstanceFromNamespace(ctx, namespace, pod)
 	}
 
 	var instNamespacedName types.NamespacedName
@@ -123,24 +280,151 @@ func (pm *instPodMutator) getInstrumentationInstance(ctx context.Context, namesp
 	otelInst := &v1alpha1.Instrumentation{}
 	err := pm.Client.Get(ctx, instNamespacedName, otelInst)
 	if err != nil {
-		return nil, err
+		return nil, pm.withInheritedFailurePolicy(ctx, pod, err)
 	}
 
-	return otelInst, nil
+	return pm.resolveInstrumentation(ctx, pod, otelInst)
 }
 
-func (pm *instPodMutator) selectInstrumentationInstanceFromNamespace(ctx context.Context, namespace corev1.Namespace) (*v1alpha1.Instrumentation, error) {
+func (pm *instPodMutator) selectInstrumentationInstanceFromNamespace(ctx context.Context, namespace corev1.Namespace, pod corev1.Pod) (*v1alpha1.Instrumentation, error) {
 	var otelInsts v1alpha1.InstrumentationList
 	if err := pm.Client.List(ctx, &otelInsts, client.InNamespace(namespace.Name)); err != nil {
 		return nil, err
 	}
-	switch items := len(otelInsts.Items); {
-	case items == 0:
-		pm.Logger.Info("no OpenTelemetry Instrumentation instances available. Using default Instrumentation instance")
-		return getDefaultInstrumentation()
-	case items > 1:
-		return nil, errMultipleInstancesPossible
-	default:
-		return &otelInsts.Items[0], nil
+	// a namespace without Instrumentation selecting the pod is treated as one without Instrumentation at all
+	inst := selectInstrumentation(otelInsts.Items, pod, false)
+	if inst == nil {
+		clusterInst, err := pm.selectClusterInstrumentation(ctx, pod, false)
+		if err != nil {
+			return nil, err
+		}
+		if clusterInst != nil {
+			pm.Logger.Info("no OpenTelemetry Instrumentation instance selects the pod. Using ClusterInstrumentation over the default Instrumentation instance", "clusterinstrumentation", clusterInst.Name, "candidates", len(otelInsts.Items))
+		} else {
+			pm.Logger.Info("no OpenTelemetry Instrumentation instance selects the pod. Using default Instrumentation instance", "candidates", len(otelInsts.Items))
+		}
+		return pm.resolveDefaultInstrumentation(ctx, clusterInst)
 	}
+	pm.Logger.V(1).Info("selected OpenTelemetry Instrumentation instance for pod", "namespace", namespace.Name, "pod", pod.Name, "instrumentation", inst.Name, "priority", inst.Spec.Priority, "candidates", len(otelInsts.Items))
+	return pm.resolveInstrumentation(ctx, pod, inst)
+}
+
+// selectInstrumentationInstanceBySelector returns the Instrumentation whose selector matches the pod, if any, so that
+// pods can be instrumented without an annotation. Namespaced Instrumentations are preferred over ClusterInstrumentations.
+func (pm *instPodMutator) selectInstrumentationInstanceBySelector(ctx context.Context, namespace corev1.Namespace, pod corev1.Pod) (*v1alpha1.Instrumentation, error) {
+	var otelInsts v1alpha1.InstrumentationList
+	if err := pm.Client.List(ctx, &otelInsts, client.InNamespace(namespace.Name)); err != nil {
+		return nil, err
+	}
+	if inst := selectInstrumentation(otelInsts.Items, pod, true); inst != nil {
+		pm.Logger.V(1).Info("selected OpenTelemetry Instrumentation instance for pod by its selector", "namespace", namespace.Name, "pod", pod.Name, "instrumentation", inst.Name, "priority", inst.Spec.Priority)
+		return pm.resolveInstrumentation(ctx, pod, inst)
+	}
+
+	clusterInst, err := pm.selectClusterInstrumentation(ctx, pod, true)
+	if err != nil || clusterInst == nil {
+		return nil, err
+	}
+	pm.Logger.V(1).Info("selected ClusterInstrumentation for pod by its selector", "namespace", namespace.Name, "pod", pod.Name, "clusterinstrumentation", clusterInst.Name, "priority", clusterInst.Spec.Priority)
+	return pm.resolveDefaultInstrumentation(ctx, clusterInst)
+}
+
+// resolveInstrumentation applies the cluster defaults and the agent reference to the Instrumentation selected for the pod.
+// Its signal settings are applied before the cluster defaults are merged, so that they take precedence over the env
+// vars inherited from them, and again afterwards for the settings inherited from the ClusterInstrumentation.
+func (pm *instPodMutator) resolveInstrumentation(ctx context.Context, pod corev1.Pod, inst *v1alpha1.Instrumentation) (*v1alpha1.Instrumentation, error) {
+	resolved, err := pm.withClusterDefaults(ctx, pod, withSignals(inst))
+	if err != nil {
+		return nil, withFailurePolicy(inst.Spec.FailurePolicy, err)
+	}
+	if resolved, err = pm.withAgentExporter(ctx, withSignals(resolved)); err != nil {
+		return nil, withFailurePolicy(inst.Spec.FailurePolicy, err)
+	}
+	return resolved, nil
+}
+
+// resolveDefaultInstrumentation returns the operator default Instrumentation, overridden by the given ClusterInstrumentation.
+// The signal settings and agent endpoints of the ClusterInstrumentation are resolved first, so that they take
+// precedence over the default ones.
+func (pm *instPodMutator) resolveDefaultInstrumentation(ctx context.Context, clusterInst *v1alpha1.ClusterInstrumentation) (*v1alpha1.Instrumentation, error) {
+	if clusterInst != nil {
+		resolved := withSignals(&v1alpha1.Instrumentation{ObjectMeta: clusterInst.ObjectMeta, Spec: clusterInst.Spec})
+		if resolvesAgentEndpoints(clusterInst.Spec.Exporter) {
+			var err error
+			if resolved, err = pm.withAgentExporter(ctx, resolved); err != nil {
+				return nil, withFailurePolicy(clusterInst.Spec.FailurePolicy, err)
+			}
+		}
+		clusterInst = &v1alpha1.ClusterInstrumentation{ObjectMeta: resolved.ObjectMeta, Spec: resolved.Spec}
+	}
+	inst, err := defaultInstrumentationWithCluster(pm.config, clusterInst)
+	if err != nil {
+		return nil, err
+	}
+	return withSignals(inst), nil
+}
+
+// withInheritedFailurePolicy attaches to the error the failure policy that an Instrumentation that couldn't be read
+// would have inherited from the ClusterInstrumentation that applies to the pod.
+func (pm *instPodMutator) withInheritedFailurePolicy(ctx context.Context, pod corev1.Pod, err error) error {
+	clusterInst, clusterErr := pm.selectClusterInstrumentation(ctx, pod, false)
+	if clusterErr != nil || clusterInst == nil {
+		return err
+	}
+	return withFailurePolicy(clusterInst.Spec.FailurePolicy, err)
+}
+
+// rejectsPod tells whether an injection failure rejects the pod, rather than admitting it with the instrumentations
+// that could be injected.
+func rejectsPod(namespace corev1.Namespace, pod corev1.Pod, err error) bool {
+	return err != nil && webhookhandler.ResolveFailurePolicy(namespace, pod, err) == v1alpha1.FailurePolicyFail
+}
+
+// withFailurePolicy attaches the failure policy of the Instrumentation that couldn't be resolved to the error, so that
+// the webhook can reject the pod when it is set to Fail.
+func withFailurePolicy(policy v1alpha1.FailurePolicy, err error) error {
+	if policy == "" {
+		return err
+	}
+	return &webhookhandler.FailurePolicyError{Policy: policy, Err: err}
+}
+
+// selectInstrumentation picks the Instrumentation with the highest priority among the ones that apply to the pod.
+// Ties are broken in favour of an Instrumentation with a selector, as it is more specific, then by name so that
+// the choice is deterministic. When requireSelector is set, Instrumentations without a selector are ignored.
+func selectInstrumentation(insts []v1alpha1.Instrumentation, pod corev1.Pod, requireSelector bool) *v1alpha1.Instrumentation {
+	var selected *v1alpha1.Instrumentation
+	for i := range insts {
+		candidate := &insts[i]
+		hasSelector := !isEmptySelector(candidate.Spec.Selector)
+		if requireSelector && !hasSelector {
+			continue
+		}
+		if hasSelector {
+			selector, err := metav1.LabelSelectorAsSelector(candidate.Spec.Selector)
+			if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+		}
+		if selected == nil || preferInstrumentation(candidate, selected) {
+			selected = candidate
+		}
+	}
+	return selected
+}
+
+func preferInstrumentation(candidate, current *v1alpha1.Instrumentation) bool {
+	if candidate.Spec.Priority != current.Spec.Priority {
+		return candidate.Spec.Priority > current.Spec.Priority
+	}
+	candidateHasSelector := !isEmptySelector(candidate.Spec.Selector)
+	currentHasSelector := !isEmptySelector(current.Spec.Selector)
+	if candidateHasSelector != currentHasSelector {
+		return candidateHasSelector
+	}
+	return candidate.Name < current.Name
+}
+
+func isEmptySelector(selector *metav1.LabelSelector) bool {
+	return selector == nil || (len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0)
 }
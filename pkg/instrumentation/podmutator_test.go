@@ -5,17 +5,24 @@ package instrumentation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
 
 	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/webhookhandler"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/securitycontext"
 
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -33,13 +40,376 @@ func TestGetInstrumentationInstanceFromNameSpaceDefault(t *testing.T) {
 		fmt.Printf("failed to register scheme: %v", err)
 		os.Exit(1)
 	}
+	t.Setenv("AUTO_INSTRUMENTATION_JAVA", "java:1")
+	t.Setenv("AUTO_INSTRUMENTATION_PYTHON", "python:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NODEJS", "nodejs:1")
+	t.Setenv("AUTO_INSTRUMENTATION_DOTNET", "dotnet:1")
+	t.Setenv("AUTO_INSTRUMENTATION_GO", "go:1")
+	t.Setenv("AUTO_INSTRUMENTATION_APACHE_HTTPD", "apache-httpd:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NGINX", "nginx:1")
+	defaultInst, err := getDefaultInstrumentation(config.New())
+	assert.Nil(t, err)
 	podMutator := instPodMutator{
 		Client: fake.NewClientBuilder().Build(),
 		Logger: logr.Logger{},
+		config: config.New(),
 	}
-	instrumentation, err := podMutator.selectInstrumentationInstanceFromNamespace(context.Background(), namespace)
+	instrumentation, err := podMutator.selectInstrumentationInstanceFromNamespace(context.Background(), namespace, corev1.Pod{})
 
 	assert.Nil(t, err)
-	assert.Equal(t, defaultInst, instrumentation)
+	assert.Equal(t, withSignals(defaultInst), instrumentation)
+
+}
+
+func TestGetInstrumentationInstanceFromNamespaceWithoutMatch(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	t.Setenv("AUTO_INSTRUMENTATION_JAVA", "java:1")
+	t.Setenv("AUTO_INSTRUMENTATION_PYTHON", "python:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NODEJS", "nodejs:1")
+	t.Setenv("AUTO_INSTRUMENTATION_DOTNET", "dotnet:1")
+	t.Setenv("AUTO_INSTRUMENTATION_GO", "go:1")
+	t.Setenv("AUTO_INSTRUMENTATION_APACHE_HTTPD", "apache-httpd:1")
+	t.Setenv("AUTO_INSTRUMENTATION_NGINX", "nginx:1")
+	namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	payments := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "apps"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			Exporter: v1alpha1.Exporter{Endpoint: "http://payments:4317"},
+		},
+	}
+	clusterInst := &v1alpha1.ClusterInstrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-defaults"},
+		Spec:       v1alpha1.InstrumentationSpec{Exporter: v1alpha1.Exporter{Endpoint: "http://cluster:4317"}},
+	}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps", Labels: map[string]string{"team": "orders"}}}
+
+	// none of the Instrumentations of the namespace selects the pod, the operator default applies
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(payments).Build(), record.NewFakeRecorder(10))
+	inst, err := podMutator.selectInstrumentationInstanceFromNamespace(context.Background(), namespace, pod)
+	require.NoError(t, err)
+	defaultInst, err := getDefaultInstrumentation(config.New())
+	require.NoError(t, err)
+	assert.Equal(t, withSignals(defaultInst), inst)
+
+	// and the ClusterInstrumentation over it when there is one
+	podMutator = NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(payments, clusterInst).Build(), record.NewFakeRecorder(10))
+	inst, err = podMutator.selectInstrumentationInstanceFromNamespace(context.Background(), namespace, pod)
+	require.NoError(t, err)
+	assert.Equal(t, "http://cluster:4317", inst.Spec.Endpoint)
+	assert.Equal(t, "java:1", inst.Spec.Java.Image)
+}
+
+func TestSelectInstrumentation(t *testing.T) {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Labels: map[string]string{"team": "payments"}}}
+	newInst := func(name string, priority int32, selector *metav1.LabelSelector) v1alpha1.Instrumentation {
+		return v1alpha1.Instrumentation{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.InstrumentationSpec{Priority: priority, Selector: selector},
+		}
+	}
+	payments := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}
+	search := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "search"}}
+
+	tests := []struct {
+		name            string
+		insts           []v1alpha1.Instrumentation
+		requireSelector bool
+		expected        string
+	}{
+		{
+			name:     "single instance without selector",
+			insts:    []v1alpha1.Instrumentation{newInst("platform", 0, nil)},
+			expected: "platform",
+		},
+		{
+			name:     "highest priority wins",
+			insts:    []v1alpha1.Instrumentation{newInst("platform", 0, nil), newInst("team", 10, payments)},
+			expected: "team",
+		},
+		{
+			name:     "higher priority without selector wins over selector",
+			insts:    []v1alpha1.Instrumentation{newInst("platform", 20, nil), newInst("team", 10, payments)},
+			expected: "platform",
+		},
+		{
+			name:     "non-matching selector is ignored",
+			insts:    []v1alpha1.Instrumentation{newInst("platform", 0, nil), newInst("team", 10, search)},
+			expected: "platform",
+		},
+		{
+			name:     "tie is broken by selector then name",
+			insts:    []v1alpha1.Instrumentation{newInst("b", 0, nil), newInst("c", 0, payments), newInst("a", 0, nil)},
+			expected: "c",
+		},
+		{
+			name:     "tie without selectors is broken by name",
+			insts:    []v1alpha1.Instrumentation{newInst("b", 0, nil), newInst("a", 0, nil)},
+			expected: "a",
+		},
+		{
+			name:  "nothing matches",
+			insts: []v1alpha1.Instrumentation{newInst("team", 0, search)},
+		},
+		{
+			name:            "selector required",
+			insts:           []v1alpha1.Instrumentation{newInst("platform", 20, nil), newInst("team", 10, payments)},
+			requireSelector: true,
+			expected:        "team",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inst := selectInstrumentation(test.insts, pod, test.requireSelector)
+			if test.expected == "" {
+				assert.Nil(t, inst)
+				return
+			}
+			assert.NotNil(t, inst)
+			assert.Equal(t, test.expected, inst.Name)
+		})
+	}
+}
+
+func TestMutateSelectedWithoutAnnotation(t *testing.T) {
+	assert.NoError(t, v1alpha1.AddToScheme(testScheme))
+	inst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "apps"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			Java:     v1alpha1.Java{Image: "java:1"},
+		},
+	}
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(inst).Build(), record.NewFakeRecorder(10))
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	newPod := func(labels map[string]string, annotations map[string]string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps", Labels: labels, Annotations: annotations},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Command: []string{"java", "-jar", "app.jar"}}},
+			},
+		}
+	}
+
+	selected, err := podMutator.Mutate(context.Background(), ns, newPod(map[string]string{"team": "payments"}, nil))
+	assert.NoError(t, err)
+	assert.Len(t, selected.Spec.InitContainers, 1)
+	assert.Equal(t, `app=java (command "java")`, selected.Annotations[annotationInjectAutoDecision])
+
+	notSelected, err := podMutator.Mutate(context.Background(), ns, newPod(map[string]string{"team": "search"}, nil))
+	assert.NoError(t, err)
+	assert.Empty(t, notSelected.Spec.InitContainers)
+
+	optedOut, err := podMutator.Mutate(context.Background(), ns, newPod(map[string]string{"team": "payments"}, map[string]string{annotationInjectAuto: "false"}))
+	assert.NoError(t, err)
+	assert.Empty(t, optedOut.Spec.InitContainers)
+}
+
+func TestMutateSecurityContext(t *testing.T) {
+	assert.NoError(t, v1alpha1.AddToScheme(testScheme))
+	privileged := true
+	runAsUser := int64(1000)
+	newInst := func(name string, securityContext *corev1.SecurityContext) *v1alpha1.Instrumentation {
+		return &v1alpha1.Instrumentation{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+			Spec: v1alpha1.InstrumentationSpec{
+				Selector:        &metav1.LabelSelector{MatchLabels: map[string]string{"team": name}},
+				SecurityContext: securityContext,
+				Java:            v1alpha1.Java{Image: "java:1"},
+			},
+		}
+	}
+	client := fake.NewClientBuilder().WithObjects(
+		newInst("payments", nil),
+		newInst("search", &corev1.SecurityContext{Privileged: &privileged}),
+	).Build()
+	podMutator := NewMutator(logr.Discard(), config.New(), client, record.NewFakeRecorder(10))
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	newPod := func(team string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps", Labels: map[string]string{"team": team}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:            "app",
+					Command:         []string{"java", "-jar", "app.jar"},
+					SecurityContext: &corev1.SecurityContext{RunAsUser: &runAsUser},
+				}},
+			},
+		}
+	}
+
+	restricted, err := podMutator.Mutate(context.Background(), ns, newPod("payments"))
+	assert.NoError(t, err)
+	assert.Len(t, restricted.Spec.InitContainers, 1)
+	expected := securitycontext.Restricted()
+	expected.RunAsUser = &runAsUser
+	assert.Equal(t, expected, restricted.Spec.InitContainers[0].SecurityContext)
+
+	overridden, err := podMutator.Mutate(context.Background(), ns, newPod("search"))
+	assert.NoError(t, err)
+	assert.Len(t, overridden.Spec.InitContainers, 1)
+	assert.Equal(t, &corev1.SecurityContext{Privileged: &privileged, RunAsUser: &runAsUser}, overridden.Spec.InitContainers[0].SecurityContext)
+}
+
+func TestResolveInstrumentationFailurePolicy(t *testing.T) {
+	if err := v1alpha1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().Build(), record.NewFakeRecorder(10))
+
+	// the referenced agent doesn't exist
+	inst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "inst", Namespace: "apps"},
+		Spec: v1alpha1.InstrumentationSpec{
+			FailurePolicy: v1alpha1.FailurePolicyFail,
+			Exporter:      v1alpha1.Exporter{Agent: &v1alpha1.AgentReference{Name: "missing"}},
+		},
+	}
+	_, err := podMutator.resolveInstrumentation(context.Background(), corev1.Pod{}, inst)
+	var policyErr *webhookhandler.FailurePolicyError
+	if assert.True(t, errors.As(err, &policyErr)) {
+		assert.Equal(t, v1alpha1.FailurePolicyFail, policyErr.Policy)
+	}
+
+	inst.Spec.FailurePolicy = ""
+	_, err = podMutator.resolveInstrumentation(context.Background(), corev1.Pod{}, inst)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &policyErr))
+}
+
+func TestGetInstrumentationInstanceMissingFailurePolicy(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	clusterInst := &v1alpha1.ClusterInstrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults"},
+		Spec:       v1alpha1.InstrumentationSpec{FailurePolicy: v1alpha1.FailurePolicyFail},
+	}
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "apps",
+			Annotations: map[string]string{annotationInjectJava: "missing"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	// the failure policy is inherited from the ClusterInstrumentation
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(clusterInst).Build(), record.NewFakeRecorder(10))
+	_, err := podMutator.Mutate(context.Background(), ns, pod)
+	require.Error(t, err)
+	var policyErr *webhookhandler.FailurePolicyError
+	if assert.True(t, errors.As(err, &policyErr)) {
+		assert.Equal(t, v1alpha1.FailurePolicyFail, policyErr.Policy)
+	}
+
+	// or set on the pod
+	podMutator = NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().Build(), record.NewFakeRecorder(10))
+	_, err = podMutator.Mutate(context.Background(), ns, pod)
+	require.Error(t, err)
+	assert.Equal(t, v1alpha1.FailurePolicyIgnore, webhookhandler.ResolveFailurePolicy(ns, pod, err))
+	pod.Annotations[webhookhandler.AnnotationFailurePolicy] = string(v1alpha1.FailurePolicyFail)
+	assert.Equal(t, v1alpha1.FailurePolicyFail, webhookhandler.ResolveFailurePolicy(ns, pod, err))
+}
+
+func TestMutateInjectionFailurePolicy(t *testing.T) {
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	inst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "inst", Namespace: "apps"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Java:   v1alpha1.Java{Image: "java:1"},
+			Python: v1alpha1.Python{Image: "python:1"},
+		},
+	}
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	// the Python SDK refuses an env var it extends when its value comes from a ValueFrom
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "apps",
+			Annotations: map[string]string{
+				annotationInjectJava:   "true",
+				annotationInjectPython: "true",
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Env: []corev1.EnvVar{{Name: envPythonPath, ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			}}},
+		}}},
+	}
+
+	// the languages that can be injected are, by default
+	podMutator := NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(inst).Build(), record.NewFakeRecorder(10))
+	got, err := podMutator.Mutate(context.Background(), ns, *pod.DeepCopy())
+	require.NoError(t, err)
+	assert.NotEqual(t, -1, getIndexOfEnv(got.Spec.Containers[0].Env, envJavaToolsOptions))
+	assert.False(t, hasVolume(got, pythonVolumeName))
+
+	// the pod is rejected under the Fail policy
+	inst.Spec.FailurePolicy = v1alpha1.FailurePolicyFail
+	podMutator = NewMutator(logr.Discard(), config.New(), fake.NewClientBuilder().WithObjects(inst).Build(), record.NewFakeRecorder(10))
+	_, err = podMutator.Mutate(context.Background(), ns, *pod.DeepCopy())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the Python instrumentation could not be injected into container app")
+	assert.Equal(t, v1alpha1.FailurePolicyFail, webhookhandler.ResolveFailurePolicy(ns, pod, err))
+}
+
+func TestInjectGoWithoutTargetExeFailurePolicy(t *testing.T) {
+	inst := v1alpha1.Instrumentation{
+		Spec: v1alpha1.InstrumentationSpec{
+			FailurePolicy: v1alpha1.FailurePolicyFail,
+			Go:            v1alpha1.Go{Image: "go:1"},
+		},
+	}
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	injector := sdkInjector{
+		logger: logr.Discard(),
+		owners: owners.NewResolver(fake.NewClientBuilder().Build(), logr.Discard()),
+		config: config.New(),
+	}
 
+	got, err := injector.inject(context.Background(), languageInstrumentations{Go: &inst}, corev1.Namespace{}, pod, "app")
+
+	assert.Equal(t, pod, got)
+	var policyErr *webhookhandler.FailurePolicyError
+	if assert.True(t, errors.As(err, &policyErr)) {
+		assert.Equal(t, v1alpha1.FailurePolicyFail, policyErr.Policy)
+	}
+	assert.Contains(t, err.Error(), envOtelTargetExe)
+}
+
+func TestInjectSeveralLanguagesIntoOneContainer(t *testing.T) {
+	inst := v1alpha1.Instrumentation{
+		Spec: v1alpha1.InstrumentationSpec{
+			Java:   v1alpha1.Java{Image: "java:1"},
+			Python: v1alpha1.Python{Image: "python:1"},
+			NodeJS: v1alpha1.NodeJS{Image: "nodejs:1"},
+			DotNet: v1alpha1.DotNet{Image: "dotnet:1"},
+		},
+	}
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	injector := sdkInjector{
+		logger: logr.Discard(),
+		owners: owners.NewResolver(fake.NewClientBuilder().Build(), logr.Discard()),
+		config: config.New(),
+	}
+
+	got, err := injector.inject(context.Background(), languageInstrumentations{Java: &inst, Python: &inst, NodeJS: &inst, DotNet: &inst}, corev1.Namespace{}, pod, "app")
+	require.NoError(t, err)
+
+	// the API server rejects a container mounting two volumes at the same path
+	mountPaths := map[string]string{}
+	for _, mount := range got.Spec.Containers[0].VolumeMounts {
+		assert.NotContains(t, mountPaths, mount.MountPath, "volume %s", mount.Name)
+		mountPaths[mount.MountPath] = mount.Name
+	}
+	assert.Equal(t, map[string]string{
+		javaDefaultMountPath: javaVolumeName,
+		pythonMountPath:      pythonVolumeName,
+		nodejsMountPath:      nodejsVolumeName,
+		dotnetMountPath:      dotnetVolumeName,
+	}, mountPaths)
+	assert.Len(t, got.Spec.InitContainers, 4)
 }
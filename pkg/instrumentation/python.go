@@ -0,0 +1,118 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+const (
+	envPythonPath                      = "PYTHONPATH"
+	envOtelTracesExporter              = "OTEL_TRACES_EXPORTER"
+	envOtelMetricsExporter             = "OTEL_METRICS_EXPORTER"
+	envOtelExporterOTLPTracesProtocol  = "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"
+	envOtelExporterOTLPMetricsProtocol = "OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"
+	// pythonPathPrefix holds the sitecustomize.py bootstrap that loads the ADOT Python distro on interpreter start.
+	pythonPathPrefix = pythonMountPath + "/opentelemetry/instrumentation/auto_instrumentation"
+	pythonPathSuffix = pythonMountPath
+	// pythonMountPath is not shared with the other languages, so a container instrumented for several of them
+	// doesn't mount two volumes at the same path.
+	pythonMountPath = "/otel-auto-instrumentation-python"
+)
+
+func injectPythonSDK(pythonSpec v1alpha1.Python, pod corev1.Pod, index int) (corev1.Pod, error) {
+	// caller checks if there is at least one container.
+	container := &pod.Spec.Containers[index]
+
+	err := validateContainerEnv(container.Env, envPythonPath)
+	if err != nil {
+		return pod, err
+	}
+
+	// inject Python instrumentation spec env vars.
+	for _, env := range pythonSpec.Env {
+		idx := getIndexOfEnv(container.Env, env.Name)
+		if idx == -1 {
+			container.Env = append(container.Env, env)
+		}
+	}
+
+	// The bootstrap directory has to come first so its sitecustomize.py wins over any other on the path,
+	// while the distro packages go last so the application's own dependencies take precedence.
+	idx := getIndexOfEnv(container.Env, envPythonPath)
+	if idx == -1 {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  envPythonPath,
+			Value: fmt.Sprintf("%s:%s", pythonPathPrefix, pythonPathSuffix),
+		})
+	} else {
+		container.Env[idx].Value = fmt.Sprintf("%s:%s:%s", pythonPathPrefix, container.Env[idx].Value, pythonPathSuffix)
+	}
+
+	// Set OTEL_TRACES_EXPORTER to OTLP if not set by user because it is what our autoinstrumentation supports.
+	idx = getIndexOfEnv(container.Env, envOtelTracesExporter)
+	if idx == -1 {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  envOtelTracesExporter,
+			Value: "otlp",
+		})
+	}
+
+	// Set OTEL_EXPORTER_OTLP_TRACES_PROTOCOL to http/protobuf if not set by user because it is what our autoinstrumentation supports.
+	idx = getIndexOfEnv(container.Env, envOtelExporterOTLPTracesProtocol)
+	if idx == -1 {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  envOtelExporterOTLPTracesProtocol,
+			Value: "http/protobuf",
+		})
+	}
+
+	// Set OTEL_METRICS_EXPORTER to OTLP if not set by user because it is what our autoinstrumentation supports.
+	idx = getIndexOfEnv(container.Env, envOtelMetricsExporter)
+	if idx == -1 {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  envOtelMetricsExporter,
+			Value: "otlp",
+		})
+	}
+
+	// Set OTEL_EXPORTER_OTLP_METRICS_PROTOCOL to http/protobuf if not set by user because it is what our autoinstrumentation supports.
+	idx = getIndexOfEnv(container.Env, envOtelExporterOTLPMetricsProtocol)
+	if idx == -1 {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  envOtelExporterOTLPMetricsProtocol,
+			Value: "http/protobuf",
+		})
+	}
+
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      pythonVolumeName,
+		MountPath: pythonMountPath,
+	})
+
+	// We just inject Volumes and init containers for the first processed container.
+	if isInitContainerMissingByName(pod, pythonInitContainerName) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: pythonVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			}})
+
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+			Name:      pythonInitContainerName,
+			Image:     pythonSpec.Image,
+			Command:   []string{"cp", "-a", "/autoinstrumentation/.", pythonMountPath + "/"},
+			Resources: pythonSpec.Resources,
+			VolumeMounts: []corev1.VolumeMount{{
+				Name:      pythonVolumeName,
+				MountPath: pythonMountPath,
+			}},
+		})
+	}
+	return pod, nil
+}
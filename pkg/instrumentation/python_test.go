@@ -0,0 +1,65 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+func TestInjectPythonSDK(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        []corev1.EnvVar
+		pythonPath string
+		err        string
+	}{
+		{
+			name:       "PYTHONPATH not defined",
+			pythonPath: "/otel-auto-instrumentation-python/opentelemetry/instrumentation/auto_instrumentation:/otel-auto-instrumentation-python",
+		},
+		{
+			name:       "PYTHONPATH defined",
+			env:        []corev1.EnvVar{{Name: envPythonPath, Value: "/app/lib"}},
+			pythonPath: "/otel-auto-instrumentation-python/opentelemetry/instrumentation/auto_instrumentation:/app/lib:/otel-auto-instrumentation-python",
+		},
+		{
+			name: "PYTHONPATH defined via ValueFrom",
+			env: []corev1.EnvVar{{Name: envPythonPath, ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "path"},
+			}}},
+			err: "the container defines env var value via ValueFrom, envVar: PYTHONPATH",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Env: test.env}},
+				},
+			}
+			pod, err := injectPythonSDK(v1alpha1.Python{Image: "python:1"}, pod, 0)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				assert.Empty(t, pod.Spec.InitContainers)
+				return
+			}
+			assert.NoError(t, err)
+
+			container := pod.Spec.Containers[0]
+			idx := getIndexOfEnv(container.Env, envPythonPath)
+			assert.NotEqual(t, -1, idx)
+			assert.Equal(t, test.pythonPath, container.Env[idx].Value)
+			assert.Equal(t, "/otel-auto-instrumentation-python", container.VolumeMounts[0].MountPath)
+			assert.Len(t, pod.Spec.InitContainers, 1)
+			assert.Equal(t, "python:1", pod.Spec.InitContainers[0].Image)
+			assert.Equal(t, []string{"cp", "-a", "/autoinstrumentation/.", "/otel-auto-instrumentation-python/"}, pod.Spec.InitContainers[0].Command)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+// annotationResourcePrefix prefixes the pod annotations overriding resource attributes, e.g.
+// resource.opentelemetry.io/service.namespace: checkout.
+const annotationResourcePrefix = "resource.opentelemetry.io/"
+
+// mappedResourceAttributes returns the resource attributes mapped from the labels and annotations of the pod and
+// its namespace.
+func mappedResourceAttributes(resource v1alpha1.Resource, ns metav1.ObjectMeta, pod metav1.ObjectMeta) map[string]string {
+	res := map[string]string{}
+	for _, mapping := range resource.FromLabels {
+		if mapping.From == v1alpha1.AttributeMappingScopeNamespace {
+			mapAttributes(mapping, ns.Labels, "k8s.namespace.labels.", res)
+		} else {
+			mapAttributes(mapping, pod.Labels, "k8s.pod.labels.", res)
+		}
+	}
+	for _, mapping := range resource.FromAnnotations {
+		if mapping.From == v1alpha1.AttributeMappingScopeNamespace {
+			mapAttributes(mapping, ns.Annotations, "k8s.namespace.annotations.", res)
+		} else {
+			mapAttributes(mapping, pod.Annotations, "k8s.pod.annotations.", res)
+		}
+	}
+	return res
+}
+
+func mapAttributes(mapping v1alpha1.AttributeMapping, values map[string]string, defaultPrefix string, res map[string]string) {
+	if mapping.Key != "" {
+		if value, ok := values[mapping.Key]; ok {
+			res[attributeName(mapping.Attribute, defaultPrefix, mapping.Key)] = value
+		}
+		return
+	}
+
+	// the expression is validated by the webhook of the Instrumentation
+	re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", mapping.KeyRegex))
+	if err != nil {
+		return
+	}
+	// keys are sorted so that the same attribute is set consistently when several keys map to it
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		match := re.FindStringSubmatchIndex(key)
+		if match == nil {
+			continue
+		}
+		name := attributeName("", defaultPrefix, key)
+		if mapping.Attribute != "" {
+			name = string(re.ExpandString(nil, mapping.Attribute, key, match))
+		}
+		if name != "" {
+			res[name] = values[key]
+		}
+	}
+}
+
+func attributeName(attribute string, defaultPrefix string, key string) string {
+	if attribute != "" {
+		return attribute
+	}
+	return defaultPrefix + key
+}
+
+// annotatedResourceAttributes returns the resource attributes defined by the resource.opentelemetry.io/ annotations
+// of the pod.
+func annotatedResourceAttributes(pod metav1.ObjectMeta) map[string]string {
+	res := map[string]string{}
+	for key, value := range pod.Annotations {
+		if name := strings.TrimPrefix(key, annotationResourcePrefix); name != key && name != "" {
+			res[name] = value
+		}
+	}
+	return res
+}
@@ -0,0 +1,125 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
+)
+
+func TestMappedResourceAttributes(t *testing.T) {
+	ns := metav1.ObjectMeta{
+		Name:        "payments",
+		Labels:      map[string]string{"cost-center": "cc-42"},
+		Annotations: map[string]string{"owner": "team-payments"},
+	}
+	pod := metav1.ObjectMeta{
+		Labels: map[string]string{
+			"app.kubernetes.io/name":    "checkout",
+			"app.kubernetes.io/version": "1.2.3",
+			"team":                      "payments",
+		},
+		Annotations: map[string]string{"example.com/tier": "gold"},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		resource v1alpha1.Resource
+		expected map[string]string
+	}{
+		{
+			name: "by key",
+			resource: v1alpha1.Resource{FromLabels: []v1alpha1.AttributeMapping{
+				{Key: "app.kubernetes.io/version", Attribute: "service.version"},
+				{Key: "team"},
+				{Key: "missing"},
+			}},
+			expected: map[string]string{"service.version": "1.2.3", "k8s.pod.labels.team": "payments"},
+		},
+		{
+			name: "by key regex",
+			resource: v1alpha1.Resource{FromLabels: []v1alpha1.AttributeMapping{
+				{KeyRegex: `app\.kubernetes\.io/(.*)`, Attribute: "app.$1"},
+				{KeyRegex: "tea"},
+			}},
+			expected: map[string]string{"app.name": "checkout", "app.version": "1.2.3"},
+		},
+		{
+			name: "namespace scope",
+			resource: v1alpha1.Resource{
+				FromLabels:      []v1alpha1.AttributeMapping{{From: v1alpha1.AttributeMappingScopeNamespace, Key: "cost-center", Attribute: "cost_center"}},
+				FromAnnotations: []v1alpha1.AttributeMapping{{From: v1alpha1.AttributeMappingScopeNamespace, KeyRegex: "owner"}},
+			},
+			expected: map[string]string{"cost_center": "cc-42", "k8s.namespace.annotations.owner": "team-payments"},
+		},
+		{
+			name:     "pod annotations",
+			resource: v1alpha1.Resource{FromAnnotations: []v1alpha1.AttributeMapping{{KeyRegex: `example\.com/.*`}}},
+			expected: map[string]string{"k8s.pod.annotations.example.com/tier": "gold"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mappedResourceAttributes(tt.resource, ns, pod))
+		})
+	}
+}
+
+func TestCreateResourceMapFromLabelsAndAnnotations(t *testing.T) {
+	inst := v1alpha1.Instrumentation{Spec: v1alpha1.InstrumentationSpec{Resource: v1alpha1.Resource{
+		Attributes: map[string]string{"deployment.environment": "prod"},
+		FromLabels: []v1alpha1.AttributeMapping{
+			{Key: "app.kubernetes.io/name", Attribute: "service.name"},
+			{Key: "environment", Attribute: "deployment.environment"},
+			{Key: "app.kubernetes.io/instance", Attribute: "k8s.pod.name"},
+		},
+	}}}
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments"}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "checkout-0",
+			Labels: map[string]string{
+				"app.kubernetes.io/name":     "checkout",
+				"app.kubernetes.io/instance": "checkout-blue",
+				"environment":                "staging",
+			},
+			Annotations: map[string]string{
+				"resource.opentelemetry.io/service.name":      "checkout-api",
+				"resource.opentelemetry.io/service.namespace": "shop",
+				"resource.opentelemetry.io/team":              "payments",
+				"resource.opentelemetry.io/k8s.cluster.name":  "prod-eu",
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Env:  []corev1.EnvVar{{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "team=checkout"}},
+		}}},
+	}
+	injector := sdkInjector{
+		logger: logr.Discard(),
+		owners: owners.NewResolver(fake.NewClientBuilder().Build(), logr.Discard()),
+		config: config.New(config.WithClusterAttributes(config.ClusterAttributes{CloudProvider: "aws", ClusterName: "prod"})),
+	}
+
+	res := injector.createResourceMap(context.Background(), inst, ns, pod, 0)
+	assert.Equal(t, map[string]string{
+		"deployment.environment": "prod",
+		"service.name":           "checkout-api",
+		"service.namespace":      "shop",
+		"k8s.namespace.name":     "payments",
+		"k8s.container.name":     "app",
+		"k8s.pod.name":           "checkout-0",
+		"k8s.cluster.name":       "prod-eu",
+		"cloud.provider":         "aws",
+	}, res)
+}
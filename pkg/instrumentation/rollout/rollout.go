@@ -0,0 +1,530 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package rollout restarts the workloads whose pods run an outdated auto-instrumentation.
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/instrumentation"
+)
+
+const (
+	// AnnotationRestartedAt is set on the pod template of the restarted workloads, which triggers their rollout.
+	AnnotationRestartedAt = "instrumentation.opentelemetry.io/restartedAt"
+
+	defaultInterval    = time.Minute
+	defaultMaxRestarts = 1
+)
+
+// PodMutator computes the pod that the webhook would admit.
+type PodMutator interface {
+	Mutate(ctx context.Context, ns corev1.Namespace, pod corev1.Pod) (corev1.Pod, error)
+}
+
+// InstrumentationRollout periodically looks for Deployments, StatefulSets and DaemonSets whose pods carry an injection
+// fingerprint that differs from the one their pod template would get, and restarts them. Pods that should be
+// instrumented but have no fingerprint, such as the ones created before the operator was installed, are backfilled
+// the same way.
+//
+// After a first check of the whole cluster, only the namespaces affected by a change of an Instrumentation or of the
+// namespace annotations are checked again, and a change of a ClusterInstrumentation checks the whole cluster again.
+type InstrumentationRollout struct {
+	Client   client.Client
+	Logger   logr.Logger
+	Recorder record.EventRecorder
+	// Mutator is used as a dry-run of the pod webhook, to compute the expected fingerprint of a pod template.
+	Mutator PodMutator
+	// Interval between two checks, one minute by default.
+	Interval time.Duration
+	// MaxRestarts is the maximum number of restarted workloads rolling out at the same time, one by default.
+	MaxRestarts int
+
+	mu sync.Mutex
+	// versions of the Instrumentations, ClusterInstrumentations and namespace annotations at the last check, nil
+	// before the first one
+	versions map[objectKey]string
+	// pending are the namespaces with workloads left to check, or all of them when allPending is set
+	pending    map[string]bool
+	allPending bool
+	// restarting are the restarted workloads whose rollout isn't complete yet
+	restarting map[objectKey]workload
+}
+
+// objectKey identifies an object of a kind, the namespace of a Namespace being its name.
+type objectKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// workload abstracts the fields of the supported workload kinds.
+type workload struct {
+	object   client.Object
+	kind     string
+	template *corev1.PodTemplateSpec
+	selector *metav1.LabelSelector
+	// ready reports whether the workload is fully rolled out and available, and otherwise why it isn't
+	ready func() (bool, string)
+	// rolledOut reports whether the latest pod template of the workload is rolled out and available
+	rolledOut func() bool
+}
+
+func (w workload) key() objectKey {
+	return objectKey{kind: w.kind, namespace: w.object.GetNamespace(), name: w.object.GetName()}
+}
+
+// +kubebuilder:rbac:groups="apps",resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="apps",resources=replicasets,verbs=list;watch
+// +kubebuilder:rbac:groups="",resources=pods;namespaces,verbs=list;watch
+// +kubebuilder:rbac:groups=cloudwatch.aws.amazon.com,resources=instrumentations;clusterinstrumentations,verbs=list;watch
+
+// Start checks the workloads at each interval, until the context is done.
+func (r *InstrumentationRollout) Start(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RolloutStaleWorkloads(ctx); err != nil {
+			r.Logger.Error(err, "failed to roll out the workloads with an outdated instrumentation")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecheckAll makes the next check cover the workloads of all the namespaces, for the changes that can affect any of
+// them, such as the default Instrumentation of the operator configuration.
+func (r *InstrumentationRollout) RecheckAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.allPending = true
+}
+
+// RolloutStaleWorkloads restarts the workloads running an outdated instrumentation in the namespaces left to check,
+// while fewer than MaxRestarts restarted workloads are rolling out.
+func (r *InstrumentationRollout) RolloutStaleWorkloads(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	maxRestarts := r.MaxRestarts
+	if maxRestarts <= 0 {
+		maxRestarts = defaultMaxRestarts
+	}
+
+	if err := r.collectChanges(ctx); err != nil {
+		return err
+	}
+	available := maxRestarts - r.trackRestarts(ctx)
+	if !r.allPending && len(r.pending) == 0 {
+		return nil
+	}
+
+	workloads, err := r.listWorkloads(ctx, r.pendingNamespaces())
+	if err != nil {
+		return err
+	}
+
+	// the namespaces checked again at the next interval, as they have workloads restarted, postponed or not checked
+	unsettled := map[string]bool{}
+	pods := newPodIndex(r.Client)
+	for _, w := range workloads {
+		ns := w.object.GetNamespace()
+		if _, ok := r.restarting[w.key()]; ok {
+			unsettled[ns] = true
+			continue
+		}
+		if available <= 0 {
+			r.Logger.V(1).Info("rollout limit reached, remaining workloads are checked once the restarted ones are rolled out", "limit", maxRestarts)
+			unsettled[ns] = true
+			continue
+		}
+		logger := r.Logger.WithValues("kind", w.kind, "namespace", ns, "name", w.object.GetName())
+
+		stale, err := r.isStale(ctx, pods, w)
+		if err != nil {
+			logger.Error(err, "failed to check the instrumentation of the workload")
+			unsettled[ns] = true
+			continue
+		}
+		if !stale {
+			continue
+		}
+		// restarting a workload that isn't fully available would go beyond its maxUnavailable
+		if ready, reason := w.ready(); !ready {
+			logger.V(1).Info("postponing the restart of the workload running an outdated instrumentation", "reason", reason)
+			unsettled[ns] = true
+			continue
+		}
+		if err := r.restart(ctx, w); err != nil {
+			logger.Error(err, "failed to restart the workload")
+			unsettled[ns] = true
+			continue
+		}
+		logger.Info("restarted the workload to update its instrumentation")
+		r.Recorder.Event(w.object, corev1.EventTypeNormal, "InstrumentationRollout", "restarted to apply the current instrumentation")
+		r.restarting[w.key()] = w
+		unsettled[ns] = true
+		available--
+	}
+	r.pending = unsettled
+	r.allPending = false
+	return nil
+}
+
+// collectChanges marks the namespaces whose Instrumentation or annotations changed since the last check as pending,
+// and all of them on the first check or when a ClusterInstrumentation changed.
+func (r *InstrumentationRollout) collectChanges(ctx context.Context) error {
+	versions := map[objectKey]string{}
+
+	instrumentations := v1alpha1.InstrumentationList{}
+	if err := r.Client.List(ctx, &instrumentations); err != nil {
+		return fmt.Errorf("failed to list instrumentations: %w", err)
+	}
+	for _, inst := range instrumentations.Items {
+		versions[objectKey{kind: "Instrumentation", namespace: inst.Namespace, name: inst.Name}] = fmt.Sprint(inst.Generation)
+	}
+	clusterInstrumentations := v1alpha1.ClusterInstrumentationList{}
+	if err := r.Client.List(ctx, &clusterInstrumentations); err != nil {
+		return fmt.Errorf("failed to list clusterinstrumentations: %w", err)
+	}
+	for _, inst := range clusterInstrumentations.Items {
+		versions[objectKey{kind: "ClusterInstrumentation", name: inst.Name}] = fmt.Sprint(inst.Generation)
+	}
+	// the namespace annotations can request or configure the injection of all their pods
+	namespaces := corev1.NamespaceList{}
+	if err := r.Client.List(ctx, &namespaces); err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	for _, ns := range namespaces.Items {
+		versions[objectKey{kind: "Namespace", namespace: ns.Name, name: ns.Name}] = fmt.Sprint(ns.Annotations)
+	}
+
+	if r.pending == nil {
+		r.pending = map[string]bool{}
+	}
+	if r.versions == nil {
+		r.allPending = true
+	}
+	changed := func(key objectKey) {
+		if key.kind == "ClusterInstrumentation" {
+			r.allPending = true
+		} else {
+			r.pending[key.namespace] = true
+		}
+	}
+	for key, version := range versions {
+		if previous, ok := r.versions[key]; !ok || previous != version {
+			changed(key)
+		}
+	}
+	for key := range r.versions {
+		if _, ok := versions[key]; !ok {
+			changed(key)
+		}
+	}
+	r.versions = versions
+	return nil
+}
+
+// trackRestarts forgets the restarted workloads that are rolled out or deleted, and returns the number of the ones
+// still rolling out.
+func (r *InstrumentationRollout) trackRestarts(ctx context.Context) int {
+	if r.restarting == nil {
+		r.restarting = map[objectKey]workload{}
+	}
+	for key, w := range r.restarting {
+		obj := w.object.DeepCopyObject().(client.Object)
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				delete(r.restarting, key)
+			} else {
+				r.Logger.Error(err, "failed to get the rollout status of the restarted workload", "kind", key.kind, "namespace", key.namespace, "name", key.name)
+			}
+			continue
+		}
+		if refreshed, ok := newWorkload(obj); !ok || refreshed.rolledOut() {
+			delete(r.restarting, key)
+		}
+	}
+	return len(r.restarting)
+}
+
+// pendingNamespaces returns the sorted namespaces left to check, or nil for all of them.
+func (r *InstrumentationRollout) pendingNamespaces() []string {
+	if r.allPending {
+		return nil
+	}
+	namespaces := make([]string, 0, len(r.pending))
+	for ns := range r.pending {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// podIndex lists the pods and ReplicaSets of a namespace once per check, and matches the selector of each workload
+// against them.
+type podIndex struct {
+	client      client.Client
+	pods        map[string][]corev1.Pod
+	replicaSets map[string][]appsv1.ReplicaSet
+}
+
+func newPodIndex(c client.Client) *podIndex {
+	return &podIndex{client: c, pods: map[string][]corev1.Pod{}, replicaSets: map[string][]appsv1.ReplicaSet{}}
+}
+
+func (p *podIndex) podsOf(ctx context.Context, namespace string, selector labels.Selector) ([]corev1.Pod, error) {
+	if _, ok := p.pods[namespace]; !ok {
+		pods := corev1.PodList{}
+		if err := p.client.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		p.pods[namespace] = pods.Items
+	}
+	var matching []corev1.Pod
+	for _, pod := range p.pods[namespace] {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			matching = append(matching, pod)
+		}
+	}
+	return matching, nil
+}
+
+func (p *podIndex) replicaSetsOf(ctx context.Context, namespace string, selector labels.Selector) ([]appsv1.ReplicaSet, error) {
+	if _, ok := p.replicaSets[namespace]; !ok {
+		replicaSets := appsv1.ReplicaSetList{}
+		if err := p.client.List(ctx, &replicaSets, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		p.replicaSets[namespace] = replicaSets.Items
+	}
+	var matching []appsv1.ReplicaSet
+	for _, rs := range p.replicaSets[namespace] {
+		if selector.Matches(labels.Set(rs.Labels)) {
+			matching = append(matching, rs)
+		}
+	}
+	return matching, nil
+}
+
+// isStale tells whether a pod of the workload has a fingerprint that differs from the expected one.
+func (r *InstrumentationRollout) isStale(ctx context.Context, pods *podIndex, w workload) (bool, error) {
+	ns := corev1.Namespace{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: w.object.GetNamespace()}, &ns); err != nil {
+		return false, err
+	}
+	template := corev1.Pod{
+		ObjectMeta: *w.template.ObjectMeta.DeepCopy(),
+		Spec:       *w.template.Spec.DeepCopy(),
+	}
+	template.Namespace = w.object.GetNamespace()
+	mutated, err := r.Mutator.Mutate(ctx, ns, template)
+	if err != nil {
+		return false, err
+	}
+	expected := mutated.Annotations[instrumentation.AnnotationInjectionFingerprint]
+	if expected == "" {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(w.selector)
+	if err != nil {
+		return false, err
+	}
+	owners, err := podOwners(ctx, pods, w, selector)
+	if err != nil {
+		return false, err
+	}
+	matching, err := pods.podsOf(ctx, w.object.GetNamespace(), selector)
+	if err != nil {
+		return false, err
+	}
+	for _, pod := range matching {
+		if pod.DeletionTimestamp != nil || !isOwnedBy(pod, owners) {
+			continue
+		}
+		if pod.Annotations[instrumentation.AnnotationInjectionFingerprint] != expected {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// podOwners returns the UIDs of the controllers of the pods of the workload: the workload itself, or the ReplicaSets
+// of a Deployment.
+func podOwners(ctx context.Context, pods *podIndex, w workload, selector labels.Selector) (map[types.UID]bool, error) {
+	if w.kind != "Deployment" {
+		return map[types.UID]bool{w.object.GetUID(): true}, nil
+	}
+	replicaSets, err := pods.replicaSetsOf(ctx, w.object.GetNamespace(), selector)
+	if err != nil {
+		return nil, err
+	}
+	owners := map[types.UID]bool{}
+	for i := range replicaSets {
+		if owner := metav1.GetControllerOf(&replicaSets[i]); owner != nil && owner.UID == w.object.GetUID() {
+			owners[replicaSets[i].UID] = true
+		}
+	}
+	return owners, nil
+}
+
+// isOwnedBy filters out the pods of other workloads matching the same selector.
+func isOwnedBy(pod corev1.Pod, owners map[types.UID]bool) bool {
+	owner := metav1.GetControllerOf(&pod)
+	return owner != nil && owners[owner.UID]
+}
+
+func (r *InstrumentationRollout) restart(ctx context.Context, w workload) error {
+	patch := client.MergeFrom(w.object.DeepCopyObject().(client.Object))
+	if w.template.Annotations == nil {
+		w.template.Annotations = map[string]string{}
+	}
+	w.template.Annotations[AnnotationRestartedAt] = time.Now().UTC().Format(time.RFC3339)
+	return r.Client.Patch(ctx, w.object, patch)
+}
+
+// listWorkloads lists the workloads of the given namespaces, or of all of them when nil.
+func (r *InstrumentationRollout) listWorkloads(ctx context.Context, namespaces []string) ([]workload, error) {
+	if namespaces == nil {
+		return r.listNamespaceWorkloads(ctx)
+	}
+	var workloads []workload
+	for _, ns := range namespaces {
+		nsWorkloads, err := r.listNamespaceWorkloads(ctx, client.InNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+		workloads = append(workloads, nsWorkloads...)
+	}
+	return workloads, nil
+}
+
+func (r *InstrumentationRollout) listNamespaceWorkloads(ctx context.Context, opts ...client.ListOption) ([]workload, error) {
+	var workloads []workload
+
+	deployments := appsv1.DeploymentList{}
+	if err := r.Client.List(ctx, &deployments, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		w, _ := newWorkload(&deployments.Items[i])
+		workloads = append(workloads, w)
+	}
+
+	statefulSets := appsv1.StatefulSetList{}
+	if err := r.Client.List(ctx, &statefulSets, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		w, _ := newWorkload(&statefulSets.Items[i])
+		workloads = append(workloads, w)
+	}
+
+	daemonSets := appsv1.DaemonSetList{}
+	if err := r.Client.List(ctx, &daemonSets, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for i := range daemonSets.Items {
+		w, _ := newWorkload(&daemonSets.Items[i])
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+// newWorkload wraps a Deployment, a StatefulSet or a DaemonSet, and returns false for the other kinds.
+func newWorkload(obj client.Object) (workload, bool) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return workload{object: o, kind: "Deployment", template: &o.Spec.Template, selector: o.Spec.Selector,
+			ready:     func() (bool, string) { return deploymentReady(o) },
+			rolledOut: func() bool { return deploymentRolledOut(o) },
+		}, true
+	case *appsv1.StatefulSet:
+		return workload{object: o, kind: "StatefulSet", template: &o.Spec.Template, selector: o.Spec.Selector,
+			ready:     func() (bool, string) { return statefulSetReady(o) },
+			rolledOut: func() bool { return statefulSetRolledOut(o) },
+		}, true
+	case *appsv1.DaemonSet:
+		return workload{object: o, kind: "DaemonSet", template: &o.Spec.Template, selector: o.Spec.Selector,
+			ready:     func() (bool, string) { return daemonSetReady(o) },
+			rolledOut: func() bool { return daemonSetRolledOut(o) },
+		}, true
+	}
+	return workload{}, false
+}
+
+func deploymentReady(d *appsv1.Deployment) (bool, string) {
+	if d.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+		return false, "the Recreate strategy would make the workload unavailable"
+	}
+	if !deploymentRolledOut(d) {
+		return false, "a rollout is in progress"
+	}
+	return true, ""
+}
+
+func deploymentRolledOut(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation && d.Status.UpdatedReplicas >= replicas && d.Status.UnavailableReplicas == 0
+}
+
+func statefulSetReady(s *appsv1.StatefulSet) (bool, string) {
+	if s.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return false, "the OnDelete strategy doesn't restart pods"
+	}
+	if !statefulSetRolledOut(s) {
+		return false, "a rollout is in progress"
+	}
+	return true, ""
+}
+
+func statefulSetRolledOut(s *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	return s.Status.ObservedGeneration >= s.Generation && s.Status.CurrentRevision == s.Status.UpdateRevision && s.Status.ReadyReplicas >= replicas
+}
+
+func daemonSetReady(ds *appsv1.DaemonSet) (bool, string) {
+	if ds.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		return false, "the OnDelete strategy doesn't restart pods"
+	}
+	if !daemonSetRolledOut(ds) {
+		return false, "a rollout is in progress"
+	}
+	return true, ""
+}
+
+func daemonSetRolledOut(ds *appsv1.DaemonSet) bool {
+	return ds.Status.ObservedGeneration >= ds.Generation && ds.Status.UpdatedNumberScheduled >= ds.Status.DesiredNumberScheduled && ds.Status.NumberUnavailable == 0
+}
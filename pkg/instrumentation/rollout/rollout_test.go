@@ -0,0 +1,315 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package rollout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/instrumentation"
+)
+
+const currentFingerprint = "current"
+
+// fakeMutator instruments the pods annotated with inject-java, with the current fingerprint.
+type fakeMutator struct{}
+
+func (fakeMutator) Mutate(_ context.Context, _ corev1.Namespace, pod corev1.Pod) (corev1.Pod, error) {
+	if pod.Annotations["instrumentation.opentelemetry.io/inject-java"] == "true" {
+		pod.Annotations[instrumentation.AnnotationInjectionFingerprint] = currentFingerprint
+	}
+	return pod, nil
+}
+
+func newClient(t *testing.T, objects ...client.Object) client.Client {
+	require.NoError(t, v1alpha1.AddToScheme(scheme.Scheme))
+	return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+}
+
+func newDeployment(name string, annotations map[string]string, available bool) *appsv1.Deployment {
+	replicas := int32(1)
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps", UID: types.UID(name)},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}, Annotations: annotations},
+			},
+		},
+		Status: appsv1.DeploymentStatus{UpdatedReplicas: 1},
+	}
+	if !available {
+		d.Status.UnavailableReplicas = 1
+	}
+	return d
+}
+
+func newReplicaSet(deployment string) *appsv1.ReplicaSet {
+	controller := true
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment + "-rs",
+			Namespace:       "apps",
+			UID:             types.UID(deployment + "-rs"),
+			Labels:          map[string]string{"app": deployment},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: deployment, UID: types.UID(deployment), Controller: &controller}},
+		},
+	}
+}
+
+func newPod(deployment string, fingerprint string) *corev1.Pod {
+	controller := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment + "-pod",
+			Namespace:       "apps",
+			Labels:          map[string]string{"app": deployment},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: deployment + "-rs", UID: types.UID(deployment + "-rs"), Controller: &controller}},
+		},
+	}
+	if fingerprint != "" {
+		pod.Annotations = map[string]string{instrumentation.AnnotationInjectionFingerprint: fingerprint}
+	}
+	return pod
+}
+
+func TestRolloutStaleWorkloads(t *testing.T) {
+	injected := map[string]string{"instrumentation.opentelemetry.io/inject-java": "true"}
+
+	tests := []struct {
+		name        string
+		deployment  *appsv1.Deployment
+		pod         *corev1.Pod
+		wantRestart bool
+	}{
+		{
+			name:        "outdated fingerprint",
+			deployment:  newDeployment("outdated", injected, true),
+			pod:         newPod("outdated", "previous"),
+			wantRestart: true,
+		},
+		{
+			name:        "backfill of a pod created before the operator",
+			deployment:  newDeployment("backfill", injected, true),
+			pod:         newPod("backfill", ""),
+			wantRestart: true,
+		},
+		{
+			name:       "current fingerprint",
+			deployment: newDeployment("current", injected, true),
+			pod:        newPod("current", currentFingerprint),
+		},
+		{
+			name:       "not instrumented",
+			deployment: newDeployment("plain", map[string]string{}, true),
+			pod:        newPod("plain", ""),
+		},
+		{
+			name:       "unavailable replicas",
+			deployment: newDeployment("unavailable", injected, false),
+			pod:        newPod("unavailable", "previous"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+			c := newClient(t, ns, test.deployment, newReplicaSet(test.deployment.Name), test.pod)
+			r := &InstrumentationRollout{Client: c, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10), Mutator: fakeMutator{}}
+
+			require.NoError(t, r.RolloutStaleWorkloads(context.Background()))
+
+			updated := appsv1.Deployment{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(test.deployment), &updated))
+			_, restarted := updated.Spec.Template.Annotations[AnnotationRestartedAt]
+			assert.Equal(t, test.wantRestart, restarted)
+		})
+	}
+}
+
+func TestRolloutStaleWorkloadsRateLimit(t *testing.T) {
+	injected := map[string]string{"instrumentation.opentelemetry.io/inject-java": "true"}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	c := newClient(t, ns,
+		newDeployment("first", injected, true), newReplicaSet("first"), newPod("first", "previous"),
+		newDeployment("second", injected, true), newReplicaSet("second"), newPod("second", "previous"),
+	)
+	r := &InstrumentationRollout{Client: c, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10), Mutator: fakeMutator{}, MaxRestarts: 1}
+
+	require.NoError(t, r.RolloutStaleWorkloads(context.Background()))
+
+	deployments := appsv1.DeploymentList{}
+	require.NoError(t, c.List(context.Background(), &deployments))
+	restarted := 0
+	for _, d := range deployments.Items {
+		if _, ok := d.Spec.Template.Annotations[AnnotationRestartedAt]; ok {
+			restarted++
+		}
+	}
+	assert.Equal(t, 1, restarted)
+}
+
+func TestRolloutStaleWorkloadsPacedByRolloutStatus(t *testing.T) {
+	injected := map[string]string{"instrumentation.opentelemetry.io/inject-java": "true"}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	c := newClient(t, ns,
+		newDeployment("first", injected, true), newReplicaSet("first"), newPod("first", "previous"),
+		newDeployment("second", injected, true), newReplicaSet("second"), newPod("second", "previous"),
+		newDeployment("third", injected, true), newReplicaSet("third"), newPod("third", "previous"),
+	)
+	r := &InstrumentationRollout{Client: c, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10), Mutator: fakeMutator{}, MaxRestarts: 1}
+	ctx := context.Background()
+
+	require.NoError(t, r.RolloutStaleWorkloads(ctx))
+	assert.Equal(t, []string{"first"}, restartedDeployments(t, c))
+
+	// the restarted Deployment is still rolling out
+	setRolloutStatus(t, c, "first", false)
+	require.NoError(t, r.RolloutStaleWorkloads(ctx))
+	assert.Equal(t, []string{"first"}, restartedDeployments(t, c))
+
+	// the restarted Deployment is rolled out, with pods carrying the current fingerprint
+	setRolloutStatus(t, c, "first", true)
+	pod := corev1.Pod{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "apps", Name: "first-pod"}, &pod))
+	pod.Annotations[instrumentation.AnnotationInjectionFingerprint] = currentFingerprint
+	require.NoError(t, c.Update(ctx, &pod))
+	require.NoError(t, r.RolloutStaleWorkloads(ctx))
+	assert.Equal(t, []string{"first", "second"}, restartedDeployments(t, c))
+}
+
+func TestRolloutStaleWorkloadsAffectedNamespaces(t *testing.T) {
+	injected := map[string]string{"instrumentation.opentelemetry.io/inject-java": "true"}
+	apps := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	team := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}}
+	inst := &v1alpha1.Instrumentation{ObjectMeta: metav1.ObjectMeta{Name: "inst", Namespace: "team", Generation: 1}}
+	c := newClient(t, apps, team, inst,
+		newDeployment("first", injected, true), newReplicaSet("first"), newPod("first", currentFingerprint),
+		inNamespace("team", newDeployment("second", injected, true)), inNamespace("team", newReplicaSet("second")), inNamespace("team", newPod("second", currentFingerprint)),
+	)
+	r := &InstrumentationRollout{Client: c, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10), Mutator: fakeMutator{}, MaxRestarts: 2}
+	ctx := context.Background()
+
+	// the first check covers the whole cluster, where everything is up to date
+	require.NoError(t, r.RolloutStaleWorkloads(ctx))
+	assert.Empty(t, restartedDeployments(t, c))
+
+	// both pods are outdated, but only the Instrumentation of the "team" namespace changed
+	for _, key := range []types.NamespacedName{{Namespace: "apps", Name: "first-pod"}, {Namespace: "team", Name: "second-pod"}} {
+		pod := corev1.Pod{}
+		require.NoError(t, c.Get(ctx, key, &pod))
+		pod.Annotations[instrumentation.AnnotationInjectionFingerprint] = "previous"
+		require.NoError(t, c.Update(ctx, &pod))
+	}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(inst), inst))
+	inst.Generation = 2
+	require.NoError(t, c.Update(ctx, inst))
+
+	require.NoError(t, r.RolloutStaleWorkloads(ctx))
+	assert.Equal(t, []string{"second"}, restartedDeployments(t, c))
+
+	// a change of the operator configuration checks all the namespaces again
+	r.RecheckAll()
+	require.NoError(t, r.RolloutStaleWorkloads(ctx))
+	assert.Equal(t, []string{"first", "second"}, restartedDeployments(t, c))
+}
+
+func inNamespace[T client.Object](namespace string, obj T) T {
+	obj.SetNamespace(namespace)
+	return obj
+}
+
+// restartedDeployments returns the names of the Deployments restarted by the rollout.
+func restartedDeployments(t *testing.T, c client.Client) []string {
+	deployments := appsv1.DeploymentList{}
+	require.NoError(t, c.List(context.Background(), &deployments))
+	var restarted []string
+	for _, d := range deployments.Items {
+		if _, ok := d.Spec.Template.Annotations[AnnotationRestartedAt]; ok {
+			restarted = append(restarted, d.Name)
+		}
+	}
+	return restarted
+}
+
+func setRolloutStatus(t *testing.T, c client.Client, name string, rolledOut bool) {
+	d := appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "apps", Name: name}, &d))
+	if rolledOut {
+		d.Status.ObservedGeneration = d.Generation
+	} else {
+		d.Generation = d.Status.ObservedGeneration + 1
+	}
+	require.NoError(t, c.Update(context.Background(), &d))
+}
+
+func TestRolloutStaleWorkloadsOtherOwners(t *testing.T) {
+	injected := map[string]string{"instrumentation.opentelemetry.io/inject-java": "true"}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	deployment := newDeployment("current", injected, true)
+
+	// a ReplicaSet of another Deployment and a bare ReplicaSet, whose pods match the selector of the Deployment
+	otherReplicaSet := newReplicaSet("other")
+	otherReplicaSet.Labels = deployment.Spec.Selector.MatchLabels
+	bareReplicaSet := newReplicaSet("bare")
+	bareReplicaSet.Labels = deployment.Spec.Selector.MatchLabels
+	bareReplicaSet.OwnerReferences = nil
+	otherPod := newPod("other", "previous")
+	otherPod.Labels = deployment.Spec.Selector.MatchLabels
+	barePod := newPod("bare", "previous")
+	barePod.Labels = deployment.Spec.Selector.MatchLabels
+
+	c := newClient(t, ns,
+		deployment, newReplicaSet("current"), newPod("current", currentFingerprint),
+		otherReplicaSet, otherPod, bareReplicaSet, barePod,
+	)
+	r := &InstrumentationRollout{Client: c, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10), Mutator: fakeMutator{}}
+
+	require.NoError(t, r.RolloutStaleWorkloads(context.Background()))
+
+	updated := appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), &updated))
+	assert.NotContains(t, updated.Spec.Template.Annotations, AnnotationRestartedAt)
+}
+
+func TestWorkloadReady(t *testing.T) {
+	replicas := int32(2)
+
+	ready, _ := statefulSetReady(&appsv1.StatefulSet{
+		Spec:   appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, CurrentRevision: "a", UpdateRevision: "a"},
+	})
+	assert.True(t, ready)
+
+	ready, _ = statefulSetReady(&appsv1.StatefulSet{
+		Spec:   appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, CurrentRevision: "a", UpdateRevision: "b"},
+	})
+	assert.False(t, ready)
+
+	ready, _ = daemonSetReady(&appsv1.DaemonSet{
+		Spec: appsv1.DaemonSetSpec{UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}},
+	})
+	assert.False(t, ready)
+
+	ready, _ = deploymentReady(&appsv1.Deployment{
+		Spec:   appsv1.DeploymentSpec{Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}},
+		Status: appsv1.DeploymentStatus{UpdatedReplicas: 1},
+	})
+	assert.False(t, ready)
+}
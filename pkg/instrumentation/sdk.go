@@ -6,9 +6,7 @@ package instrumentation
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
-	"time"
 	"unsafe"
 
 	"github.com/go-logr/logr"
@@ -17,32 +15,45 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/resourceattributes"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/securitycontext"
 )
 
 const (
 	volumeName        = "opentelemetry-auto-instrumentation"
 	initContainerName = "opentelemetry-auto-instrumentation"
 	sideCarName       = "opentelemetry-auto-instrumentation"
+
+	// Each language has its own init container and volume, so that containers of different languages in the same
+	// pod all get the files of their agent.
+	javaVolumeName          = volumeName + "-java"
+	javaInitContainerName   = initContainerName + "-java"
+	pythonVolumeName        = volumeName + "-python"
+	pythonInitContainerName = initContainerName + "-python"
+	nodejsVolumeName        = volumeName + "-nodejs"
+	nodejsInitContainerName = initContainerName + "-nodejs"
+	dotnetVolumeName        = volumeName + "-dotnet"
+	dotnetInitContainerName = initContainerName + "-dotnet"
 )
 
 // inject a new sidecar container to the given pod, based on the given OpenTelemetryCollector.
 
 type sdkInjector struct {
-	client client.Client
 	logger logr.Logger
+	owners *owners.Resolver
+	config config.Config
 }
 
-func (i *sdkInjector) inject(ctx context.Context, insts languageInstrumentations, ns corev1.Namespace, pod corev1.Pod, containerName string) corev1.Pod {
+// inject injects the given instrumentations into the container. A language that can't be injected is skipped, and
+// the first of these failures is returned with the failure policy of its Instrumentation, along with the pod.
+func (i *sdkInjector) inject(ctx context.Context, insts languageInstrumentations, ns corev1.Namespace, pod corev1.Pod, containerName string) (corev1.Pod, error) {
 	if len(pod.Spec.Containers) < 1 {
-		return pod
+		return pod, nil
 	}
 
 	// We search for specific container to inject variables and if no one is found
@@ -54,23 +65,142 @@ func (i *sdkInjector) inject(ctx context.Context, insts languageInstrumentations
 		}
 	}
 
+	var injectErr error
+	skip := func(otelinst v1alpha1.Instrumentation, language, msg string, err error) {
+		i.logger.Info(msg, "reason", err.Error(), "container", pod.Spec.Containers[index].Name)
+		if injectErr == nil {
+			injectErr = withFailurePolicy(otelinst.Spec.FailurePolicy, fmt.Errorf("the %s instrumentation could not be injected into container %s: %w", language, pod.Spec.Containers[index].Name, err))
+		}
+	}
+
 	if insts.Java != nil {
 		otelinst := *insts.Java
-		var err error
+		injected := len(pod.Spec.InitContainers)
 		i.logger.V(1).Info("injecting Java instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
-		pod, err = injectJavaagent(otelinst.Spec.Java, pod, index)
+		delivery := resolveAgentDelivery(otelinst.Spec.Java.Delivery, otelinst.Spec.Java.CSIDriver, i.config.ImageVolumesAvailable())
+		pod = injectWithEnvPolicy(otelinst.Spec.EnvPolicy, pod, index, func(pod corev1.Pod) corev1.Pod {
+			pod, err := injectJavaagent(otelinst.Spec.Java, pod, index, delivery)
+			if err != nil {
+				skip(otelinst, "Java", "Skipping javaagent injection", err)
+				return pod
+			}
+			pod = i.injectCommonEnvVar(otelinst, pod, index)
+			pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
+			return secureInitContainers(otelinst.Spec.SecurityContext, pod, index, injected)
+		})
+	}
+	if insts.Python != nil {
+		otelinst := *insts.Python
+		injected := len(pod.Spec.InitContainers)
+		i.logger.V(1).Info("injecting Python instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
+		pod = injectWithEnvPolicy(otelinst.Spec.EnvPolicy, pod, index, func(pod corev1.Pod) corev1.Pod {
+			pod, err := injectPythonSDK(otelinst.Spec.Python, pod, index)
+			if err != nil {
+				skip(otelinst, "Python", "Skipping Python SDK injection", err)
+				return pod
+			}
+			pod = i.injectCommonEnvVar(otelinst, pod, index)
+			pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
+			return secureInitContainers(otelinst.Spec.SecurityContext, pod, index, injected)
+		})
+	}
+	if insts.NodeJS != nil {
+		otelinst := *insts.NodeJS
+		injected := len(pod.Spec.InitContainers)
+		i.logger.V(1).Info("injecting NodeJS instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
+		pod = injectWithEnvPolicy(otelinst.Spec.EnvPolicy, pod, index, func(pod corev1.Pod) corev1.Pod {
+			pod, err := injectNodeJSSDK(otelinst.Spec.NodeJS, pod, index)
+			if err != nil {
+				skip(otelinst, "NodeJS", "Skipping NodeJS SDK injection", err)
+				return pod
+			}
+			pod = i.injectCommonEnvVar(otelinst, pod, index)
+			pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
+			return secureInitContainers(otelinst.Spec.SecurityContext, pod, index, injected)
+		})
+	}
+	if insts.DotNet != nil {
+		otelinst := *insts.DotNet
+		injected := len(pod.Spec.InitContainers)
+		i.logger.V(1).Info("injecting DotNet instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
+		runtime := annotationValue(ns.ObjectMeta, pod.ObjectMeta, annotationDotNetRuntime)
+		pod = injectWithEnvPolicy(otelinst.Spec.EnvPolicy, pod, index, func(pod corev1.Pod) corev1.Pod {
+			pod, err := injectDotNetSDK(otelinst.Spec.DotNet, pod, index, runtime)
+			if err != nil {
+				skip(otelinst, ".NET", "Skipping DotNet SDK injection", err)
+				return pod
+			}
+			pod = i.injectCommonEnvVar(otelinst, pod, index)
+			pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
+			return secureInitContainers(otelinst.Spec.SecurityContext, pod, index, injected)
+		})
+	}
+	if insts.Go != nil {
+		origPod := pod
+		otelinst := *insts.Go
+		var err error
+		i.logger.V(1).Info("injecting Go instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
+		pod, err = injectGoSDK(otelinst.Spec.Go, pod)
 		if err != nil {
-			i.logger.Info("Skipping javaagent injection", "reason", err.Error(), "container", pod.Spec.Containers[index].Name)
+			skip(otelinst, "Go", "Skipping Go SDK injection", err)
 		} else {
+			// Common env vars and config need to be applied to the agent container. The env policy doesn't apply,
+			// as the agent container defines no env var of its own.
+			pod = i.injectCommonEnvVar(otelinst, pod, len(pod.Spec.Containers)-1)
+			pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, len(pod.Spec.Containers)-1, index)
+
+			// Ensure that after all the env var coalescing we have a value for OTEL_GO_AUTO_TARGET_EXE
+			idx := getIndexOfEnv(pod.Spec.Containers[len(pod.Spec.Containers)-1].Env, envOtelTargetExe)
+			if idx == -1 {
+				skip(otelinst, "Go", "Skipping Go SDK injection", fmt.Errorf("%s not set", envOtelTargetExe))
+				pod = origPod
+			}
+		}
+	}
+	if insts.ApacheHttpd != nil {
+		otelinst := *insts.ApacheHttpd
+		injected := len(pod.Spec.InitContainers)
+		i.logger.V(1).Info("injecting Apache Httpd instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
+		pod = injectWithEnvPolicy(otelinst.Spec.EnvPolicy, pod, index, func(pod corev1.Pod) corev1.Pod {
+			resourceMap := i.createResourceMap(ctx, otelinst, ns, pod, index)
+			serviceName := i.chooseServiceName(ctx, otelinst.Spec.Resource.ServiceName, ns, pod, resourceMap, index)
+			pod = injectApacheHttpdagent(otelinst.Spec.ApacheHttpd, pod, index, otelinst.Spec.Endpoint, resourceMap, serviceName)
 			pod = i.injectCommonEnvVar(otelinst, pod, index)
 			pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
-		}
+			return secureInitContainers(otelinst.Spec.SecurityContext, pod, index, injected)
+		})
+	}
+	if insts.Nginx != nil {
+		otelinst := *insts.Nginx
+		injected := len(pod.Spec.InitContainers)
+		i.logger.V(1).Info("injecting Nginx instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
+		pod = injectWithEnvPolicy(otelinst.Spec.EnvPolicy, pod, index, func(pod corev1.Pod) corev1.Pod {
+			resourceMap := i.createResourceMap(ctx, otelinst, ns, pod, index)
+			serviceName := i.chooseServiceName(ctx, otelinst.Spec.Resource.ServiceName, ns, pod, resourceMap, index)
+			pod = injectNginxSDK(otelinst.Spec.Nginx, pod, index, otelinst.Spec.Endpoint, resourceMap, serviceName)
+			pod = i.injectCommonEnvVar(otelinst, pod, index)
+			pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
+			return secureInitContainers(otelinst.Spec.SecurityContext, pod, index, injected)
+		})
 	}
 	if insts.Sdk != nil {
 		otelinst := *insts.Sdk
 		i.logger.V(1).Info("injecting sdk-only instrumentation into pod", "otelinst-namespace", otelinst.Namespace, "otelinst-name", otelinst.Name)
-		pod = i.injectCommonEnvVar(otelinst, pod, index)
-		pod = i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
+		pod = injectWithEnvPolicy(otelinst.Spec.EnvPolicy, pod, index, func(pod corev1.Pod) corev1.Pod {
+			pod = i.injectCommonEnvVar(otelinst, pod, index)
+			return i.injectCommonSDKConfig(ctx, otelinst, ns, pod, index, index)
+		})
+	}
+	return pod, injectErr
+}
+
+// secureInitContainers sets the security context of the init containers injected from the given position on. The
+// containers cloned from the application container keep its security context, if any.
+func secureInitContainers(override *corev1.SecurityContext, pod corev1.Pod, index int, injected int) corev1.Pod {
+	for i := injected; i < len(pod.Spec.InitContainers); i++ {
+		if pod.Spec.InitContainers[i].SecurityContext == nil {
+			pod.Spec.InitContainers[i].SecurityContext = securitycontext.ForInjectedContainer(override, pod, pod.Spec.Containers[index])
+		}
 	}
 	return pod
 }
@@ -90,7 +220,7 @@ func (i *sdkInjector) injectCommonEnvVar(otelinst v1alpha1.Instrumentation, pod
 // agentIndex represents the index of the pod the needs the env vars to instrument the application.
 // appIndex represents the index of the pod the will produce the telemetry.
 // When the pod handling the instrumentation is the same as the pod producing the telemetry agentIndex
-// and appIndex should be the same value.  This is true for dotnet, java, nodejs, and python instrumentations.
+// and appIndex should be the same value.  This is true for apache httpd, dotnet, java, nginx, nodejs, and python instrumentations.
 // Go requires the agent to be a different container in the pod, so the agentIndex should represent this new sidecar
 // and appIndex should represent the application being instrumented.
 func (i *sdkInjector) injectCommonSDKConfig(ctx context.Context, otelinst v1alpha1.Instrumentation, ns corev1.Namespace, pod corev1.Pod, agentIndex int, appIndex int) corev1.Pod {
@@ -100,7 +230,7 @@ func (i *sdkInjector) injectCommonSDKConfig(ctx context.Context, otelinst v1alph
 	if idx == -1 {
 		container.Env = append(container.Env, corev1.EnvVar{
 			Name:  constants.EnvOTELServiceName,
-			Value: chooseServiceName(pod, resourceMap, appIndex),
+			Value: i.chooseServiceName(ctx, otelinst.Spec.Resource.ServiceName, ns, pod, resourceMap, appIndex),
 		})
 	}
 	if otelinst.Spec.Exporter.Endpoint != "" {
@@ -112,6 +242,8 @@ func (i *sdkInjector) injectCommonSDKConfig(ctx context.Context, otelinst v1alph
 			})
 		}
 	}
+	pod = injectExporterConfig(otelinst.Spec.Exporter, pod, agentIndex)
+	container = &pod.Spec.Containers[agentIndex]
 
 	// Some attributes might be empty, we should get them via k8s downward API
 	if resourceMap[string(semconv.K8SPodNameKey)] == "" {
@@ -140,8 +272,8 @@ func (i *sdkInjector) injectCommonSDKConfig(ctx context.Context, otelinst v1alph
 	}
 
 	idx = getIndexOfEnv(container.Env, constants.EnvOTELResourceAttrs)
-	if idx == -1 || !strings.Contains(container.Env[idx].Value, string(semconv.ServiceVersionKey)) {
-		vsn := chooseServiceVersion(pod, appIndex)
+	if idx == -1 || !hasResourceAttribute(container.Env[idx].Value, semconv.ServiceVersionKey) {
+		vsn := i.chooseServiceVersion(ctx, otelinst.Spec.Resource.ServiceVersion, ns, pod, appIndex)
 		if vsn != "" {
 			resourceMap[string(semconv.ServiceVersionKey)] = vsn
 		}
@@ -212,36 +344,6 @@ func (i *sdkInjector) injectCommonSDKConfig(ctx context.Context, otelinst v1alph
 	return pod
 }
 
-func chooseServiceName(pod corev1.Pod, resources map[string]string, index int) string {
-	if name := resources[string(semconv.K8SDeploymentNameKey)]; name != "" {
-		return name
-	}
-	if name := resources[string(semconv.K8SStatefulSetNameKey)]; name != "" {
-		return name
-	}
-	if name := resources[string(semconv.K8SJobNameKey)]; name != "" {
-		return name
-	}
-	if name := resources[string(semconv.K8SCronJobNameKey)]; name != "" {
-		return name
-	}
-	if name := resources[string(semconv.K8SPodNameKey)]; name != "" {
-		return name
-	}
-	return pod.Spec.Containers[index].Name
-}
-
-// obtains version by splitting image string on ":" and extracting final element from resulting array.
-func chooseServiceVersion(pod corev1.Pod, index int) string {
-	parts := strings.Split(pod.Spec.Containers[index].Image, ":")
-	tag := parts[len(parts)-1]
-	//guard statement to handle case where image name has a port number
-	if strings.Contains(tag, "/") {
-		return ""
-	}
-	return tag
-}
-
 // createResourceMap creates resource attribute map.
 // User defined attributes (in explicitly set env var) have higher precedence.
 func (i *sdkInjector) createResourceMap(ctx context.Context, otelinst v1alpha1.Instrumentation, ns corev1.Namespace, pod corev1.Pod, index int) map[string]string {
@@ -249,13 +351,8 @@ func (i *sdkInjector) createResourceMap(ctx context.Context, otelinst v1alpha1.I
 	existingRes := map[string]bool{}
 	existingResourceEnvIdx := getIndexOfEnv(pod.Spec.Containers[index].Env, constants.EnvOTELResourceAttrs)
 	if existingResourceEnvIdx > -1 {
-		existingResArr := strings.Split(pod.Spec.Containers[index].Env[existingResourceEnvIdx].Value, ",")
-		for _, kv := range existingResArr {
-			keyValueArr := strings.Split(strings.TrimSpace(kv), "=")
-			if len(keyValueArr) != 2 {
-				continue
-			}
-			existingRes[keyValueArr[0]] = true
+		for k := range resourceattributes.Parse(pod.Spec.Containers[index].Env[existingResourceEnvIdx].Value) {
+			existingRes[k] = true
 		}
 	}
 
@@ -280,81 +377,79 @@ func (i *sdkInjector) createResourceMap(ctx context.Context, otelinst v1alpha1.I
 			res[string(k)] = v
 		}
 	}
+	for k, v := range mappedResourceAttributes(otelinst.Spec.Resource, ns.ObjectMeta, pod.ObjectMeta) {
+		if _, ok := res[k]; !ok && !existingRes[k] {
+			res[k] = v
+		}
+	}
+	// the cloud and cluster attributes are the same for every pod, they don't override the ones set by users
+	for k, v := range i.config.ClusterAttributes().ResourceAttributes() {
+		if _, ok := res[string(k)]; !ok && !existingRes[string(k)] {
+			res[string(k)] = v
+		}
+	}
+	for k, v := range annotatedResourceAttributes(pod.ObjectMeta) {
+		if !existingRes[k] {
+			res[k] = v
+		}
+	}
 	return res
 }
 
 func (i *sdkInjector) addParentResourceLabels(ctx context.Context, uid bool, ns corev1.Namespace, objectMeta metav1.ObjectMeta, resources map[attribute.Key]string) {
 	for _, owner := range objectMeta.OwnerReferences {
-		switch strings.ToLower(owner.Kind) {
-		case "replicaset":
-			resources[semconv.K8SReplicaSetNameKey] = owner.Name
-			if uid {
-				resources[semconv.K8SReplicaSetUIDKey] = string(owner.UID)
-			}
+		if strings.EqualFold(owner.Kind, "replicaset") {
 			// parent of ReplicaSet is e.g. Deployment which we are interested to know
-			rs := appsv1.ReplicaSet{}
-			nsn := types.NamespacedName{Namespace: ns.Name, Name: owner.Name}
-			backOff := wait.Backoff{Duration: 10 * time.Millisecond, Factor: 1.5, Jitter: 0.1, Steps: 20, Cap: 2 * time.Second}
-
-			checkError := func(err error) bool {
-				return apierrors.IsNotFound(err)
-			}
-
-			getReplicaSet := func() error {
-				return i.client.Get(ctx, nsn, &rs)
-			}
-
-			// use a retry loop to get the Deployment. A single call to client.get fails occasionally
-			err := retry.OnError(backOff, checkError, getReplicaSet)
-			if err != nil {
-				i.logger.Error(err, "failed to get replicaset", "replicaset", nsn.Name, "namespace", nsn.Namespace)
-			}
-			i.addParentResourceLabels(ctx, uid, ns, rs.ObjectMeta, resources)
-		case "deployment":
-			resources[semconv.K8SDeploymentNameKey] = owner.Name
-			if uid {
-				resources[semconv.K8SDeploymentUIDKey] = string(owner.UID)
-			}
-		case "statefulset":
-			resources[semconv.K8SStatefulSetNameKey] = owner.Name
-			if uid {
-				resources[semconv.K8SStatefulSetUIDKey] = string(owner.UID)
-			}
-		case "daemonset":
-			resources[semconv.K8SDaemonSetNameKey] = owner.Name
-			if uid {
-				resources[semconv.K8SDaemonSetUIDKey] = string(owner.UID)
-			}
-		case "job":
-			resources[semconv.K8SJobNameKey] = owner.Name
-			if uid {
-				resources[semconv.K8SJobUIDKey] = string(owner.UID)
-			}
-		case "cronjob":
-			resources[semconv.K8SCronJobNameKey] = owner.Name
-			if uid {
-				resources[semconv.K8SCronJobUIDKey] = string(owner.UID)
+			if parent, ok := i.owners.ReplicaSetOwner(ctx, ns.Name, owner.Name, objectMeta.Labels[appsv1.DefaultDeploymentUniqueLabelKey]); ok {
+				addOwnerResourceLabels(uid, parent, resources)
 			}
 		}
+		addOwnerResourceLabels(uid, owners.Owner{Kind: owner.Kind, Name: owner.Name, UID: owner.UID}, resources)
 	}
 }
 
-func resourceMapToStr(res map[string]string) string {
-	keys := make([]string, 0, len(res))
-	for k := range res {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	var str = ""
-	for _, k := range keys {
-		if str != "" {
-			str += ","
+func addOwnerResourceLabels(uid bool, owner owners.Owner, resources map[attribute.Key]string) {
+	switch strings.ToLower(owner.Kind) {
+	case "replicaset":
+		resources[semconv.K8SReplicaSetNameKey] = owner.Name
+		if uid {
+			resources[semconv.K8SReplicaSetUIDKey] = string(owner.UID)
+		}
+	case "deployment":
+		resources[semconv.K8SDeploymentNameKey] = owner.Name
+		if uid {
+			resources[semconv.K8SDeploymentUIDKey] = string(owner.UID)
+		}
+	case "statefulset":
+		resources[semconv.K8SStatefulSetNameKey] = owner.Name
+		if uid {
+			resources[semconv.K8SStatefulSetUIDKey] = string(owner.UID)
+		}
+	case "daemonset":
+		resources[semconv.K8SDaemonSetNameKey] = owner.Name
+		if uid {
+			resources[semconv.K8SDaemonSetUIDKey] = string(owner.UID)
+		}
+	case "job":
+		resources[semconv.K8SJobNameKey] = owner.Name
+		if uid {
+			resources[semconv.K8SJobUIDKey] = string(owner.UID)
+		}
+	case "cronjob":
+		resources[semconv.K8SCronJobNameKey] = owner.Name
+		if uid {
+			resources[semconv.K8SCronJobUIDKey] = string(owner.UID)
 		}
-		str += fmt.Sprintf("%s=%s", k, res[k])
 	}
+}
+
+func resourceMapToStr(res map[string]string) string {
+	return resourceattributes.Encode(res)
+}
 
-	return str
+func hasResourceAttribute(value string, key attribute.Key) bool {
+	_, ok := resourceattributes.Parse(value)[string(key)]
+	return ok
 }
 
 func getIndexOfEnv(envs []corev1.EnvVar, name string) int {
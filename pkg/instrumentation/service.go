@@ -0,0 +1,124 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+const (
+	ownerKindRollout        = "Rollout"
+	ownerKindKnativeService = "KnativeService"
+
+	// knativeServiceLabel is set by Knative Serving on the pods of the revisions of a Knative Service.
+	knativeServiceLabel = "serving.knative.dev/service"
+)
+
+var (
+	defaultServiceNameSources = []v1alpha1.ServiceAttributeSource{
+		{From: v1alpha1.ServiceAttributeSourceOwner, Kind: ownerKindKnativeService},
+		{From: v1alpha1.ServiceAttributeSourceOwner, Kind: "Deployment"},
+		{From: v1alpha1.ServiceAttributeSourceOwner, Kind: ownerKindRollout},
+		{From: v1alpha1.ServiceAttributeSourceOwner, Kind: "StatefulSet"},
+		{From: v1alpha1.ServiceAttributeSourceOwner, Kind: "Job"},
+		{From: v1alpha1.ServiceAttributeSourceOwner, Kind: "CronJob"},
+		{From: v1alpha1.ServiceAttributeSourcePodName},
+	}
+	defaultServiceVersionSources = []v1alpha1.ServiceAttributeSource{
+		{From: v1alpha1.ServiceAttributeSourceImage},
+	}
+
+	// ownerResourceKeys are the resource attributes holding the names of the owners of the pod.
+	ownerResourceKeys = map[string]attribute.Key{
+		"Deployment":  semconv.K8SDeploymentNameKey,
+		"StatefulSet": semconv.K8SStatefulSetNameKey,
+		"DaemonSet":   semconv.K8SDaemonSetNameKey,
+		"ReplicaSet":  semconv.K8SReplicaSetNameKey,
+		"Job":         semconv.K8SJobNameKey,
+		"CronJob":     semconv.K8SCronJobNameKey,
+	}
+)
+
+// chooseServiceName returns the value of the first source of the service name with a value, the container name
+// when there is none.
+func (i *sdkInjector) chooseServiceName(ctx context.Context, sources []v1alpha1.ServiceAttributeSource, ns corev1.Namespace, pod corev1.Pod, resources map[string]string, index int) string {
+	if len(sources) == 0 {
+		sources = defaultServiceNameSources
+	}
+	for _, source := range sources {
+		if name := i.serviceAttribute(ctx, source, ns, pod, resources, index); name != "" {
+			return name
+		}
+	}
+	return pod.Spec.Containers[index].Name
+}
+
+// chooseServiceVersion returns the value of the first source of the service version with a value, if any.
+func (i *sdkInjector) chooseServiceVersion(ctx context.Context, sources []v1alpha1.ServiceAttributeSource, ns corev1.Namespace, pod corev1.Pod, index int) string {
+	if len(sources) == 0 {
+		sources = defaultServiceVersionSources
+	}
+	for _, source := range sources {
+		if version := i.serviceAttribute(ctx, source, ns, pod, nil, index); version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+func (i *sdkInjector) serviceAttribute(ctx context.Context, source v1alpha1.ServiceAttributeSource, ns corev1.Namespace, pod corev1.Pod, resources map[string]string, index int) string {
+	switch source.From {
+	case v1alpha1.ServiceAttributeSourceLabel:
+		return pod.Labels[source.Key]
+	case v1alpha1.ServiceAttributeSourceAnnotation:
+		return pod.Annotations[source.Key]
+	case v1alpha1.ServiceAttributeSourcePodName:
+		return resources[string(semconv.K8SPodNameKey)]
+	case v1alpha1.ServiceAttributeSourceContainerName:
+		return pod.Spec.Containers[index].Name
+	case v1alpha1.ServiceAttributeSourceImage:
+		return imageVersion(pod.Spec.Containers[index].Image)
+	case v1alpha1.ServiceAttributeSourceOwner:
+		switch source.Kind {
+		case ownerKindKnativeService:
+			return pod.Labels[knativeServiceLabel]
+		case ownerKindRollout:
+			return i.rolloutName(ctx, ns, pod)
+		default:
+			return resources[string(ownerResourceKeys[source.Kind])]
+		}
+	}
+	return ""
+}
+
+// rolloutName returns the name of the Argo Rollout controlling the ReplicaSet of the pod, if any.
+func (i *sdkInjector) rolloutName(ctx context.Context, ns corev1.Namespace, pod corev1.Pod) string {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return ""
+	}
+	// the Rollout can't be derived from the name of the ReplicaSet, which is then reported as its own owner
+	if parent, ok := i.owners.ReplicaSetOwner(ctx, ns.Name, owner.Name, ""); ok && parent.Kind == ownerKindRollout {
+		return parent.Name
+	}
+	return ""
+}
+
+// imageVersion returns the tag of the image reference, or else its digest, e.g. sha256:4c1e....
+func imageVersion(image string) string {
+	name, digest, _ := strings.Cut(image, "@")
+	// a colon before the last slash separates the port of the registry
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[i+1:]
+	}
+	return digest
+}
@@ -0,0 +1,139 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
+)
+
+func TestChooseServiceName(t *testing.T) {
+	controller := true
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:      "checkout-6d4cf56db6",
+		Namespace: "apps",
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "checkout", Controller: &controller},
+		},
+	}}
+	injector := sdkInjector{
+		logger: logr.Discard(),
+		owners: owners.NewResolver(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(rs).Build(), logr.Discard()),
+	}
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	newPod := func(labels map[string]string, owner string) corev1.Pod {
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels, Annotations: map[string]string{"example.com/service": "checkout-api"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		}
+		if owner != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: owner, Controller: &controller}}
+		}
+		return pod
+	}
+
+	for _, tt := range []struct {
+		name      string
+		sources   []v1alpha1.ServiceAttributeSource
+		pod       corev1.Pod
+		resources map[string]string
+		expected  string
+	}{
+		{
+			name:      "deployment",
+			pod:       newPod(nil, "checkout-7c9f5b8d4"),
+			resources: map[string]string{"k8s.deployment.name": "checkout", "k8s.replicaset.name": "checkout-7c9f5b8d4", "k8s.pod.name": "checkout-7c9f5b8d4-x2x9z"},
+			expected:  "checkout",
+		},
+		{
+			name:      "rollout",
+			pod:       newPod(map[string]string{"rollouts-pod-template-hash": "6d4cf56db6"}, "checkout-6d4cf56db6"),
+			resources: map[string]string{"k8s.replicaset.name": "checkout-6d4cf56db6"},
+			expected:  "checkout",
+		},
+		{
+			name:      "knative service",
+			pod:       newPod(map[string]string{"serving.knative.dev/service": "checkout"}, "checkout-00001-deployment-5d8f"),
+			resources: map[string]string{"k8s.deployment.name": "checkout-00001-deployment"},
+			expected:  "checkout",
+		},
+		{
+			name:      "pod name",
+			pod:       newPod(nil, ""),
+			resources: map[string]string{"k8s.pod.name": "checkout"},
+			expected:  "checkout",
+		},
+		{
+			name:     "container name",
+			pod:      newPod(nil, ""),
+			expected: "app",
+		},
+		{
+			name: "label then annotation",
+			sources: []v1alpha1.ServiceAttributeSource{
+				{From: v1alpha1.ServiceAttributeSourceLabel, Key: "app.kubernetes.io/name"},
+				{From: v1alpha1.ServiceAttributeSourceAnnotation, Key: "example.com/service"},
+			},
+			pod:       newPod(nil, ""),
+			resources: map[string]string{"k8s.deployment.name": "checkout"},
+			expected:  "checkout-api",
+		},
+		{
+			name:      "daemonset",
+			sources:   []v1alpha1.ServiceAttributeSource{{From: v1alpha1.ServiceAttributeSourceOwner, Kind: "DaemonSet"}},
+			pod:       newPod(nil, ""),
+			resources: map[string]string{"k8s.daemonset.name": "node-agent"},
+			expected:  "node-agent",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, injector.chooseServiceName(context.Background(), tt.sources, ns, tt.pod, tt.resources, 0))
+		})
+	}
+}
+
+func TestChooseServiceVersion(t *testing.T) {
+	injector := sdkInjector{logger: logr.Discard()}
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app.kubernetes.io/version": "1.4.0"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "registry:5000/checkout@sha256:4c1e8f"}}},
+	}
+
+	assert.Equal(t, "sha256:4c1e8f", injector.chooseServiceVersion(context.Background(), nil, ns, pod, 0))
+	assert.Equal(t, "1.4.0", injector.chooseServiceVersion(context.Background(), []v1alpha1.ServiceAttributeSource{
+		{From: v1alpha1.ServiceAttributeSourceLabel, Key: "app.kubernetes.io/version"},
+		{From: v1alpha1.ServiceAttributeSourceImage},
+	}, ns, pod, 0))
+	assert.Empty(t, injector.chooseServiceVersion(context.Background(), []v1alpha1.ServiceAttributeSource{
+		{From: v1alpha1.ServiceAttributeSourceAnnotation, Key: "example.com/version"},
+	}, ns, pod, 0))
+}
+
+func TestImageVersion(t *testing.T) {
+	for image, expected := range map[string]string{
+		"checkout":                             "",
+		"checkout:1.4.0":                       "1.4.0",
+		"registry:5000/checkout":               "",
+		"registry:5000/team/checkout:1.4.0":    "1.4.0",
+		"checkout@sha256:4c1e8f":               "sha256:4c1e8f",
+		"registry:5000/checkout@sha256:4c1e8f": "sha256:4c1e8f",
+		"checkout:1.4.0@sha256:4c1e8f":         "1.4.0",
+	} {
+		t.Run(image, func(t *testing.T) {
+			assert.Equal(t, expected, imageVersion(image))
+		})
+	}
+}
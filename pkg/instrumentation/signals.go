@@ -0,0 +1,67 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+)
+
+const (
+	envOtelLogsExporter                     = "OTEL_LOGS_EXPORTER"
+	otelApplicationSignalsRuntimeEnabledKey = "OTEL_AWS_APPLICATION_SIGNALS_RUNTIME_ENABLED"
+)
+
+// withSignals returns the Instrumentation with its exporter selection and Application Signals settings turned into
+// the env vars of each language. The Instrumentation is copied when it has any of them.
+func withSignals(inst *v1alpha1.Instrumentation) *v1alpha1.Instrumentation {
+	spec := inst.Spec
+	if spec.Traces.Exporter == "" && spec.Metrics.Exporter == "" && spec.Logs.Exporter == "" && spec.ApplicationSignals == nil {
+		return inst
+	}
+	resolved := inst.DeepCopy()
+	applySignals(&resolved.Spec)
+	return resolved
+}
+
+// applySignals sets the exporter selection in the env of the SDKs and of the Go agent, and the Application Signals
+// settings in the env of the AWS distributions of the SDKs, which are the only ones generating the service metrics.
+// Env vars already set on the Instrumentation, for the language or for all of them, are kept.
+func applySignals(spec *v1alpha1.InstrumentationSpec) {
+	sdks := []*[]corev1.EnvVar{&spec.Java.Env, &spec.Python.Env, &spec.NodeJS.Env, &spec.DotNet.Env}
+	exporters := []struct {
+		name     string
+		exporter v1alpha1.SignalExporter
+	}{
+		{envOtelTracesExporter, spec.Traces.Exporter},
+		{envOtelMetricsExporter, spec.Metrics.Exporter},
+		{envOtelLogsExporter, spec.Logs.Exporter},
+	}
+	for _, env := range append(sdks, &spec.Go.Env) {
+		for _, e := range exporters {
+			if e.exporter != "" {
+				setLanguageEnvIfMissing(spec, env, e.name, string(e.exporter))
+			}
+		}
+	}
+
+	appSignals := spec.ApplicationSignals
+	if appSignals == nil {
+		return
+	}
+	for _, env := range sdks {
+		if appSignals.Enabled != nil {
+			setLanguageEnvIfMissing(spec, env, otelSampleEnabledKey, strconv.FormatBool(*appSignals.Enabled))
+		}
+		if appSignals.Endpoint != "" {
+			setLanguageEnvIfMissing(spec, env, otelExporterSmpEndpointKey, appSignals.Endpoint)
+		}
+		if appSignals.RuntimeMetrics != nil {
+			setLanguageEnvIfMissing(spec, env, otelApplicationSignalsRuntimeEnabledKey, strconv.FormatBool(*appSignals.RuntimeMetrics))
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
+)
+
+func TestApplySignals(t *testing.T) {
+	enabled, runtimeMetrics := true, true
+	spec := v1alpha1.InstrumentationSpec{
+		Traces:  v1alpha1.Signal{Exporter: v1alpha1.SignalExporterOTLP},
+		Metrics: v1alpha1.Signal{Exporter: v1alpha1.SignalExporterNone},
+		Logs:    v1alpha1.Signal{Exporter: v1alpha1.SignalExporterLogging},
+		ApplicationSignals: &v1alpha1.ApplicationSignals{
+			Enabled:        &enabled,
+			Endpoint:       "http://agent.monitoring:4316/v1/metrics",
+			RuntimeMetrics: &runtimeMetrics,
+		},
+		Env: []corev1.EnvVar{{Name: envOtelLogsExporter, Value: "otlp"}},
+		Python: v1alpha1.Python{
+			Env: []corev1.EnvVar{{Name: otelSampleEnabledKey, Value: "false"}},
+		},
+	}
+	applySignals(&spec)
+
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: envOtelTracesExporter, Value: "otlp"},
+		{Name: envOtelMetricsExporter, Value: "none"},
+		{Name: otelSampleEnabledKey, Value: "true"},
+		{Name: otelExporterSmpEndpointKey, Value: "http://agent.monitoring:4316/v1/metrics"},
+		{Name: otelApplicationSignalsRuntimeEnabledKey, Value: "true"},
+	}, spec.Java.Env)
+	// the env vars set for the language or for all of them are kept
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: otelSampleEnabledKey, Value: "false"},
+		{Name: envOtelTracesExporter, Value: "otlp"},
+		{Name: envOtelMetricsExporter, Value: "none"},
+		{Name: otelExporterSmpEndpointKey, Value: "http://agent.monitoring:4316/v1/metrics"},
+		{Name: otelApplicationSignalsRuntimeEnabledKey, Value: "true"},
+	}, spec.Python.Env)
+	// the Go agent doesn't generate service metrics
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: envOtelTracesExporter, Value: "otlp"},
+		{Name: envOtelMetricsExporter, Value: "none"},
+	}, spec.Go.Env)
+	assert.Empty(t, spec.ApacheHttpd.Env)
+}
+
+func TestWithSignals(t *testing.T) {
+	inst := &v1alpha1.Instrumentation{}
+	assert.Same(t, inst, withSignals(inst))
+
+	inst.Spec.Metrics.Exporter = v1alpha1.SignalExporterOTLP
+	resolved := withSignals(inst)
+	assert.Contains(t, resolved.Spec.DotNet.Env, corev1.EnvVar{Name: envOtelMetricsExporter, Value: "otlp"})
+	assert.Empty(t, inst.Spec.DotNet.Env)
+}
+
+func TestResolveInstrumentationSignals(t *testing.T) {
+	clusterInst := &v1alpha1.ClusterInstrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Metrics: v1alpha1.Signal{Exporter: v1alpha1.SignalExporterOTLP},
+			Java: v1alpha1.Java{
+				Env: []corev1.EnvVar{{Name: envOtelTracesExporter, Value: "otlp"}},
+			},
+		},
+	}
+	require.NoError(t, v1alpha1.AddToScheme(testScheme))
+	podMutator := instPodMutator{
+		Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(clusterInst).Build(),
+		Logger: logr.Discard(),
+		config: config.New(),
+	}
+	inst := &v1alpha1.Instrumentation{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1alpha1.InstrumentationSpec{
+			Traces: v1alpha1.Signal{Exporter: v1alpha1.SignalExporterNone},
+		},
+	}
+
+	resolved, err := podMutator.resolveInstrumentation(context.Background(), corev1.Pod{}, inst)
+	require.NoError(t, err)
+	// the exporter of the Instrumentation wins over the env inherited from the ClusterInstrumentation, and the
+	// exporter inherited from it is applied as well
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: envOtelTracesExporter, Value: "none"},
+		{Name: envOtelMetricsExporter, Value: "otlp"},
+	}, resolved.Spec.Java.Env)
+}
@@ -9,10 +9,12 @@ import (
 	"reflect"
 
 	"github.com/go-logr/logr"
-	"github.com/open-telemetry/opentelemetry-operator/apis/v1alpha1"
 	"github.com/open-telemetry/opentelemetry-operator/pkg/featuregate"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	cwfeaturegate "github.com/aws/amazon-cloudwatch-agent-operator/pkg/featuregate"
 )
 
 type InstrumentationUpgrade struct {
@@ -20,11 +22,12 @@ type InstrumentationUpgrade struct {
 	Logger                     logr.Logger
 	Recorder                   record.EventRecorder
 	DefaultAutoInstJava        string
-	DefaultAutoInstNodeJS      string
 	DefaultAutoInstPython      string
+	DefaultAutoInstNodeJS      string
 	DefaultAutoInstDotNet      string
-	DefaultAutoInstApacheHttpd string
 	DefaultAutoInstGo          string
+	DefaultAutoInstApacheHttpd string
+	DefaultAutoInstNginx       string
 }
 
 // +kubebuilder:rbac:groups=cloudwatch.aws.amazon.com,resources=instrumentations,verbs=get;list;watch;update;patch
@@ -75,19 +78,6 @@ func (u *InstrumentationUpgrade) upgrade(_ context.Context, inst v1alpha1.Instru
 			u.Recorder.Event(inst.DeepCopy(), "Warning", "InstrumentationUpgradeRejected", "support for Java auto instrumentation is not enabled")
 		}
 	}
-	autoInstNodeJS := inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationNodeJS]
-	if autoInstNodeJS != "" {
-		if featuregate.EnableNodeJSAutoInstrumentationSupport.IsEnabled() {
-			// upgrade the image only if the image matches the annotation
-			if inst.Spec.NodeJS.Image == autoInstNodeJS {
-				inst.Spec.NodeJS.Image = u.DefaultAutoInstNodeJS
-				inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationNodeJS] = u.DefaultAutoInstNodeJS
-			}
-		} else {
-			u.Logger.Error(nil, "support for NodeJS auto instrumentation is not enabled")
-			u.Recorder.Event(inst.DeepCopy(), "Warning", "InstrumentationUpgradeRejected", "support for NodeJS auto instrumentation is not enabled")
-		}
-	}
 	autoInstPython := inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationPython]
 	if autoInstPython != "" {
 		if featuregate.EnablePythonAutoInstrumentationSupport.IsEnabled() {
@@ -101,11 +91,24 @@ func (u *InstrumentationUpgrade) upgrade(_ context.Context, inst v1alpha1.Instru
 			u.Recorder.Event(inst.DeepCopy(), "Warning", "InstrumentationUpgradeRejected", "support for Python auto instrumentation is not enabled")
 		}
 	}
-	autoInstDotnet := inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationDotNet]
-	if autoInstDotnet != "" {
+	autoInstNodeJS := inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationNodeJS]
+	if autoInstNodeJS != "" {
+		if featuregate.EnableNodeJSAutoInstrumentationSupport.IsEnabled() {
+			// upgrade the image only if the image matches the annotation
+			if inst.Spec.NodeJS.Image == autoInstNodeJS {
+				inst.Spec.NodeJS.Image = u.DefaultAutoInstNodeJS
+				inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationNodeJS] = u.DefaultAutoInstNodeJS
+			}
+		} else {
+			u.Logger.Error(nil, "support for NodeJS auto instrumentation is not enabled")
+			u.Recorder.Event(inst.DeepCopy(), "Warning", "InstrumentationUpgradeRejected", "support for NodeJS auto instrumentation is not enabled")
+		}
+	}
+	autoInstDotNet := inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationDotNet]
+	if autoInstDotNet != "" {
 		if featuregate.EnableDotnetAutoInstrumentationSupport.IsEnabled() {
 			// upgrade the image only if the image matches the annotation
-			if inst.Spec.DotNet.Image == autoInstDotnet {
+			if inst.Spec.DotNet.Image == autoInstDotNet {
 				inst.Spec.DotNet.Image = u.DefaultAutoInstDotNet
 				inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationDotNet] = u.DefaultAutoInstDotNet
 			}
@@ -140,5 +143,18 @@ func (u *InstrumentationUpgrade) upgrade(_ context.Context, inst v1alpha1.Instru
 			u.Recorder.Event(inst.DeepCopy(), "Warning", "InstrumentationUpgradeRejected", "support for Apache HTTPD auto instrumentation is not enabled")
 		}
 	}
+	autoInstNginx := inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationNginx]
+	if autoInstNginx != "" {
+		if cwfeaturegate.EnableNginxAutoInstrumentationSupport.IsEnabled() {
+			// upgrade the image only if the image matches the annotation
+			if inst.Spec.Nginx.Image == autoInstNginx {
+				inst.Spec.Nginx.Image = u.DefaultAutoInstNginx
+				inst.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationNginx] = u.DefaultAutoInstNginx
+			}
+		} else {
+			u.Logger.Error(nil, "support for Nginx auto instrumentation is not enabled")
+			u.Recorder.Event(inst.DeepCopy(), "Warning", "InstrumentationUpgradeRejected", "support for Nginx auto instrumentation is not enabled")
+		}
+	}
 	return inst
 }
@@ -16,8 +16,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
-	"github.com/open-telemetry/opentelemetry-operator/apis/v1alpha1"
 	"github.com/open-telemetry/opentelemetry-operator/pkg/featuregate"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
+	cwfeaturegate "github.com/aws/amazon-cloudwatch-agent-operator/pkg/featuregate"
 )
 
 func TestUpgrade(t *testing.T) {
@@ -26,11 +28,10 @@ func TestUpgrade(t *testing.T) {
 	t.Cleanup(func() {
 		require.NoError(t, colfeaturegate.GlobalRegistry().Set(featuregate.EnableGoAutoInstrumentationSupport.ID(), originalVal))
 	})
-
-	originalVal = featuregate.EnableApacheHTTPAutoInstrumentationSupport.IsEnabled()
-	require.NoError(t, colfeaturegate.GlobalRegistry().Set(featuregate.EnableApacheHTTPAutoInstrumentationSupport.ID(), true))
+	originalNginxVal := cwfeaturegate.EnableNginxAutoInstrumentationSupport.IsEnabled()
+	require.NoError(t, colfeaturegate.GlobalRegistry().Set(cwfeaturegate.EnableNginxAutoInstrumentationSupport.ID(), true))
 	t.Cleanup(func() {
-		require.NoError(t, colfeaturegate.GlobalRegistry().Set(featuregate.EnableApacheHTTPAutoInstrumentationSupport.ID(), originalVal))
+		require.NoError(t, colfeaturegate.GlobalRegistry().Set(cwfeaturegate.EnableNginxAutoInstrumentationSupport.ID(), originalNginxVal))
 	})
 
 	nsName := strings.ToLower(t.Name())
@@ -52,6 +53,7 @@ func TestUpgrade(t *testing.T) {
 				v1alpha1.AnnotationDefaultAutoInstrumentationDotNet:      "dotnet:1",
 				v1alpha1.AnnotationDefaultAutoInstrumentationGo:          "go:1",
 				v1alpha1.AnnotationDefaultAutoInstrumentationApacheHttpd: "apache-httpd:1",
+				v1alpha1.AnnotationDefaultAutoInstrumentationNginx:       "nginx:1",
 			},
 		},
 		Spec: v1alpha1.InstrumentationSpec{
@@ -67,6 +69,7 @@ func TestUpgrade(t *testing.T) {
 	assert.Equal(t, "dotnet:1", inst.Spec.DotNet.Image)
 	assert.Equal(t, "go:1", inst.Spec.Go.Image)
 	assert.Equal(t, "apache-httpd:1", inst.Spec.ApacheHttpd.Image)
+	assert.Equal(t, "nginx:1", inst.Spec.Nginx.Image)
 	err = k8sClient.Create(context.Background(), inst)
 	require.NoError(t, err)
 
@@ -78,6 +81,7 @@ func TestUpgrade(t *testing.T) {
 		DefaultAutoInstDotNet:      "dotnet:2",
 		DefaultAutoInstGo:          "go:2",
 		DefaultAutoInstApacheHttpd: "apache-httpd:2",
+		DefaultAutoInstNginx:       "nginx:2",
 		Client:                     k8sClient,
 	}
 	err = up.ManagedInstances(context.Background())
@@ -101,4 +105,6 @@ func TestUpgrade(t *testing.T) {
 	assert.Equal(t, "go:2", updated.Spec.Go.Image)
 	assert.Equal(t, "apache-httpd:2", updated.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationApacheHttpd])
 	assert.Equal(t, "apache-httpd:2", updated.Spec.ApacheHttpd.Image)
+	assert.Equal(t, "nginx:2", updated.Annotations[v1alpha1.AnnotationDefaultAutoInstrumentationNginx])
+	assert.Equal(t, "nginx:2", updated.Spec.Nginx.Image)
 }
@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package owners looks up the workloads owning the pods admitted by the webhook, from metadata-only informers.
+package owners
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultLookupTimeout bounds the wait of a single lookup, including the initial sync of the informer.
+	DefaultLookupTimeout = 250 * time.Millisecond
+	// DefaultRequestBudget bounds the time spent on lookups during one admission request.
+	DefaultRequestBudget = time.Second
+
+	// notFoundRetryInterval is the interval at which a ReplicaSet not yet known by the informer is looked up again.
+	notFoundRetryInterval = 10 * time.Millisecond
+)
+
+var errBudgetExhausted = errors.New("the owner lookup budget of the request is exhausted")
+
+// Owner identifies the workload controlling an object. The UID is empty when the owner was derived from a name.
+type Owner struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	UID        types.UID
+}
+
+// Resolver looks up the controllers of ReplicaSets. Reads are expected to be served by the cache of the manager, in
+// which case the ReplicaSets are watched in metadata-only form and shared by every caller.
+type Resolver struct {
+	reader client.Reader
+	logger logr.Logger
+	// LookupTimeout bounds the wait of a single lookup, DefaultLookupTimeout by default.
+	LookupTimeout time.Duration
+}
+
+// NewResolver creates a Resolver reading from the given reader, usually the client of the manager.
+func NewResolver(reader client.Reader, logger logr.Logger) *Resolver {
+	return &Resolver{
+		reader:        reader,
+		logger:        logger,
+		LookupTimeout: DefaultLookupTimeout,
+	}
+}
+
+type budgetKey struct{}
+
+// WithBudget returns a context in which the lookups share the given budget. Once it is spent, owners are derived
+// from the object names only, without waiting for the informers.
+func WithBudget(ctx context.Context, budget time.Duration) context.Context {
+	return context.WithValue(ctx, budgetKey{}, time.Now().Add(budget))
+}
+
+// RegisterInformers registers the informers used by the Resolver in the cache, so that they are synced when the
+// cache starts instead of on the first admission request.
+func RegisterInformers(ctx context.Context, informers cache.Informers) error {
+	_, err := informers.GetInformer(ctx, replicaSetMetadata())
+	return err
+}
+
+// ReplicaSetOwner returns the controller of the ReplicaSet, usually a Deployment, whatever its kind. When the
+// ReplicaSet cannot be looked up in time, its Deployment is only derived when the pods carry the pod-template-hash
+// label that the Deployment controller suffixes the ReplicaSet name with; otherwise the ReplicaSet itself is
+// reported, its controller being unknown. False is returned when the ReplicaSet has no owner.
+func (r *Resolver) ReplicaSetOwner(ctx context.Context, namespace string, replicaSet string, podTemplateHash string) (Owner, bool) {
+	rs, err := r.getReplicaSet(ctx, types.NamespacedName{Namespace: namespace, Name: replicaSet})
+	if err == nil {
+		controller := metav1.GetControllerOfNoCopy(rs)
+		if controller == nil {
+			return Owner{}, false
+		}
+		return Owner{APIVersion: controller.APIVersion, Kind: controller.Kind, Name: controller.Name, UID: controller.UID}, true
+	}
+
+	r.logger.V(1).Info("failed to look up the replicaset, deriving its owner from its name", "replicaset", replicaSet, "namespace", namespace, "reason", err.Error())
+	if deployment := deploymentName(replicaSet, podTemplateHash); deployment != "" {
+		return Owner{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment", Name: deployment}, true
+	}
+	return Owner{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "ReplicaSet", Name: replicaSet}, true
+}
+
+// getReplicaSet reads the metadata of the ReplicaSet, within the lookup timeout and the budget of the request. A
+// ReplicaSet created right before its pods may not be known by the informer yet, so it is looked up again until
+// the timeout.
+func (r *Resolver) getReplicaSet(ctx context.Context, key types.NamespacedName) (*metav1.PartialObjectMetadata, error) {
+	timeout := r.LookupTimeout
+	if timeout <= 0 {
+		timeout = DefaultLookupTimeout
+	}
+	if deadline, ok := ctx.Value(budgetKey{}).(time.Time); ok {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, errBudgetExhausted
+		}
+		if remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	rs := replicaSetMetadata()
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, notFoundRetryInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		lastErr = r.reader.Get(ctx, key, rs)
+		if apierrors.IsNotFound(lastErr) {
+			return false, nil
+		}
+		return true, lastErr
+	})
+	if err != nil && lastErr != nil {
+		return nil, lastErr
+	}
+	return rs, err
+}
+
+func replicaSetMetadata() *metav1.PartialObjectMetadata {
+	rs := &metav1.PartialObjectMetadata{}
+	rs.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))
+	return rs
+}
+
+// deploymentName derives the name of a Deployment from the name of one of its ReplicaSets, which is the name of the
+// Deployment suffixed with the hash of the pod template.
+func deploymentName(replicaSet string, podTemplateHash string) string {
+	if podTemplateHash == "" {
+		return ""
+	}
+	if name := strings.TrimSuffix(replicaSet, "-"+podTemplateHash); name != replicaSet && name != "" {
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package owners
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// slowReader simulates an informer that hasn't synced yet, or an overloaded API server.
+type slowReader struct {
+	client.Reader
+	latency time.Duration
+}
+
+func (r slowReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(r.latency):
+		return r.Reader.Get(ctx, key, obj, opts...)
+	}
+}
+
+func newReplicaSet(name string, deployment string) *appsv1.ReplicaSet {
+	controller := true
+	return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "apps",
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: deployment, UID: types.UID(deployment + "-uid"), Controller: &controller},
+		},
+	}}
+}
+
+func newRolloutReplicaSet(name string, rollout string) *appsv1.ReplicaSet {
+	rs := newReplicaSet(name, rollout)
+	rs.OwnerReferences[0].APIVersion = "argoproj.io/v1alpha1"
+	rs.OwnerReferences[0].Kind = "Rollout"
+	return rs
+}
+
+func TestReplicaSetOwner(t *testing.T) {
+	reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		newReplicaSet("checkout-7c9f5b8d4", "checkout"),
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "apps"}},
+	).Build()
+
+	for _, tt := range []struct {
+		name       string
+		reader     client.Reader
+		budget     time.Duration
+		replicaSet string
+		hash       string
+		expected   Owner
+		found      bool
+	}{
+		{
+			name:       "looked up",
+			reader:     reader,
+			replicaSet: "checkout-7c9f5b8d4",
+			expected:   Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "checkout", UID: "checkout-uid"},
+			found:      true,
+		},
+		{
+			name:       "without controller",
+			reader:     reader,
+			replicaSet: "orphan",
+		},
+		{
+			name:       "not found falls back to the pod template hash",
+			reader:     reader,
+			replicaSet: "payments-api-6b7d9c",
+			hash:       "6b7d9c",
+			expected:   Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "payments-api"},
+			found:      true,
+		},
+		{
+			name:       "slow lookup falls back to the pod template hash",
+			reader:     slowReader{Reader: reader, latency: time.Second},
+			replicaSet: "checkout-7c9f5b8d4",
+			hash:       "7c9f5b8d4",
+			expected:   Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "checkout"},
+			found:      true,
+		},
+		{
+			name:       "exhausted budget falls back to the pod template hash",
+			reader:     reader,
+			budget:     -time.Second,
+			replicaSet: "checkout-7c9f5b8d4",
+			hash:       "7c9f5b8d4",
+			expected:   Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "checkout"},
+			found:      true,
+		},
+		{
+			name:       "not found without pod template hash reports the replicaset",
+			reader:     reader,
+			replicaSet: "checkout-6d4cf56db6",
+			expected:   Owner{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "checkout-6d4cf56db6"},
+			found:      true,
+		},
+		{
+			name:       "not found with another hash reports the replicaset",
+			reader:     reader,
+			replicaSet: "checkout-6d4cf56db6",
+			hash:       "7c9f5b8d4",
+			expected:   Owner{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "checkout-6d4cf56db6"},
+			found:      true,
+		},
+		{
+			name:       "looked up controller of another kind",
+			reader:     fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(newRolloutReplicaSet("checkout-6d4cf56db6", "checkout")).Build(),
+			replicaSet: "checkout-6d4cf56db6",
+			hash:       "6d4cf56db6",
+			expected:   Owner{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "checkout", UID: "checkout-uid"},
+			found:      true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewResolver(tt.reader, logr.Discard())
+			resolver.LookupTimeout = 50 * time.Millisecond
+			ctx := context.Background()
+			if tt.budget != 0 {
+				ctx = WithBudget(ctx, tt.budget)
+			}
+
+			start := time.Now()
+			owner, found := resolver.ReplicaSetOwner(ctx, "apps", tt.replicaSet, tt.hash)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, owner)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+		})
+	}
+}
+
+func TestDeploymentName(t *testing.T) {
+	for _, tt := range []struct {
+		replicaSet string
+		hash       string
+		expected   string
+	}{
+		{replicaSet: "checkout-7c9f5b8d4", hash: "7c9f5b8d4", expected: "checkout"},
+		{replicaSet: "checkout-7c9f5b8d4", hash: "5b8d4", expected: ""},
+		{replicaSet: "checkout-7c9f5b8d4", expected: ""},
+		{replicaSet: "-7c9f5b8d4", hash: "7c9f5b8d4", expected: ""},
+	} {
+		t.Run(tt.replicaSet+"/"+tt.hash, func(t *testing.T) {
+			assert.Equal(t, tt.expected, deploymentName(tt.replicaSet, tt.hash))
+		})
+	}
+}
+
+// BenchmarkReplicaSetOwner looks up owners concurrently, as during a large rollout, from a synced informer, from a
+// reader slower than the lookup timeout, and with the request budget already spent.
+func BenchmarkReplicaSetOwner(b *testing.B) {
+	var objects []client.Object
+	for i := 0; i < 1000; i++ {
+		objects = append(objects, newReplicaSet(fmt.Sprintf("app-%d-7c9f5b8d4", i), fmt.Sprintf("app-%d", i)))
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+
+	for _, bb := range []struct {
+		name   string
+		reader client.Reader
+		budget time.Duration
+	}{
+		{name: "synced", reader: reader, budget: DefaultRequestBudget},
+		{name: "slow", reader: slowReader{Reader: reader, latency: time.Second}, budget: DefaultRequestBudget},
+		{name: "exhausted budget", reader: slowReader{Reader: reader, latency: time.Second}, budget: -time.Second},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			resolver := NewResolver(bb.reader, logr.Discard())
+			resolver.LookupTimeout = 10 * time.Millisecond
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					ctx := WithBudget(context.Background(), bb.budget)
+					if _, found := resolver.ReplicaSetOwner(ctx, "apps", fmt.Sprintf("app-%d-7c9f5b8d4", i%1000), "7c9f5b8d4"); !found {
+						b.Fatal("owner not found")
+					}
+					i++
+				}
+			})
+		})
+	}
+}
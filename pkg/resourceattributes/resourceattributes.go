@@ -0,0 +1,111 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package resourceattributes encodes and parses the value of the OTEL_RESOURCE_ATTRIBUTES env var, a comma
+// separated list of key=value pairs whose keys and values are percent-encoded like W3C baggage.
+// See: https://github.com/open-telemetry/opentelemetry-specification/blob/v1.26.0/specification/resource/sdk.md#specifying-resource-information-via-an-environment-variable
+package resourceattributes
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const upperHex = "0123456789ABCDEF"
+
+// envVarReference matches a reference to another env var of the container, which the kubelet expands.
+var envVarReference = regexp.MustCompile(`^\$\([A-Za-z_][A-Za-z0-9_]*\)$`)
+
+// Encode returns the attributes as the value of OTEL_RESOURCE_ATTRIBUTES, sorted by key. Values that are exactly
+// a reference to another env var, e.g. $(OTEL_RESOURCE_ATTRIBUTES_POD_NAME), are kept as they are, so that they
+// are expanded by the kubelet. Any other $ is encoded, so that it is not.
+func Encode(attributes map[string]string) string {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		escape(&sb, k)
+		sb.WriteByte('=')
+		if v := attributes[k]; envVarReference.MatchString(v) {
+			sb.WriteString(v)
+		} else {
+			escape(&sb, v)
+		}
+	}
+	return sb.String()
+}
+
+// escape percent-encodes the bytes out of the baggage-octet range, as well as the separators and escape characters.
+func escape(sb *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isBaggageOctet(c) && c != '%' && c != '=' && c != '$' {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(upperHex[c>>4])
+		sb.WriteByte(upperHex[c&0x0F])
+	}
+}
+
+// isBaggageOctet tells whether the character is a US-ASCII character excluding control characters, whitespace,
+// double quote, comma, semicolon and backslash.
+func isBaggageOctet(c byte) bool {
+	return c > ' ' && c < 0x7F && c != '"' && c != ',' && c != ';' && c != '\\'
+}
+
+// Parse returns the attributes of an OTEL_RESOURCE_ATTRIBUTES value, which may have been written by hand. Pairs
+// without a key are dropped, values may contain '=', and invalid percent-encoded sequences are kept as they are.
+// When a key is repeated, the last value wins.
+func Parse(value string) map[string]string {
+	attributes := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, _ := strings.Cut(pair, "=")
+		k = unescape(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		attributes[k] = unescape(strings.TrimSpace(v))
+	}
+	return attributes
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			sb.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
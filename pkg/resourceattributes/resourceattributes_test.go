@@ -0,0 +1,83 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package resourceattributes
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, "", Encode(nil))
+	assert.Equal(t,
+		"k8s.pod.labels.app.kubernetes.io/name=checkout,k8s.pod.name=$(OTEL_RESOURCE_ATTRIBUTES_POD_NAME),"+
+			"owner=Payments%2C%20Inc.,query=a%3Db%25,team%3Dname=caf%C3%A9,template=%24%24(SECRET)",
+		Encode(map[string]string{
+			"k8s.pod.labels.app.kubernetes.io/name": "checkout",
+			"k8s.pod.name":                          "$(OTEL_RESOURCE_ATTRIBUTES_POD_NAME)",
+			"owner":                                 "Payments, Inc.",
+			"query":                                 "a=b%",
+			"team=name":                             "café",
+			"template":                              "$$(SECRET)",
+		}),
+	)
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		value    string
+		expected map[string]string
+	}{
+		{value: "", expected: map[string]string{}},
+		{value: "service.name=checkout,team=payments", expected: map[string]string{"service.name": "checkout", "team": "payments"}},
+		{value: " service.name = checkout , ,=orphan,", expected: map[string]string{"service.name": "checkout"}},
+		{value: "token=a=b,flag", expected: map[string]string{"token": "a=b", "flag": ""}},
+		{value: "owner=Payments%2C%20Inc.,city=S%C3%A3o Paulo", expected: map[string]string{"owner": "Payments, Inc.", "city": "São Paulo"}},
+		{value: "ratio=100%,code=%zz%4", expected: map[string]string{"ratio": "100%", "code": "%zz%4"}},
+		{value: "team=checkout,team=payments", expected: map[string]string{"team": "payments"}},
+	} {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Parse(tt.value))
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	roundTrip := func(attributes map[string]string) bool {
+		for k := range attributes {
+			if k == "" {
+				delete(attributes, k)
+			}
+		}
+		return assert.Equal(t, attributes, Parse(Encode(attributes)))
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodedCharacters(t *testing.T) {
+	// every encoded value can be read by the SDKs, which split the pairs on ',' and the key from the value on '='
+	encoded := func(key string, value string) bool {
+		if key == "" {
+			return true
+		}
+		pairs := strings.Split(Encode(map[string]string{key: value}), ",")
+		if len(pairs) != 1 || strings.Count(pairs[0], "=") != 1 {
+			return false
+		}
+		for i := 0; i < len(pairs[0]); i++ {
+			if !isBaggageOctet(pairs[0][i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(encoded, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
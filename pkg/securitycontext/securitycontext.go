@@ -0,0 +1,63 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package securitycontext builds the security context of the containers injected into application pods.
+package securitycontext
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultRunAsUser is the user injected containers run as when neither the pod nor the application container
+// define one, the images of the agents being built to run as root.
+const DefaultRunAsUser int64 = 65532
+
+// Restricted returns a security context complying with the restricted Pod Security Standard.
+func Restricted() *corev1.SecurityContext {
+	runAsNonRoot := true
+	allowPrivilegeEscalation := false
+	readOnlyRootFilesystem := true
+	return &corev1.SecurityContext{
+		RunAsNonRoot:             &runAsNonRoot,
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+}
+
+// ForInjectedContainer returns the security context of a container injected next to the application container:
+// the override when set, Restricted otherwise. Unless set by the override, the user and group are those of the
+// application container, so that the files shared through volumes are accessible to both. Nil is returned for
+// Windows pods, which don't support most of these settings.
+func ForInjectedContainer(override *corev1.SecurityContext, pod corev1.Pod, app corev1.Container) *corev1.SecurityContext {
+	if pod.Spec.OS != nil && pod.Spec.OS.Name == corev1.Windows {
+		return override.DeepCopy()
+	}
+
+	securityContext := Restricted()
+	if override != nil {
+		securityContext = override.DeepCopy()
+	}
+	if app.SecurityContext != nil {
+		if securityContext.RunAsUser == nil && app.SecurityContext.RunAsUser != nil {
+			runAsUser := *app.SecurityContext.RunAsUser
+			securityContext.RunAsUser = &runAsUser
+		}
+		if securityContext.RunAsGroup == nil && app.SecurityContext.RunAsGroup != nil {
+			runAsGroup := *app.SecurityContext.RunAsGroup
+			securityContext.RunAsGroup = &runAsGroup
+		}
+	}
+	// the user of the pod applies to every container
+	podRunsAsUser := pod.Spec.SecurityContext != nil && pod.Spec.SecurityContext.RunAsUser != nil
+	if securityContext.RunAsUser == nil && !podRunsAsUser && securityContext.RunAsNonRoot != nil && *securityContext.RunAsNonRoot {
+		runAsUser := DefaultRunAsUser
+		securityContext.RunAsUser = &runAsUser
+	}
+	return securityContext
+}
@@ -0,0 +1,63 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package securitycontext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestForInjectedContainer(t *testing.T) {
+	defaultUser, appUser, appGroup, podUser, overrideUser := DefaultRunAsUser, int64(1000), int64(3000), int64(2000), int64(4000)
+	runAsNonRoot := false
+	withUser := func(securityContext *corev1.SecurityContext, user *int64, group *int64) *corev1.SecurityContext {
+		securityContext.RunAsUser = user
+		securityContext.RunAsGroup = group
+		return securityContext
+	}
+
+	for _, tt := range []struct {
+		name     string
+		override *corev1.SecurityContext
+		pod      corev1.PodSpec
+		app      corev1.Container
+		expected *corev1.SecurityContext
+	}{
+		{
+			name:     "default user",
+			expected: withUser(Restricted(), &defaultUser, nil),
+		},
+		{
+			name:     "user of the application container",
+			app:      corev1.Container{SecurityContext: &corev1.SecurityContext{RunAsUser: &appUser, RunAsGroup: &appGroup}},
+			expected: withUser(Restricted(), &appUser, &appGroup),
+		},
+		{
+			name:     "user of the pod",
+			pod:      corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsUser: &podUser}},
+			expected: Restricted(),
+		},
+		{
+			name:     "override",
+			override: &corev1.SecurityContext{RunAsUser: &overrideUser},
+			app:      corev1.Container{SecurityContext: &corev1.SecurityContext{RunAsUser: &appUser, RunAsGroup: &appGroup}},
+			expected: &corev1.SecurityContext{RunAsUser: &overrideUser, RunAsGroup: &appGroup},
+		},
+		{
+			name:     "override running as root",
+			override: &corev1.SecurityContext{RunAsNonRoot: &runAsNonRoot},
+			expected: &corev1.SecurityContext{RunAsNonRoot: &runAsNonRoot},
+		},
+		{
+			name: "windows",
+			pod:  corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ForInjectedContainer(tt.override, corev1.Pod{Spec: tt.pod}, tt.app))
+		})
+	}
+}
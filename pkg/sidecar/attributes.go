@@ -6,14 +6,14 @@ package sidecar
 
 import (
 	"fmt"
-	"sort"
-	"strings"
 
 	"github.com/open-telemetry/opentelemetry-operator/pkg/constants"
 	"go.opentelemetry.io/otel/attribute"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/resourceattributes"
 )
 
 const resourceAttributesEnvName = "OTEL_RESOURCE_ATTRIBUTES"
@@ -24,8 +24,9 @@ type podReferences struct {
 }
 
 // getResourceAttributesEnv returns a list of environment variables. The list contains OTEL_RESOURCE_ATTRIBUTES and additional environment variables that use Kubernetes downward API to read pod specification.
+// The cloud and cluster attributes don't override the Kubernetes ones.
 // see: https://kubernetes.io/docs/tasks/inject-data-application/environment-variable-expose-pod-information/
-func getResourceAttributesEnv(ns corev1.Namespace, podReferences podReferences) []corev1.EnvVar {
+func getResourceAttributesEnv(ns corev1.Namespace, podReferences podReferences, clusterAttributes map[attribute.Key]string) []corev1.EnvVar {
 
 	var envvars []corev1.EnvVar
 
@@ -46,6 +47,12 @@ func getResourceAttributesEnv(ns corev1.Namespace, podReferences podReferences)
 		attributes[semconv.K8SReplicaSetNameKey] = string(podReferences.replicaset.Name)
 	}
 
+	for k, v := range clusterAttributes {
+		if _, ok := attributes[k]; !ok {
+			attributes[k] = v
+		}
+	}
+
 	envvars = append(envvars, corev1.EnvVar{
 		Name: constants.EnvPodName,
 		ValueFrom: &corev1.EnvVarSource{
@@ -82,19 +89,11 @@ func getResourceAttributesEnv(ns corev1.Namespace, podReferences podReferences)
 }
 
 func mapToValue(attributesMap map[attribute.Key]string) string {
-	var parts []string
-
-	// Sort it to make it predictable
-	keys := make([]string, 0, len(attributesMap))
-	for k := range attributesMap {
-		keys = append(keys, string(k))
-	}
-	sort.Strings(keys)
-
-	for _, key := range keys {
-		parts = append(parts, fmt.Sprintf("%s=%s", key, attributesMap[attribute.Key(key)]))
+	attributes := make(map[string]string, len(attributesMap))
+	for k, v := range attributesMap {
+		attributes[string(k)] = v
 	}
-	return strings.Join(parts, ",")
+	return resourceattributes.Encode(attributes)
 }
 
 // check if container doesn't have already the OTEL_RESOURCE_ATTRIBUTES, we don't want to override it if it's already specified.
@@ -9,10 +9,13 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-operator/pkg/constants"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	appv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/resourceattributes"
 )
 
 func TestGetAttributesEnvNoPodReferences(t *testing.T) {
@@ -22,7 +25,7 @@ func TestGetAttributesEnvNoPodReferences(t *testing.T) {
 		},
 	}
 	references := podReferences{}
-	envs := getResourceAttributesEnv(ns, references)
+	envs := getResourceAttributesEnv(ns, references, nil)
 
 	expectedEnv := []corev1.EnvVar{
 		{
@@ -86,7 +89,7 @@ func TestGetAttributesEnvWithPodReferences(t *testing.T) {
 			},
 		},
 	}
-	envs := getResourceAttributesEnv(ns, references)
+	envs := getResourceAttributesEnv(ns, references, nil)
 
 	expectedEnv := []corev1.EnvVar{
 		{
@@ -134,6 +137,25 @@ func TestGetAttributesEnvWithPodReferences(t *testing.T) {
 	assert.Equal(t, expectedEnv, envs)
 }
 
+func TestGetAttributesEnvWithClusterAttributes(t *testing.T) {
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "my-ns"}}
+	envs := getResourceAttributesEnv(ns, podReferences{}, map[attribute.Key]string{
+		semconv.CloudRegionKey:      "us-west-2",
+		semconv.K8SClusterNameKey:   "prod",
+		semconv.K8SNamespaceNameKey: "other-ns",
+	})
+
+	assert.Equal(t, resourceAttributesEnvName, envs[len(envs)-1].Name)
+	assert.Equal(t, map[string]string{
+		"cloud.region":       "us-west-2",
+		"k8s.cluster.name":   "prod",
+		"k8s.namespace.name": "my-ns",
+		"k8s.node.name":      "$(OTEL_RESOURCE_ATTRIBUTES_NODE_NAME)",
+		"k8s.pod.name":       "$(OTEL_RESOURCE_ATTRIBUTES_POD_NAME)",
+		"k8s.pod.uid":        "$(OTEL_RESOURCE_ATTRIBUTES_POD_UID)",
+	}, resourceattributes.Parse(envs[len(envs)-1].Value))
+}
+
 func TestHasResourceAttributeEnvVar(t *testing.T) {
 	for _, tt := range []struct {
 		desc     string
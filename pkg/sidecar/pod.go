@@ -6,6 +6,7 @@ package sidecar
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -15,13 +16,21 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/collector"
 	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/collector/reconcile"
 	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/naming"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/securitycontext"
 )
 
 const (
 	label      = "sidecar.opentelemetry.io/injected"
 	confEnvVar = "OTEL_CONFIG"
+	// agentVolumeName is the emptyDir backing the directories the agent writes to when its root filesystem is read-only.
+	agentVolumeName = "amazon-cloudwatch-agent-writable"
+	agentInstallDir = "/opt/aws/amazon-cloudwatch-agent"
 )
 
+// agentWritableDirs are the directories of the agent installation it writes to: the translated configuration,
+// its logs and its state.
+var agentWritableDirs = []string{"etc", "logs", "var"}
+
 // add a new sidecar container to the given pod, based on the given AmazonCloudWatchAgent.
 func add(cfg config.Config, logger logr.Logger, otelcol v1alpha1.AmazonCloudWatchAgent, pod corev1.Pod, attributes []corev1.EnvVar) (corev1.Pod, error) {
 	otelColCfg, err := reconcile.ReplaceConfig(otelcol)
@@ -32,6 +41,23 @@ func add(cfg config.Config, logger logr.Logger, otelcol v1alpha1.AmazonCloudWatc
 	container := collector.Container(cfg, logger, otelcol, false)
 	container.Args = append(container.Args, fmt.Sprintf("--config=env:%s", confEnvVar))
 
+	// the sidecar runs next to the first container, which is the application container
+	if len(pod.Spec.Containers) > 0 {
+		container.SecurityContext = securitycontext.ForInjectedContainer(otelcol.Spec.SecurityContext, pod, pod.Spec.Containers[0])
+	}
+	if sc := container.SecurityContext; sc != nil && sc.ReadOnlyRootFilesystem != nil && *sc.ReadOnlyRootFilesystem {
+		for _, dir := range agentWritableDirs {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      agentVolumeName,
+				MountPath: path.Join(agentInstallDir, dir),
+				SubPath:   dir,
+			})
+		}
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name:         agentVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+	}
 	container.Env = append(container.Env, corev1.EnvVar{Name: confEnvVar, Value: otelColCfg})
 	if !hasResourceAttributeEnvVar(container.Env) {
 		container.Env = append(container.Env, attributes...)
@@ -60,6 +86,14 @@ func remove(pod corev1.Pod) (corev1.Pod, error) {
 		}
 	}
 	pod.Spec.Containers = containers
+
+	var volumes []corev1.Volume
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name != agentVolumeName {
+			volumes = append(volumes, volume)
+		}
+	}
+	pod.Spec.Volumes = volumes
 	return pod, nil
 }
 
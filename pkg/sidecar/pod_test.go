@@ -15,6 +15,7 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
 	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/naming"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/securitycontext"
 )
 
 var logger = logf.Log.WithName("unit-tests")
@@ -206,3 +207,47 @@ func TestAddSidecarWithAditionalEnv(t *testing.T) {
 	assert.Contains(t, changed.Spec.Containers[1].Env, extraEnv)
 
 }
+
+func TestAddSidecarSecurityContext(t *testing.T) {
+	runAsUser, runAsNonRoot := int64(1000), true
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "my-app", SecurityContext: &corev1.SecurityContext{RunAsUser: &runAsUser}},
+			},
+		},
+	}
+	otelcol := v1alpha1.AmazonCloudWatchAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "otelcol-sample", Namespace: "some-app"},
+		Spec:       v1alpha1.AmazonCloudWatchAgentSpec{Config: `{}`},
+	}
+	cfg := config.New(config.WithCollectorImage("some-default-image"))
+
+	// the sidecar is restricted by default, writing to an emptyDir, and runs as the user of the application container
+	changed, err := add(cfg, logger, otelcol, pod, nil)
+	require.NoError(t, err)
+	require.Len(t, changed.Spec.Containers, 2)
+	expected := securitycontext.Restricted()
+	expected.RunAsUser = &runAsUser
+	assert.Equal(t, expected, changed.Spec.Containers[1].SecurityContext)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: agentVolumeName, MountPath: "/opt/aws/amazon-cloudwatch-agent/etc", SubPath: "etc"},
+		{Name: agentVolumeName, MountPath: "/opt/aws/amazon-cloudwatch-agent/logs", SubPath: "logs"},
+		{Name: agentVolumeName, MountPath: "/opt/aws/amazon-cloudwatch-agent/var", SubPath: "var"},
+	}, changed.Spec.Containers[1].VolumeMounts)
+	assert.Equal(t, []corev1.Volume{{Name: agentVolumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}, changed.Spec.Volumes)
+
+	removed, err := remove(changed)
+	require.NoError(t, err)
+	assert.Equal(t, pod.Spec, removed.Spec)
+
+	// a security context without user runs the sidecar as the user of the application container
+	otelcol.Spec.SecurityContext = &corev1.SecurityContext{RunAsNonRoot: &runAsNonRoot}
+	changed, err = add(cfg, logger, otelcol, pod, nil)
+	require.NoError(t, err)
+	require.Len(t, changed.Spec.Containers, 2)
+	assert.Equal(t, &corev1.SecurityContext{RunAsNonRoot: &runAsNonRoot, RunAsUser: &runAsUser}, changed.Spec.Containers[1].SecurityContext)
+	// with a writable root filesystem, the agent writes to its installation directory
+	assert.Empty(t, changed.Spec.Containers[1].VolumeMounts)
+	assert.Empty(t, changed.Spec.Volumes)
+}
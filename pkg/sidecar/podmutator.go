@@ -18,6 +18,7 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent-operator/apis/v1alpha1"
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/config"
 	"github.com/aws/amazon-cloudwatch-agent-operator/internal/webhookhandler"
+	"github.com/aws/amazon-cloudwatch-agent-operator/pkg/owners"
 )
 
 var (
@@ -30,6 +31,7 @@ type sidecarPodMutator struct {
 	client client.Client
 	logger logr.Logger
 	config config.Config
+	owners *owners.Resolver
 }
 
 var _ webhookhandler.PodMutator = (*sidecarPodMutator)(nil)
@@ -39,6 +41,7 @@ func NewMutator(logger logr.Logger, config config.Config, client client.Client)
 		config: config,
 		logger: logger,
 		client: client,
+		owners: owners.NewResolver(client, logger),
 	}
 }
 
@@ -79,8 +82,8 @@ func (p *sidecarPodMutator) Mutate(ctx context.Context, ns corev1.Namespace, pod
 	}
 
 	// getting pod references, if any
-	references := p.podReferences(ctx, pod.OwnerReferences, ns)
-	attributes := getResourceAttributesEnv(ns, references)
+	references := p.podReferences(ctx, pod, ns)
+	attributes := getResourceAttributesEnv(ns, references, p.config.ClusterAttributes().ResourceAttributes())
 
 	// once it's been determined that a sidecar is desired, none exists yet, and we know which instance it should talk to,
 	// we should add the sidecar.
@@ -141,48 +144,25 @@ func (p *sidecarPodMutator) selectCollectorInstance(ctx context.Context, ns core
 	}
 }
 
-func (p *sidecarPodMutator) podReferences(ctx context.Context, ownerReferences []metav1.OwnerReference, ns corev1.Namespace) podReferences {
-	references := &podReferences{}
-	replicaSet := p.getReplicaSetReference(ctx, ownerReferences, ns)
-	if replicaSet != nil {
-		references.replicaset = replicaSet
-		deployment := p.getDeploymentReference(ctx, replicaSet)
-		if deployment != nil {
-			references.deployment = deployment
-		}
+func (p *sidecarPodMutator) podReferences(ctx context.Context, pod corev1.Pod, ns corev1.Namespace) podReferences {
+	references := podReferences{}
+	replicaSet := findOwnerReference(pod.OwnerReferences, "ReplicaSet")
+	if replicaSet == nil {
+		return references
 	}
-	return *references
-}
-
-func (p *sidecarPodMutator) getReplicaSetReference(ctx context.Context, ownerReferences []metav1.OwnerReference, ns corev1.Namespace) *appsv1.ReplicaSet {
-	replicaSetName := findOwnerReferenceKind(ownerReferences, "ReplicaSet")
-	if replicaSetName != "" {
-		replicaSet := &appsv1.ReplicaSet{}
-		err := p.client.Get(ctx, types.NamespacedName{Name: replicaSetName, Namespace: ns.Name}, replicaSet)
-		if err == nil {
-			return replicaSet
-		}
+	references.replicaset = &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: replicaSet.Name, UID: replicaSet.UID}}
+	owner, found := p.owners.ReplicaSetOwner(ctx, ns.Name, replicaSet.Name, pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey])
+	if found && owner.Kind == "Deployment" {
+		references.deployment = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: owner.Name, UID: owner.UID}}
 	}
-	return nil
+	return references
 }
 
-func (p *sidecarPodMutator) getDeploymentReference(ctx context.Context, replicaSet *appsv1.ReplicaSet) *appsv1.Deployment {
-	deploymentName := findOwnerReferenceKind(replicaSet.OwnerReferences, "Deployment")
-	if deploymentName != "" {
-		deployment := &appsv1.Deployment{}
-		err := p.client.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: replicaSet.Namespace}, deployment)
-		if err == nil {
-			return deployment
+func findOwnerReference(references []metav1.OwnerReference, kind string) *metav1.OwnerReference {
+	for i := range references {
+		if references[i].Kind == kind {
+			return &references[i]
 		}
 	}
 	return nil
 }
-
-func findOwnerReferenceKind(references []metav1.OwnerReference, kind string) string {
-	for _, reference := range references {
-		if reference.Kind == kind {
-			return reference.Name
-		}
-	}
-	return ""
-}